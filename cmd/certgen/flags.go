@@ -0,0 +1,503 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"certgen/internal/cert"
+)
+
+// certFlags holds the certificate flags shared by the ca, cert and csr commands.
+// Flags that were set on the command line override values from the config file.
+type certFlags struct {
+	class      string
+	commonName string
+	org        string
+	country    string
+	validity   string
+	notBefore  string
+	notAfter   string
+	backdate   string
+	keySize    int
+	keyType    string
+	curve      string
+	keyFormat  string
+	outputDir  string
+	format     string
+	crlURLs    []string
+	ocspURLs   []string
+	caIssuers  []string
+	policyOIDs []string
+	keyUsages  []string
+	sigAlg     string
+	report     string
+	serialMode string
+	serial     string
+	fileName   string
+	fileNameCN bool
+	publicKey  bool
+	fprint     bool
+	fprintFile bool
+
+	keyFile           string
+	keyPassphraseEnv  string
+	keyPassphraseFile string
+
+	certFileMode     string
+	keyFileMode      string
+	worldReadableKey bool
+
+	encryptKey     bool
+	passphrase     string
+	passphraseEnv  string
+	passphraseFile string
+}
+
+// register adds the shared certificate flags to a command
+func (f *certFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.class, "class", "", "Certificate class (1-3)")
+	cmd.Flags().StringVar(&f.commonName, "common-name", "", "Common Name for the certificate")
+	cmd.Flags().StringVar(&f.org, "org", "", "Organization name")
+	cmd.Flags().StringVar(&f.country, "country", "", "Country code")
+	cmd.Flags().IntVar(&f.keySize, "key-size", 0, "RSA key size in bits (default: class dependent)")
+	cmd.Flags().StringVar(&f.keyType, "key-type", "", "Key type: rsa, ecdsa or ed25519 (default: rsa)")
+	cmd.Flags().StringVar(&f.curve, "curve", "", "ECDSA curve: P256, P384 or P521 (default: class dependent)")
+	cmd.Flags().StringVar(&f.keyFormat, "key-format", "", "Private key format: pkcs8, or pkcs1 for rsa keys (default: pkcs8)")
+	cmd.Flags().StringVar(&f.outputDir, "output-dir", "", "Output directory for certificates (default: certs)")
+	cmd.Flags().StringVar(&f.certFileMode, "cert-file-mode", "", "Octal permissions of written certificates (default: 0644)")
+	cmd.Flags().StringVar(&f.keyFileMode, "key-file-mode", "", "Octal permissions of written private keys and keystores (default: 0600)")
+	cmd.Flags().BoolVar(&f.worldReadableKey, "world-readable-key", false, "Allow a --key-file-mode that gives other users access")
+	cmd.Flags().BoolVar(&f.encryptKey, "encrypt-key", false, "Encrypt the private key with a passphrase")
+	cmd.Flags().StringVar(&f.passphrase, "passphrase", "", "Passphrase for the private key (prefer --passphrase-env or --passphrase-file)")
+	cmd.Flags().StringVar(&f.passphraseEnv, "passphrase-env", "", "Environment variable holding the private key passphrase")
+	cmd.Flags().StringVar(&f.passphraseFile, "passphrase-file", "", "File holding the private key passphrase")
+
+	completeValues(cmd, "class",
+		"1\tLow assurance, personal use and email",
+		"2\tMedium assurance, organization validation",
+		"3\tHigh assurance, extended validation and code signing")
+	completeValues(cmd, "key-type", "rsa", "ecdsa", "ed25519")
+	completeValues(cmd, "curve", "P256", "P384", "P521")
+	completeValues(cmd, "key-format", "pkcs8", "pkcs1")
+}
+
+// registerIssuance adds the flags that only apply when issuing a certificate
+func (f *certFlags) registerIssuance(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.validity, "validity", "", "Validity period in days, or a duration such as 90d, 2w, 1y or 8760h (default: class dependent)")
+	cmd.Flags().StringVar(&f.notBefore, "not-before", "", "Start of the validity period, RFC 3339 (default: now)")
+	cmd.Flags().StringVar(&f.notAfter, "validity-not-after", "", "End of the validity period, RFC 3339, overriding --validity")
+	cmd.Flags().StringVar(&f.backdate, "backdate", "", "Move the start of the validity period back by a duration, e.g. 5m")
+	cmd.Flags().StringVar(&f.format, "format", "", "Output format: pem, pkcs12, der, pkcs7 or, for cert, jks (default: pem)")
+	cmd.Flags().StringSliceVar(&f.crlURLs, "crl-urls", nil, "Comma-separated CRL distribution point URLs (default: the issuing CA's)")
+	cmd.Flags().StringSliceVar(&f.ocspURLs, "ocsp-urls", nil, "Comma-separated OCSP responder URLs (default: the issuing CA's)")
+	cmd.Flags().StringSliceVar(&f.caIssuers, "ca-issuers-urls", nil, "Comma-separated CA Issuers URLs where the issuing CA certificate is published (default: the issuing CA's)")
+	cmd.Flags().StringSliceVar(&f.policyOIDs, "policy-oids", nil, "Comma-separated certificate policy OIDs added to the class defaults")
+	cmd.Flags().StringSliceVar(&f.keyUsages, "key-usages", nil, "Comma-separated key usages replacing the defaults, e.g. digitalSignature,keyAgreement")
+	cmd.Flags().StringVar(&f.sigAlg, "signature-algorithm", "", "Signature algorithm, e.g. SHA384WithRSA or ECDSAWithSHA512 (default: SHA-256, or the curve's hash for ECDSA)")
+	cmd.Flags().StringVar(&f.keyFile, "key", "", "Existing private key to use instead of generating one")
+	cmd.Flags().StringVar(&f.keyPassphraseEnv, "key-passphrase-env", "", "Environment variable holding the existing key passphrase")
+	cmd.Flags().StringVar(&f.keyPassphraseFile, "key-passphrase-file", "", "File holding the existing key passphrase")
+	cmd.Flags().StringVar(&f.serialMode, "serial-mode", "", "Serial numbers: random or sequential, kept next to the CA key (default: random)")
+	cmd.Flags().StringVar(&f.serial, "serial", "", "Explicit serial number, decimal or hex with a 0x prefix, hex letters or colons (default: random)")
+	cmd.Flags().StringVar(&f.fileName, "file-name", "", "Base name of the output files (default: "+cmd.Name()+")")
+	cmd.Flags().BoolVar(&f.fileNameCN, "file-name-from-cn", false, "Name the output files after the common name")
+	cmd.Flags().BoolVar(&f.publicKey, "public-key", false, "Also write the public key as a PEM file with the .pub extension")
+	cmd.Flags().BoolVar(&f.fprint, "fingerprint", false, "Print the SHA-1 and SHA-256 fingerprints of the certificate")
+	cmd.Flags().BoolVar(&f.fprintFile, "fingerprint-file", false, "Write the SHA-256 fingerprint to a .sha256 file")
+	cmd.Flags().StringVar(&f.report, "report", "", "Print a report of the certificate and written files to stdout: json")
+
+	completeValues(cmd, "format", "pem", "pkcs12", "der", "pkcs7", "jks")
+	completeValues(cmd, "serial-mode", "random", "sequential")
+	completeValues(cmd, "report", "json")
+}
+
+// applyCA overrides CAConfig fields with the flags set on cmd
+func (f *certFlags) applyCA(cmd *cobra.Command, config *cert.CAConfig) error {
+	flags := cmd.Flags()
+	if flags.Changed("class") {
+		class, err := parseClass(f.class)
+		if err != nil {
+			return err
+		}
+		config.Class = class
+	}
+	if flags.Changed("common-name") {
+		config.CommonName = f.commonName
+	}
+	if flags.Changed("org") {
+		config.Organization = cert.StringList{f.org}
+	}
+	if flags.Changed("country") {
+		config.Country = cert.StringList{f.country}
+	}
+	if flags.Changed("validity") {
+		config.Validity = f.validity
+	}
+	if flags.Changed("not-before") {
+		config.NotBefore = f.notBefore
+	}
+	if flags.Changed("validity-not-after") {
+		config.NotAfter = f.notAfter
+	}
+	if flags.Changed("backdate") {
+		config.Backdate = f.backdate
+	}
+	if flags.Changed("key-size") {
+		config.KeySize = f.keySize
+	}
+	if flags.Changed("key-type") {
+		config.KeyType = cert.KeyType(f.keyType)
+	}
+	if flags.Changed("curve") {
+		config.Curve = cert.Curve(f.curve)
+	}
+	if flags.Changed("key-format") {
+		config.KeyFormat = cert.KeyFormat(f.keyFormat)
+	}
+	if flags.Changed("cert-file-mode") {
+		config.CertFileMode = f.certFileMode
+	}
+	if flags.Changed("key-file-mode") {
+		config.KeyFileMode = f.keyFileMode
+	}
+	if flags.Changed("world-readable-key") {
+		config.WorldReadableKey = f.worldReadableKey
+	}
+	if flags.Changed("output-dir") {
+		config.OutputDir = f.outputDir
+	}
+	if flags.Changed("format") {
+		config.Format = cert.OutputFormat(f.format)
+	}
+	if flags.Changed("crl-urls") {
+		config.CRLDistributionPoints = f.crlURLs
+	}
+	if flags.Changed("ocsp-urls") {
+		config.OCSPServers = f.ocspURLs
+	}
+	if flags.Changed("ca-issuers-urls") {
+		config.IssuingCertificateURL = f.caIssuers
+	}
+	if flags.Changed("policy-oids") {
+		config.PolicyOIDs = f.policyOIDs
+	}
+	if flags.Changed("key-usages") {
+		config.KeyUsages = f.keyUsages
+	}
+	if flags.Changed("signature-algorithm") {
+		config.SignatureAlgorithm = f.sigAlg
+	}
+	if flags.Changed("report") {
+		if err := checkReportFormat(f.report); err != nil {
+			return err
+		}
+		config.ReportOutput, config.ProgressOutput = os.Stdout, os.Stderr
+	}
+	if flags.Changed("serial-mode") {
+		config.SerialMode = cert.SerialMode(f.serialMode)
+	}
+	if flags.Changed("serial") {
+		config.Serial = f.serial
+	}
+	if flags.Changed("file-name") {
+		config.FileName = f.fileName
+	}
+	if flags.Changed("file-name-from-cn") {
+		config.FileNameFromCommonName = f.fileNameCN
+	}
+	if flags.Changed("public-key") {
+		config.WritePublicKey = f.publicKey
+	}
+	if flags.Changed("fingerprint") {
+		config.Fingerprint = f.fprint
+	}
+	if flags.Changed("fingerprint-file") {
+		config.WriteFingerprint = f.fprintFile
+	}
+	if flags.Changed("key") {
+		config.KeyFile = f.keyFile
+	}
+	if flags.Changed("key-passphrase-env") {
+		config.KeyPassphraseEnv = f.keyPassphraseEnv
+	}
+	if flags.Changed("key-passphrase-file") {
+		config.KeyPassphraseFile = f.keyPassphraseFile
+	}
+	if flags.Changed("encrypt-key") {
+		config.EncryptKey = f.encryptKey
+	}
+	if flags.Changed("passphrase") {
+		config.Passphrase = f.passphrase
+	}
+	if flags.Changed("passphrase-env") {
+		config.PassphraseEnv = f.passphraseEnv
+	}
+	if flags.Changed("passphrase-file") {
+		config.PassphraseFile = f.passphraseFile
+	}
+	return nil
+}
+
+// applyCert overrides CertConfig fields with the flags set on cmd
+func (f *certFlags) applyCert(cmd *cobra.Command, config *cert.CertConfig) error {
+	flags := cmd.Flags()
+	if flags.Changed("class") {
+		class, err := parseClass(f.class)
+		if err != nil {
+			return err
+		}
+		config.Class = class
+	}
+	if flags.Changed("common-name") {
+		config.CommonName = f.commonName
+	}
+	if flags.Changed("org") {
+		config.Organization = cert.StringList{f.org}
+	}
+	if flags.Changed("country") {
+		config.Country = cert.StringList{f.country}
+	}
+	if flags.Changed("validity") {
+		config.Validity = f.validity
+	}
+	if flags.Changed("not-before") {
+		config.NotBefore = f.notBefore
+	}
+	if flags.Changed("validity-not-after") {
+		config.NotAfter = f.notAfter
+	}
+	if flags.Changed("backdate") {
+		config.Backdate = f.backdate
+	}
+	if flags.Changed("key-size") {
+		config.KeySize = f.keySize
+	}
+	if flags.Changed("key-type") {
+		config.KeyType = cert.KeyType(f.keyType)
+	}
+	if flags.Changed("curve") {
+		config.Curve = cert.Curve(f.curve)
+	}
+	if flags.Changed("key-format") {
+		config.KeyFormat = cert.KeyFormat(f.keyFormat)
+	}
+	if flags.Changed("cert-file-mode") {
+		config.CertFileMode = f.certFileMode
+	}
+	if flags.Changed("key-file-mode") {
+		config.KeyFileMode = f.keyFileMode
+	}
+	if flags.Changed("world-readable-key") {
+		config.WorldReadableKey = f.worldReadableKey
+	}
+	if flags.Changed("output-dir") {
+		config.OutputDir = f.outputDir
+	}
+	if flags.Changed("format") {
+		config.Format = cert.OutputFormat(f.format)
+	}
+	if flags.Changed("crl-urls") {
+		config.CRLDistributionPoints = f.crlURLs
+	}
+	if flags.Changed("ocsp-urls") {
+		config.OCSPServers = f.ocspURLs
+	}
+	if flags.Changed("ca-issuers-urls") {
+		config.IssuingCertificateURL = f.caIssuers
+	}
+	if flags.Changed("policy-oids") {
+		config.PolicyOIDs = f.policyOIDs
+	}
+	if flags.Changed("key-usages") {
+		config.KeyUsages = f.keyUsages
+	}
+	if flags.Changed("signature-algorithm") {
+		config.SignatureAlgorithm = f.sigAlg
+	}
+	if flags.Changed("report") {
+		if err := checkReportFormat(f.report); err != nil {
+			return err
+		}
+		config.ReportOutput, config.ProgressOutput = os.Stdout, os.Stderr
+	}
+	if flags.Changed("serial-mode") {
+		config.SerialMode = cert.SerialMode(f.serialMode)
+	}
+	if flags.Changed("serial") {
+		config.Serial = f.serial
+	}
+	if flags.Changed("file-name") {
+		config.FileName = f.fileName
+	}
+	if flags.Changed("file-name-from-cn") {
+		config.FileNameFromCommonName = f.fileNameCN
+	}
+	if flags.Changed("public-key") {
+		config.WritePublicKey = f.publicKey
+	}
+	if flags.Changed("fingerprint") {
+		config.Fingerprint = f.fprint
+	}
+	if flags.Changed("fingerprint-file") {
+		config.WriteFingerprint = f.fprintFile
+	}
+	if flags.Changed("key") {
+		config.KeyFile = f.keyFile
+	}
+	if flags.Changed("key-passphrase-env") {
+		config.KeyPassphraseEnv = f.keyPassphraseEnv
+	}
+	if flags.Changed("key-passphrase-file") {
+		config.KeyPassphraseFile = f.keyPassphraseFile
+	}
+	if flags.Changed("encrypt-key") {
+		config.EncryptKey = f.encryptKey
+	}
+	if flags.Changed("passphrase") {
+		config.Passphrase = f.passphrase
+	}
+	if flags.Changed("passphrase-env") {
+		config.PassphraseEnv = f.passphraseEnv
+	}
+	if flags.Changed("passphrase-file") {
+		config.PassphraseFile = f.passphraseFile
+	}
+	return nil
+}
+
+// applyCSR overrides CSRConfig fields with the flags set on cmd
+func (f *certFlags) applyCSR(cmd *cobra.Command, config *cert.CSRConfig) error {
+	flags := cmd.Flags()
+	if flags.Changed("class") {
+		class, err := parseClass(f.class)
+		if err != nil {
+			return err
+		}
+		config.Class = class
+	}
+	if flags.Changed("common-name") {
+		config.CommonName = f.commonName
+	}
+	if flags.Changed("org") {
+		config.Organization = cert.StringList{f.org}
+	}
+	if flags.Changed("country") {
+		config.Country = cert.StringList{f.country}
+	}
+	if flags.Changed("key-size") {
+		config.KeySize = f.keySize
+	}
+	if flags.Changed("key-type") {
+		config.KeyType = cert.KeyType(f.keyType)
+	}
+	if flags.Changed("curve") {
+		config.Curve = cert.Curve(f.curve)
+	}
+	if flags.Changed("key-format") {
+		config.KeyFormat = cert.KeyFormat(f.keyFormat)
+	}
+	if flags.Changed("cert-file-mode") {
+		config.CertFileMode = f.certFileMode
+	}
+	if flags.Changed("key-file-mode") {
+		config.KeyFileMode = f.keyFileMode
+	}
+	if flags.Changed("world-readable-key") {
+		config.WorldReadableKey = f.worldReadableKey
+	}
+	if flags.Changed("output-dir") {
+		config.OutputDir = f.outputDir
+	}
+	if flags.Changed("encrypt-key") {
+		config.EncryptKey = f.encryptKey
+	}
+	if flags.Changed("passphrase") {
+		config.Passphrase = f.passphrase
+	}
+	if flags.Changed("passphrase-env") {
+		config.PassphraseEnv = f.passphraseEnv
+	}
+	if flags.Changed("passphrase-file") {
+		config.PassphraseFile = f.passphraseFile
+	}
+	return nil
+}
+
+// completeValues offers a fixed list of values, each optionally followed by
+// a tab and a description, when completing a flag in the shell
+func completeValues(cmd *cobra.Command, flag string, values ...string) {
+	if err := cmd.RegisterFlagCompletionFunc(flag, cobra.FixedCompletions(values, cobra.ShellCompDirectiveNoFileComp)); err != nil {
+		panic(err)
+	}
+}
+
+// registerDirMode adds the output directory permission flags to a command
+func registerDirMode(cmd *cobra.Command) {
+	cmd.Flags().String("dir-mode", "", "Octal permissions of created output directories (default: 0755)")
+	cmd.Flags().Bool("enforce-dir-mode", false, "Also apply --dir-mode to an existing output directory")
+}
+
+// applyDirMode overrides the output directory permissions of a configuration
+// with the flags that were set
+func applyDirMode(cmd *cobra.Command, mode *string, enforce *bool) {
+	flags := cmd.Flags()
+	if flags.Changed("dir-mode") {
+		*mode, _ = flags.GetString("dir-mode")
+	}
+	if flags.Changed("enforce-dir-mode") {
+		*enforce, _ = flags.GetBool("enforce-dir-mode")
+	}
+}
+
+// checkReportFormat accepts the formats of --report. The report is written
+// to stdout, so progress and warnings move to stderr.
+func checkReportFormat(format string) error {
+	if format != "json" {
+		return fmt.Errorf("unsupported report format %q (must be json)", format)
+	}
+	return nil
+}
+
+// envPrefix starts the names of the environment variables that set flags
+const envPrefix = "CERTGEN_"
+
+// insecureFastKeysFlag allows keys below the class requirements. It is only
+// accepted on the command line, so neither a config file nor a stray
+// environment variable can weaken the keys.
+const insecureFastKeysFlag = "insecure-fast-keys"
+
+// commandLineOnlyFlags are never set from the environment: a variable
+// exported for one run must not overwrite keys, expose them or weaken them
+// in every later run
+var commandLineOnlyFlags = map[string]bool{
+	"help":               true,
+	"force":              true,
+	"world-readable-key": true,
+	insecureFastKeysFlag: true,
+}
+
+// applyEnv sets each flag of cmd that was not given on the command line from
+// the environment variable named after it, e.g. CERTGEN_COMMON_NAME for
+// --common-name. Like flags, these values override the config file. The
+// commandLineOnlyFlags are skipped.
+func applyEnv(cmd *cobra.Command) error {
+	var err error
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed || commandLineOnlyFlags[flag.Name] {
+			return
+		}
+		name := envPrefix + strings.ToUpper(strings.ReplaceAll(flag.Name, "-", "_"))
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+		if setErr := cmd.Flags().Set(flag.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid %s: %w", name, setErr)
+		}
+	})
+	return err
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"certgen/internal/cert"
+)
+
+// wizard asks questions on out and reads the answers from in, one per line
+type wizard struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// ask prints a question with its default answer and returns the answer, or
+// the default if the line is empty
+func (w *wizard) ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(w.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(w.out, "%s: ", question)
+	}
+	line, err := w.in.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		if errors.Is(err, io.EOF) {
+			fmt.Fprintln(w.out)
+			return "", fmt.Errorf("no answer to %q", question)
+		}
+		return "", err
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+// askValid asks a question until set accepts the answer
+func (w *wizard) askValid(question, def string, set func(answer string) error) error {
+	for {
+		answer, err := w.ask(question, def)
+		if err != nil {
+			return err
+		}
+		if err := set(answer); err != nil {
+			fmt.Fprintf(w.out, "  %v\n", err)
+			continue
+		}
+		return nil
+	}
+}
+
+// confirm asks a yes or no question
+func (w *wizard) confirm(question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		answer, err := w.ask(question+" ("+hint+")", "")
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintln(w.out, "  answer yes or no")
+	}
+}
+
+// runInit asks for the settings of a root CA, validating each answer against
+// the rest of the configuration as it goes, and writes them to path. The root
+// CA is generated right away if the user agrees.
+func runInit(in io.Reader, out io.Writer, path string, config *cert.CAConfig) error {
+	if !config.Force {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+		}
+	}
+
+	// Every answer is checked by validating a copy of the configuration, with
+	// the defaults standing in for the questions not asked yet
+	*config = cert.CAConfig{
+		Type:         cert.Root,
+		Class:        cert.Class2,
+		CommonName:   "Development Root CA",
+		Organization: cert.StringList{"Development"},
+		Country:      cert.StringList{"US"},
+		ValidityDays: 3650,
+		KeyType:      cert.KeyTypeRSA,
+		KeySize:      4096,
+		OutputDir:    "certs",
+		NoProgress:   config.NoProgress,
+		Quiet:        config.Quiet,
+		Force:        config.Force,
+	}
+	check := func() error {
+		candidate := *config
+		return candidate.Validate()
+	}
+
+	w := &wizard{in: bufio.NewReader(in), out: out}
+	fmt.Fprintln(out, "Set up a root CA. Press Enter to accept the default in brackets.")
+	fmt.Fprintln(out)
+
+	err := w.askValid("Common name", config.CommonName, func(answer string) error {
+		config.CommonName = answer
+		return check()
+	})
+	if err != nil {
+		return err
+	}
+	err = w.askValid("Organization", config.Organization[0], func(answer string) error {
+		config.Organization = cert.StringList{answer}
+		return check()
+	})
+	if err != nil {
+		return err
+	}
+	err = w.askValid("Country (two-letter code)", config.Country[0], func(answer string) error {
+		config.Country = cert.StringList{answer}
+		return check()
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out)
+	for _, class := range []cert.CertificateClass{cert.Class1, cert.Class2, cert.Class3} {
+		minKeySize, maxValidityDays := cert.ClassRequirements(class)
+		fmt.Fprintf(out, "  Class %d: RSA keys of at least %d bits, issued certificates valid for up to %d days\n", class, minKeySize, maxValidityDays)
+	}
+	err = w.askValid("Class (root CAs need 2 or 3)", strconv.Itoa(int(config.Class)), func(answer string) error {
+		class, err := parseClass(answer)
+		if err != nil {
+			return err
+		}
+		config.Class = class
+		return check()
+	})
+	if err != nil {
+		return err
+	}
+	err = w.askValid("Validity in days (root CAs need at least 1825)", strconv.Itoa(config.ValidityDays), func(answer string) error {
+		days, err := strconv.Atoi(answer)
+		if err != nil || days <= 0 {
+			return fmt.Errorf("enter a number of days")
+		}
+		config.ValidityDays = days
+		return check()
+	})
+	if err != nil {
+		return err
+	}
+
+	err = w.askValid("Key type (rsa, ecdsa or ed25519)", string(config.KeyType), func(answer string) error {
+		config.KeyType = cert.KeyType(strings.ToLower(answer))
+		config.KeySize, config.Curve = 0, ""
+		switch config.KeyType {
+		case cert.KeyTypeRSA:
+			config.KeySize = 4096
+		case cert.KeyTypeECDSA:
+			config.Curve = cert.CurveP384
+		}
+		return check()
+	})
+	if err != nil {
+		return err
+	}
+	switch config.KeyType {
+	case cert.KeyTypeRSA:
+		err = w.askValid("Key size in bits", strconv.Itoa(config.KeySize), func(answer string) error {
+			size, err := strconv.Atoi(answer)
+			if err != nil || size <= 0 {
+				return fmt.Errorf("enter a number of bits")
+			}
+			config.KeySize = size
+			return check()
+		})
+	case cert.KeyTypeECDSA:
+		err = w.askValid("Curve (P384 or P521)", string(config.Curve), func(answer string) error {
+			config.Curve = cert.Curve(answer)
+			return check()
+		})
+	}
+	if err != nil {
+		return err
+	}
+	err = w.askValid("Output directory", config.OutputDir, func(answer string) error {
+		config.OutputDir = answer
+		return check()
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := writeInitConfig(path, config); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\nWrote %s. Generate the root CA with: certgen ca -c %s\n\n", path, path)
+
+	generate, err := w.confirm("Generate the root CA now?", true)
+	if err != nil || !generate {
+		return err
+	}
+	_, err = cert.GenerateCA(config)
+	return err
+}
+
+// writeInitConfig writes the settings chosen in the wizard as a commented
+// CA configuration file
+func writeInitConfig(path string, config *cert.CAConfig) error {
+	var b strings.Builder
+	fmt.Fprintln(&b, "# Root CA configuration written by certgen init")
+	fmt.Fprintln(&b, "# See config/ca.yaml in the certgen sources for all settings")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "type: 0  # 0 for Root, 1 for Intermediate")
+	fmt.Fprintf(&b, "class: %d\n", config.Class)
+	fmt.Fprintln(&b)
+	fmt.Fprintf(&b, "commonName: %s\n", yamlScalar(config.CommonName))
+	fmt.Fprintf(&b, "organization: %s\n", yamlScalar(config.Organization[0]))
+	fmt.Fprintf(&b, "country: %s\n", yamlScalar(config.Country[0]))
+	fmt.Fprintln(&b)
+	fmt.Fprintf(&b, "validityDays: %d\n", config.ValidityDays)
+	fmt.Fprintf(&b, "keyType: %s\n", config.KeyType)
+	switch config.KeyType {
+	case cert.KeyTypeRSA:
+		fmt.Fprintf(&b, "keySize: %d\n", config.KeySize)
+	case cert.KeyTypeECDSA:
+		fmt.Fprintf(&b, "curve: %s\n", config.Curve)
+	}
+	fmt.Fprintln(&b)
+	fmt.Fprintf(&b, "outputDir: %s\n", yamlScalar(config.OutputDir))
+
+	flag := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if config.Force {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flag, 0644)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", path, err)
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// yamlScalar encodes a string as a YAML scalar, quoting it where needed
+func yamlScalar(s string) string {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return strconv.Quote(s)
+	}
+	return strings.TrimSuffix(string(data), "\n")
+}
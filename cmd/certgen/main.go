@@ -1,29 +1,23 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"text/tabwriter"
 
+	"github.com/BurntSushi/toml"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 
 	"certgen/internal/cert"
+	"certgen/internal/kms"
 )
 
-var rootCmd = &cobra.Command{
-	Use:   "certgen",
-	Short: "A certificate generation and management tool",
-	Long: `A tool for generating and managing certificates with support for
-different certificate classes and trust operations.
-
-Certificate Classes:
-  Class 1: Low-assurance certificates (email, personal use)
-  Class 2: Medium-assurance certificates (organization validation)
-  Class 3: High-assurance certificates (extended validation)`,
-}
-
 var helpCmd = &cobra.Command{
 	Use:   "classes",
 	Short: "Display information about certificate classes",
@@ -64,10 +58,25 @@ var completeHelpCmd = &cobra.Command{
 		fmt.Fprintln(w, "-------\t-----------\t-----")
 		fmt.Fprintln(w, "ca\tGenerate a CA certificate\tcertgen ca [flags]")
 		fmt.Fprintln(w, "cert\tGenerate a server/client certificate\tcertgen cert [flags]")
+		fmt.Fprintln(w, "csr\tGenerate a certificate signing request\tcertgen csr [flags]")
+		fmt.Fprintln(w, "renew\tRenew a certificate with its existing key\tcertgen renew [flags]")
+		fmt.Fprintln(w, "revoke\tRevoke a certificate and regenerate the CRL\tcertgen revoke [flags]")
+		fmt.Fprintln(w, "ocsp-respond\tAnswer OCSP requests for a CA\tcertgen ocsp-respond --ca ca.crt --ca-key ca.key")
+		fmt.Fprintln(w, "pki\tGenerate a root, intermediates and leaves\tcertgen pki -c hierarchy.yaml")
+		fmt.Fprintln(w, "batch\tGenerate many certificates from one CA concurrently\tcertgen batch -c batch.yaml")
 		fmt.Fprintln(w, "install\tInstall a certificate\tcertgen install [flags]")
 		fmt.Fprintln(w, "trust\tTrust a CA certificate\tcertgen trust [flags]")
+		fmt.Fprintln(w, "untrust\tRemove a trusted CA certificate\tcertgen untrust --cert ca.crt")
+		fmt.Fprintln(w, "scan\tReport expiring and expired certificates\tcertgen scan --dir certs --warn 30d")
+		fmt.Fprintln(w, "list-trusted\tList trusted CA certificates\tcertgen list-trusted [--all]")
+		fmt.Fprintln(w, "verify\tVerify a certificate against a CA\tcertgen verify [flags]")
+		fmt.Fprintln(w, "bundle\tOrder certificates into a chain bundle\tcertgen bundle --in a.crt --in b.crt")
+		fmt.Fprintln(w, "convert\tConvert between PEM, DER and PKCS#12\tcertgen convert --in cert.pem --out cert.der")
 		fmt.Fprintln(w, "classes\tShow certificate class information\tcertgen classes")
 		fmt.Fprintln(w, "help-all\tShow this help message\tcertgen help-all")
+		fmt.Fprintln(w, "init\tInteractively set up a root CA\tcertgen init [--out ca.yaml]")
+		fmt.Fprintln(w, "version\tPrint the version, commit and build date\tcertgen version")
+		fmt.Fprintln(w, "completion\tGenerate a shell completion script\tcertgen completion bash|zsh|fish|powershell")
 
 		// Common Flags
 		fmt.Fprintln(w, "\nCommon Flags:")
@@ -77,24 +86,118 @@ var completeHelpCmd = &cobra.Command{
 		fmt.Fprintln(w, "--common-name\tCommon Name for the certificate\t-")
 		fmt.Fprintln(w, "--org\tOrganization name\t-")
 		fmt.Fprintln(w, "--country\tCountry code\t-")
-		fmt.Fprintln(w, "--validity\tValidity period in days\tClass dependent")
+		fmt.Fprintln(w, "--validity\tValidity period in days, or a duration such as 90d, 1y or 8760h\tClass dependent")
+		fmt.Fprintln(w, "--not-before\tStart of the validity period, RFC 3339 (ca, cert)\tNow")
+		fmt.Fprintln(w, "--validity-not-after\tEnd of the validity period, RFC 3339 (ca, cert)\t-")
+		fmt.Fprintln(w, "--backdate\tDuration to move the start of validity back (ca, cert)\t-")
 		fmt.Fprintln(w, "--key-size\tKey size in bits\tClass dependent")
-		fmt.Fprintln(w, "--output-dir\tOutput directory for certificates\t./certs")
+		fmt.Fprintln(w, "--key-type\tKey type (rsa, ecdsa, ed25519)\trsa")
+		fmt.Fprintln(w, "--curve\tECDSA curve (P256, P384, P521)\tClass dependent")
+		fmt.Fprintln(w, "--key-format\tPrivate key format (pkcs8, pkcs1 for rsa) (ca, cert, csr, convert)\tpkcs8")
+		fmt.Fprintln(w, "--cert-file-mode\tOctal permissions of written certificates (ca, cert, csr)\t0644")
+		fmt.Fprintln(w, "--key-file-mode\tOctal permissions of written keys and keystores (ca, cert, csr)\t0600")
+		fmt.Fprintln(w, "--world-readable-key\tAllow a key file mode readable by others (ca, cert, csr)\tfalse")
+		fmt.Fprintln(w, "--dir-mode\tOctal permissions of created output directories (ca, cert, csr, sign, renew, revoke, trust, bundle, convert)\t0755")
+		fmt.Fprintln(w, "--enforce-dir-mode\tAlso apply --dir-mode to an existing output directory (ca, cert, csr, sign, renew, revoke, trust, bundle, convert)\tfalse")
+		fmt.Fprintln(w, "--output-dir\tOutput directory, overrides outputDir in the config (ca, cert, csr, sign, renew, revoke, trust, batch; pki: ./pki)\t./certs")
 		fmt.Fprintln(w, "--no-progress\tDisable progress display\tfalse")
-		fmt.Fprintln(w, "--root\tGenerate a root certificate\tfalse")
+		fmt.Fprintln(w, "--quiet, -q\tPrint nothing on success, not even warnings\tfalse")
+		fmt.Fprintln(w, "--force\tOverwrite existing output files (ca, cert, csr, sign, renew, pki, batch, bundle, convert, trust)\tfalse")
+		fmt.Fprintln(w, "--config, -c\tPath to configuration file, - for stdin (flags override it)\t-")
+		fmt.Fprintln(w, "CERTGEN_<FLAG>\tEnv variable setting a flag, e.g. CERTGEN_OUTPUT_DIR (overrides the config file)\t-")
+		fmt.Fprintln(w, "--root\tGenerate a root certificate (ca)\tfalse")
+		fmt.Fprintln(w, "--parent-cert\tParent CA certificate for an intermediate (ca)\t-")
+		fmt.Fprintln(w, "--parent-key\tParent CA private key for an intermediate (ca)\t-")
+		fmt.Fprintln(w, "--permitted-dns-domains\tDNS domains the CA may issue for (ca)\t-")
+		fmt.Fprintln(w, "--excluded-dns-domains\tDNS domains the CA may not issue for (ca)\t-")
+		fmt.Fprintln(w, "--permitted-ip-ranges\tCIDR ranges the CA may issue for (ca)\t-")
+		fmt.Fprintln(w, "--excluded-ip-ranges\tCIDR ranges the CA may not issue for (ca)\t-")
+		fmt.Fprintln(w, "--permitted-emails\tMailboxes or domains the CA may issue for (ca)\t-")
+		fmt.Fprintln(w, "--excluded-emails\tMailboxes or domains the CA may not issue for (ca)\t-")
+		fmt.Fprintln(w, "--dns-names\tComma-separated DNS names (ca, cert, csr)\tCommon name (cert, csr)")
+		fmt.Fprintln(w, "--ip-addresses\tComma-separated IP addresses (cert, csr)\t-")
+		fmt.Fprintln(w, "--email-addresses\tComma-separated email addresses (ca, cert, csr)\tRequired for Class 1 (cert, csr)")
+		fmt.Fprintln(w, "--uris\tComma-separated URIs (ca, cert, csr)\t-")
+		fmt.Fprintln(w, "--nss\tAlso update Firefox/Chromium NSS databases (trust, untrust)\tfalse")
+		fmt.Fprintln(w, "--no-sudo\tRun trust store commands without sudo (trust, untrust)\tfalse")
+		fmt.Fprintln(w, "--escalation\tPrivilege escalation command, e.g. doas or pkexec (trust, untrust)\tsudo")
+		fmt.Fprintln(w, "--dir\tDirectory to scan (scan)\tcerts")
+		fmt.Fprintln(w, "--warn\tExpiry warning period (scan)\t30d")
+		fmt.Fprintln(w, "--json\tWrite the results as JSON (scan)\tfalse")
+		fmt.Fprintln(w, "--all\tAlso list the CAs shipped with the OS (list-trusted)\tfalse")
+		fmt.Fprintln(w, "--user\tTrust for the current user only (trust, untrust, list-trusted)\tfalse")
+		fmt.Fprintln(w, "--purpose\tCertificate purpose: class or ocsp-signing (cert)\tclass")
+		fmt.Fprintln(w, "--profile\tPolicy profile: cabf, spiffe, client, codesign or timestamping (cert)\t-")
+		fmt.Fprintln(w, "--spiffe-id\tSPIFFE ID of an X.509-SVID, with --profile spiffe (cert)\t-")
+		fmt.Fprintln(w, "--common-name-san\tWhen a hostname common name is not a DNS name: add, warn or error (cert)\tadd")
+		fmt.Fprintln(w, "--key-usages\tKey usages replacing the defaults (ca, cert)\tType dependent")
+		fmt.Fprintln(w, "--signature-algorithm\tSignature algorithm, e.g. SHA384WithRSA (ca, cert, sign, renew)\tSHA-256 or curve hash")
+		fmt.Fprintln(w, "--ext-key-usages\tExtended key usages replacing the class defaults (cert)\tClass defaults")
+		fmt.Fprintln(w, "--full-chain\tWrite fullchain.crt (cert)\ttrue")
+		fmt.Fprintln(w, "--full-chain-root\tInclude the self-signed root in the full chain (cert)\tfalse")
+		fmt.Fprintln(w, "--no-verify\tSkip verifying the issued certificate against its CA (cert, renew)\tfalse")
+		fmt.Fprintln(w, "--ca-chain\tFiles holding the CAs above the CA certificate (cert)\t-")
+		fmt.Fprintln(w, "--stdout\tWrite the certificate and chain PEM to stdout instead of files (cert)\tfalse")
+		fmt.Fprintln(w, "--stdout-key\tAlso write the private key to stdout (cert)\tfalse")
+		fmt.Fprintln(w, "--crl-urls\tComma-separated CRL distribution points (ca, cert)\tIssuing CA's")
+		fmt.Fprintln(w, "--ocsp-urls\tComma-separated OCSP responder URLs (ca, cert)\tIssuing CA's")
+		fmt.Fprintln(w, "--ca-issuers-urls\tComma-separated AIA CA Issuers URLs (ca, cert)\tIssuing CA's")
+		fmt.Fprintln(w, "--policy-oids\tComma-separated certificate policy OIDs (ca, cert)\tClass defaults")
+		fmt.Fprintln(w, "--key\tExisting private key instead of generating one (ca, cert, csr)\t-")
+		fmt.Fprintln(w, "--create-kms-key\tCreate the CA key in Cloud KMS or AWS KMS (ca)\t-")
+		fmt.Fprintln(w, "--serial-mode\tSerial numbers: random or sequential (ca, cert, sign, renew)\trandom")
+		fmt.Fprintln(w, "--serial\tSerial number, decimal or hex: explicit (ca, cert) or to revoke (revoke)\tRandom")
+		fmt.Fprintln(w, "--ca-cert\tPath to the CA certificate (cert, sign, batch)\t-")
+		fmt.Fprintln(w, "--ca-key\tPath to the CA private key (cert, sign, batch)\t-")
+		fmt.Fprintln(w, "--cert\tPath to the certificate (sign, trust)\t-")
+		fmt.Fprintln(w, "--key\tPath to the certificate's private key (sign)\t-")
+		fmt.Fprintln(w, "--csr\tPath to a certificate request (sign)\t-")
+		fmt.Fprintln(w, "--is-ca\tIssue a CA certificate (sign)\tfalse")
+		fmt.Fprintln(w, "--file-name\tBase name of the output files (ca, cert, sign, trust)\tCommand dependent")
+		fmt.Fprintln(w, "--file-name-from-cn\tName the output files after the common name (ca, cert)\tfalse")
+		fmt.Fprintln(w, "--dry-run\tPrint a summary without writing files (ca, cert, sign)\tfalse")
+		fmt.Fprintln(w, "--insecure-fast-keys\tINSECURE: allow small keys that generate quickly, for tests only (ca, cert)\tfalse")
+		fmt.Fprintln(w, "--report\tPrint a JSON report of the certificate and written files (ca, cert, sign)\t-")
+		fmt.Fprintln(w, "--format\tOutput format: pem, pkcs12, der, pkcs7 or, for cert, jks (ca, cert)\tpem")
+		fmt.Fprintln(w, "--alias\tAlias of the key entry in a jks keystore (cert)\tFile name")
+		fmt.Fprintln(w, "--keystore-password-env\tEnv variable holding the jks keystore password (cert)\tPassphrase")
+		fmt.Fprintln(w, "--keystore-password-file\tFile holding the jks keystore password (cert)\tPassphrase")
+		fmt.Fprintln(w, "--public-key\tAlso write the public key to a .pub file (ca, cert)\tfalse")
+		fmt.Fprintln(w, "--fingerprint\tPrint the SHA-1 and SHA-256 fingerprints (ca, cert, sign)\tfalse")
+		fmt.Fprintln(w, "--fingerprint-file\tWrite the SHA-256 fingerprint to a .sha256 file (ca, cert, sign)\tfalse")
+		fmt.Fprintln(w, "--encrypt-key\tEncrypt the private key (ca, cert, convert)\tfalse")
+		fmt.Fprintln(w, "--passphrase-env\tEnv variable holding the key passphrase (ca, cert, convert)\t-")
+		fmt.Fprintln(w, "--passphrase-file\tFile holding the key passphrase (ca, cert, convert)\t-")
+		fmt.Fprintln(w, "--ca-passphrase-env\tEnv variable holding the CA key passphrase (cert, sign, batch)\t-")
+		fmt.Fprintln(w, "--ca-passphrase-file\tFile holding the CA key passphrase (cert, sign, batch)\t-")
+		fmt.Fprintln(w, "--signer-cert\tDelegated OCSP signing certificate (ocsp-respond)\t-")
+		fmt.Fprintln(w, "--signer-key\tPrivate key of the delegated OCSP signer (ocsp-respond)\t-")
+		fmt.Fprintln(w, "--index\tRevocation store to answer from (ocsp-respond)\t<CA dir>/revocations.json")
+		fmt.Fprintln(w, "--listen\tAddress to listen on (ocsp-respond)\t:8080")
+		fmt.Fprintln(w, "--response-validity\tHours until a response's next update (ocsp-respond)\t24")
+		fmt.Fprintln(w, "--in\tCertificate file to bundle, repeatable (bundle)\t-")
+		fmt.Fprintln(w, "--out\tPath of the ordered bundle (bundle)\tchain.pem")
+		fmt.Fprintln(w, "--in\tPEM, DER or PKCS#12 file to convert (convert)\t-")
+		fmt.Fprintln(w, "--out\tConverted file, its extension picks the format (convert)\t-")
+		fmt.Fprintln(w, "--to\tOutput format: pem, der or pkcs12 (convert)\tFrom --out extension")
+		fmt.Fprintln(w, "--workers\tCertificates issued at once (batch)\tNumber of CPUs")
 
 		// Examples
 		fmt.Fprintln(w, "\nExamples:")
 		fmt.Fprintln(w, "1. Generate a Root CA certificate:")
 		fmt.Fprintln(w, "   certgen ca --root --class 2 --common-name \"My Root CA\" --org \"My Company\"")
 		fmt.Fprintln(w, "\n2. Generate an Intermediate CA certificate:")
-		fmt.Fprintln(w, "   certgen ca --class 2 --common-name \"My Intermediate CA\" --org \"My Company\"")
+		fmt.Fprintln(w, "   certgen ca --class 2 --common-name \"My Intermediate CA\" --org \"My Company\" \\")
+		fmt.Fprintln(w, "     --parent-cert certs/ca.crt --parent-key certs/ca.key --output-dir certs/intermediate")
 		fmt.Fprintln(w, "\n3. Generate a server certificate:")
 		fmt.Fprintln(w, "   certgen cert --class 2 --common-name \"example.com\" --org \"My Company\" --dns-names \"example.com,www.example.com\"")
 		fmt.Fprintln(w, "\n4. Install and trust a CA certificate:")
 		fmt.Fprintln(w, "   certgen install --cert path/to/ca.crt")
 		fmt.Fprintln(w, "   certgen trust --cert path/to/ca.crt")
 
+		fmt.Fprintln(w, "\n5. Verify a certificate chains to its CA:")
+		fmt.Fprintln(w, "   certgen verify --cert certs/cert.crt --ca certs/ca.crt --dns-name example.com")
+
 		// Additional Information
 		fmt.Fprintln(w, "\nFor more information about certificate classes:")
 		fmt.Fprintln(w, "  certgen classes")
@@ -115,32 +218,154 @@ func parseClass(class string) (cert.CertificateClass, error) {
 }
 
 func init() {
-	rootCmd.AddCommand(helpCmd)
-	rootCmd.AddCommand(completeHelpCmd)
+	kms.Register()
 }
 
-// loadConfig loads a YAML configuration file into the provided config struct
+// loadConfig loads a YAML, JSON or TOML configuration file into the provided
+// config struct, picking the format from the file extension and defaulting to
+// YAML. A path of "-" reads YAML or JSON, which is valid YAML, from stdin. An
+// empty path leaves the config untouched so it can be built from flags alone.
 func loadConfig(configFile string, config interface{}) error {
 	if configFile == "" {
-		return fmt.Errorf("configuration file is required")
+		return nil
 	}
-
-	data, err := os.ReadFile(configFile)
+	data, err := readConfig(configFile)
 	if err != nil {
-		return fmt.Errorf("reading config file: %w", err)
+		return err
 	}
-
 	if err := yaml.Unmarshal(data, config); err != nil {
 		return fmt.Errorf("parsing config file: %w", err)
 	}
-
 	return nil
 }
 
+// loadCertConfig loads a certificate configuration like loadConfig, unless
+// the file lists certificates for a service mesh. Then its shared ca section
+// is loaded into config and the mesh configuration is returned as well.
+func loadCertConfig(configFile string, config *cert.CertConfig) (*cert.MeshConfig, error) {
+	if configFile == "" {
+		return nil, nil
+	}
+	data, err := readConfig(configFile)
+	if err != nil {
+		return nil, err
+	}
+	var probe struct {
+		Certificates []interface{} `yaml:"certificates"`
+	}
+	if err := yaml.Unmarshal(data, &probe); err != nil || len(probe.Certificates) == 0 {
+		if err := yaml.Unmarshal(data, config); err != nil {
+			return nil, fmt.Errorf("parsing config file: %w", err)
+		}
+		return nil, nil
+	}
+
+	mesh := &cert.MeshConfig{CA: *config}
+	if err := yaml.Unmarshal(data, mesh); err != nil {
+		return nil, fmt.Errorf("parsing config file: %w", err)
+	}
+	*config = mesh.CA
+	config.Quiet = config.Quiet || mesh.Quiet
+	return mesh, nil
+}
+
+// readConfig reads a configuration file, or stdin for "-", as YAML
+func readConfig(configFile string) ([]byte, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if configFile == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(configFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	// JSON and TOML are converted to YAML so the yaml tags stay the single
+	// description of the config fields, including those never read from a file
+	var values map[string]interface{}
+	switch strings.ToLower(filepath.Ext(configFile)) {
+	case ".json":
+		if err := json.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("parsing JSON config file: %w", err)
+		}
+	case ".toml":
+		if err := toml.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("parsing TOML config file: %w", err)
+		}
+	}
+	if values != nil {
+		if data, err = yaml.Marshal(values); err != nil {
+			return nil, fmt.Errorf("converting config file: %w", err)
+		}
+	}
+	return data, nil
+}
+
 func main() {
 	var (
 		configFile string
 		noProgress bool
+		quiet      bool
+		dryRun     bool
+		force      bool
+
+		insecureFastKeys bool
+
+		caFlags, certFlagValues certFlags
+		root                    bool
+		parentCert, parentKey   string
+		createKMSKey            string
+		parentPassphraseEnv     string
+		parentPassphraseFile    string
+		permittedDNSDomains     []string
+		excludedDNSDomains      []string
+		permittedIPRanges       []string
+		excludedIPRanges        []string
+		permittedEmails         []string
+		excludedEmails          []string
+		fullChain               bool
+		fullChainRoot           bool
+		noVerify                bool
+		caChain                 []string
+		dnsNames                []string
+		ipAddresses             []string
+		emailAddresses          []string
+		uris                    []string
+		certPurpose             string
+		commonNameSAN           string
+		profile                 string
+		spiffeID                string
+		stdout, stdoutKey       bool
+		extKeyUsageNames        []string
+		keystoreAlias           string
+		keystorePasswordEnv     string
+		keystorePasswordFile    string
+		certPath, keyPath       string
+		caCertPath, caKeyPath   string
+		signOutputDir           string
+		signCSRPath             string
+		signValidityDays        int
+		signIsCA                bool
+		signFileName            string
+		signSignatureAlgorithm  string
+		signReport              string
+		signFingerprint         bool
+		signFingerprintFile     bool
+		serialMode              string
+		caPassphraseEnv         string
+		caPassphraseFile        string
+		keyPassphraseEnv        string
+		keyPassphraseFile       string
+		trustOutputDir          string
+		trustFileName           string
+		nss                     bool
+		userScope               bool
+		noSudo                  bool
+		escalation              string
 	)
 
 	rootCmd := &cobra.Command{
@@ -148,11 +373,33 @@ func main() {
 		Short: "A tool for generating and managing certificates",
 		Long: `certgen is a tool for generating and managing certificates.
 It supports generating CA certificates, server certificates, and client certificates.`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return applyEnv(cmd)
+		},
 	}
 
 	// Global flags
-	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "Path to configuration file")
+	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "Path to configuration file, or - to read it from stdin")
 	rootCmd.PersistentFlags().BoolVar(&noProgress, "no-progress", false, "Disable progress display")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Print nothing on success, not even warnings")
+
+	// Init command
+	var initOut string
+	initCmd := &cobra.Command{
+		Use:   "init",
+		Short: "Interactively write a root CA configuration and generate the CA",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := &cert.CAConfig{
+				NoProgress: noProgress,
+				Quiet:      quiet,
+				Force:      force,
+			}
+			return runInit(cmd.InOrStdin(), cmd.OutOrStdout(), initOut, config)
+		},
+	}
+	initCmd.Flags().StringVar(&initOut, "out", "ca.yaml", "Path of the configuration file to write")
+	initCmd.Flags().BoolVar(&force, "force", false, "Overwrite an existing configuration file and CA")
 
 	// CA command
 	caCmd := &cobra.Command{
@@ -161,14 +408,94 @@ It supports generating CA certificates, server certificates, and client certific
 		RunE: func(cmd *cobra.Command, args []string) error {
 			config := &cert.CAConfig{
 				NoProgress: noProgress,
+				Quiet:      quiet,
+				Force:      force,
+				DryRun:     dryRun,
+
+				InsecureFastKeys: insecureFastKeys,
 			}
 			if err := loadConfig(configFile, config); err != nil {
 				return err
 			}
+			if err := caFlags.applyCA(cmd, config); err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("parent-cert") {
+				config.ParentCert = parentCert
+				config.Type = cert.Intermediate // Unless --root says otherwise
+			}
+			if cmd.Flags().Changed("parent-key") {
+				config.ParentKey = parentKey
+			}
+			if cmd.Flags().Changed("create-kms-key") {
+				config.CreateKMSKey = createKMSKey
+			}
+			if cmd.Flags().Changed("parent-passphrase-env") {
+				config.ParentPassphraseEnv = parentPassphraseEnv
+			}
+			if cmd.Flags().Changed("parent-passphrase-file") {
+				config.ParentPassphraseFile = parentPassphraseFile
+			}
+			if cmd.Flags().Changed("dns-names") {
+				config.DNSNames = dnsNames
+			}
+			if cmd.Flags().Changed("email-addresses") {
+				config.EmailAddresses = emailAddresses
+			}
+			if cmd.Flags().Changed("uris") {
+				config.URIs = uris
+			}
+			if cmd.Flags().Changed("permitted-dns-domains") {
+				config.PermittedDNSDomains = permittedDNSDomains
+			}
+			if cmd.Flags().Changed("excluded-dns-domains") {
+				config.ExcludedDNSDomains = excludedDNSDomains
+			}
+			if cmd.Flags().Changed("permitted-ip-ranges") {
+				config.PermittedIPRanges = permittedIPRanges
+			}
+			if cmd.Flags().Changed("excluded-ip-ranges") {
+				config.ExcludedIPRanges = excludedIPRanges
+			}
+			if cmd.Flags().Changed("permitted-emails") {
+				config.PermittedEmailAddresses = permittedEmails
+			}
+			if cmd.Flags().Changed("excluded-emails") {
+				config.ExcludedEmailAddresses = excludedEmails
+			}
+			if cmd.Flags().Changed("root") {
+				if root {
+					config.Type = cert.Root
+				} else {
+					config.Type = cert.Intermediate
+				}
+			}
+			applyDirMode(cmd, &config.DirMode, &config.EnforceDirMode)
 			_, err := cert.GenerateCA(config)
 			return err
 		},
 	}
+	caFlags.register(caCmd)
+	caFlags.registerIssuance(caCmd)
+	caCmd.Flags().BoolVar(&root, "root", false, "Generate a root certificate")
+	caCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print what would be generated without writing any files")
+	caCmd.Flags().BoolVar(&insecureFastKeys, insecureFastKeysFlag, false, "INSECURE: allow 1024-bit RSA and P256 keys below the class requirements, for tests only")
+	caCmd.Flags().StringVar(&parentCert, "parent-cert", "", "Parent CA certificate, generates an intermediate CA")
+	caCmd.Flags().StringVar(&parentKey, "parent-key", "", "Parent CA private key")
+	caCmd.Flags().StringVar(&createKMSKey, "create-kms-key", "", "Create the CA key in KMS, e.g. gcpkms://projects/.../cryptoKeys/KEY or awskms:///alias/NAME")
+	caCmd.Flags().StringVar(&parentPassphraseEnv, "parent-passphrase-env", "", "Environment variable holding the parent CA key passphrase")
+	caCmd.Flags().StringVar(&parentPassphraseFile, "parent-passphrase-file", "", "File holding the parent CA key passphrase")
+	caCmd.Flags().StringSliceVar(&dnsNames, "dns-names", nil, "Comma-separated DNS names identifying the CA itself")
+	caCmd.Flags().StringSliceVar(&emailAddresses, "email-addresses", nil, "Comma-separated email addresses identifying the CA itself")
+	caCmd.Flags().StringSliceVar(&uris, "uris", nil, "Comma-separated URIs identifying the CA itself")
+	caCmd.Flags().StringSliceVar(&permittedDNSDomains, "permitted-dns-domains", nil, "Comma-separated DNS domains the CA may issue for (name constraints)")
+	caCmd.Flags().StringSliceVar(&excludedDNSDomains, "excluded-dns-domains", nil, "Comma-separated DNS domains the CA may not issue for (name constraints)")
+	caCmd.Flags().StringSliceVar(&permittedIPRanges, "permitted-ip-ranges", nil, "Comma-separated CIDR ranges the CA may issue for (name constraints)")
+	caCmd.Flags().StringSliceVar(&excludedIPRanges, "excluded-ip-ranges", nil, "Comma-separated CIDR ranges the CA may not issue for (name constraints)")
+	caCmd.Flags().StringSliceVar(&permittedEmails, "permitted-emails", nil, "Comma-separated mailboxes or domains the CA may issue for (name constraints)")
+	caCmd.Flags().StringSliceVar(&excludedEmails, "excluded-emails", nil, "Comma-separated mailboxes or domains the CA may not issue for (name constraints)")
+	caCmd.Flags().BoolVar(&force, "force", false, "Overwrite existing output files")
+	registerDirMode(caCmd)
 
 	// Certificate command
 	certCmd := &cobra.Command{
@@ -177,13 +504,197 @@ It supports generating CA certificates, server certificates, and client certific
 		RunE: func(cmd *cobra.Command, args []string) error {
 			config := &cert.CertConfig{
 				NoProgress: noProgress,
+				Quiet:      quiet,
+				Force:      force,
+				DryRun:     dryRun,
+
+				InsecureFastKeys: insecureFastKeys,
+			}
+			mesh, err := loadCertConfig(configFile, config)
+			if err != nil {
+				return err
+			}
+			if err := certFlagValues.applyCert(cmd, config); err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("dns-names") {
+				config.DNSNames = dnsNames
+			}
+			if cmd.Flags().Changed("ip-addresses") {
+				config.IPAddresses = ipAddresses
+			}
+			if cmd.Flags().Changed("email-addresses") {
+				config.EmailAddresses = emailAddresses
+			}
+			if cmd.Flags().Changed("uris") {
+				config.URIs = uris
+			}
+			if cmd.Flags().Changed("ca-cert") {
+				config.CACert = caCertPath
+			}
+			if cmd.Flags().Changed("ca-key") {
+				config.CAKey = caKeyPath
+			}
+			if cmd.Flags().Changed("ca-passphrase-env") {
+				config.CAPassphraseEnv = caPassphraseEnv
+			}
+			if cmd.Flags().Changed("ca-passphrase-file") {
+				config.CAPassphraseFile = caPassphraseFile
+			}
+			if cmd.Flags().Changed("ca-chain") {
+				config.CAChain = caChain
+			}
+			if cmd.Flags().Changed("full-chain") {
+				config.WriteFullChain = &fullChain
+			}
+			if cmd.Flags().Changed("full-chain-root") {
+				config.FullChainRoot = fullChainRoot
+			}
+			if cmd.Flags().Changed("no-verify") {
+				config.NoVerify = noVerify
+			}
+			if cmd.Flags().Changed("purpose") {
+				config.CertPurpose = cert.CertPurpose(certPurpose)
+			}
+			if cmd.Flags().Changed("ext-key-usages") {
+				config.ExtKeyUsages = extKeyUsageNames
+			}
+			if cmd.Flags().Changed("common-name-san") {
+				config.CommonNameSAN = cert.CommonNameSAN(commonNameSAN)
+			}
+			if cmd.Flags().Changed("profile") {
+				config.Profile = cert.Profile(profile)
+			}
+			if cmd.Flags().Changed("spiffe-id") {
+				config.SPIFFEID = spiffeID
+			}
+			if (stdout || stdoutKey) && config.ReportOutput != nil {
+				return fmt.Errorf("--report cannot be combined with --stdout, which uses stdout for PEM")
+			}
+			if stdout || stdoutKey {
+				// Keep stdout for the PEM output; warnings go to stderr
+				config.PEMOutput = os.Stdout
+				config.PEMOutputKey = stdoutKey
+				config.ProgressOutput = os.Stderr
+				config.NoProgress = true
+			}
+			if cmd.Flags().Changed("alias") {
+				config.Alias = keystoreAlias
+			}
+			if cmd.Flags().Changed("keystore-password-env") {
+				config.KeystorePasswordEnv = keystorePasswordEnv
+			}
+			if cmd.Flags().Changed("keystore-password-file") {
+				config.KeystorePasswordFile = keystorePasswordFile
+			}
+			applyDirMode(cmd, &config.DirMode, &config.EnforceDirMode)
+			if mesh != nil {
+				// Flags override the shared settings of a mesh configuration
+				if stdout || stdoutKey || config.ReportOutput != nil || config.DryRun {
+					return fmt.Errorf("--stdout, --report and --dry-run issue a single certificate and cannot be used with a certificates list")
+				}
+				if cmd.Flags().Changed("output-dir") {
+					mesh.OutputDir = config.OutputDir
+				}
+				mesh.CA = *config
+				mesh.NoProgress = config.NoProgress
+				mesh.Quiet = config.Quiet
+				mesh.Force = config.Force
+				_, err = cert.GenerateMesh(mesh)
+				return err
+			}
+			_, err = cert.GenerateCertificate(config)
+			return err
+		},
+	}
+	certFlagValues.register(certCmd)
+	certFlagValues.registerIssuance(certCmd)
+	certCmd.Flags().StringSliceVar(&dnsNames, "dns-names", nil, "Comma-separated DNS names (default: common name)")
+	certCmd.Flags().StringSliceVar(&ipAddresses, "ip-addresses", nil, "Comma-separated IP addresses")
+	certCmd.Flags().StringSliceVar(&emailAddresses, "email-addresses", nil, "Comma-separated email addresses (required for Class 1 email protection)")
+	certCmd.Flags().StringSliceVar(&uris, "uris", nil, "Comma-separated URIs")
+	certCmd.Flags().StringVar(&certPurpose, "purpose", "", "Certificate purpose: class or ocsp-signing (default: class)")
+	certCmd.Flags().StringVar(&commonNameSAN, "common-name-san", "", "When a hostname common name is not a DNS name: add, warn or error (default: add)")
+	certCmd.Flags().StringVar(&profile, "profile", "", "Policy profile: cabf for the CA/Browser Forum baseline requirements, spiffe for an X.509-SVID, client for mTLS clients, codesign for code signing, timestamping for an RFC 3161 TSA")
+	certCmd.Flags().StringVar(&spiffeID, "spiffe-id", "", "SPIFFE ID of an X.509-SVID, e.g. spiffe://example.org/web (with --profile spiffe)")
+	completeValues(certCmd, "profile", "cabf\tCA/Browser Forum baseline requirements", "spiffe\tSPIFFE X.509-SVID", "client\tMutual TLS client authentication only", "codesign\tCode signing only", "timestamping\tRFC 3161 time stamping authority")
+	certCmd.Flags().StringSliceVar(&extKeyUsageNames, "ext-key-usages", nil, "Comma-separated extended key usages replacing the class defaults, e.g. clientAuth,timeStamping")
+	certCmd.Flags().StringVar(&caCertPath, "ca-cert", "", "Path to the CA certificate")
+	certCmd.Flags().StringVar(&caKeyPath, "ca-key", "", "Path to the CA private key")
+	certCmd.Flags().StringSliceVar(&caChain, "ca-chain", nil, "Comma-separated files holding the CAs above the CA certificate, for the full chain")
+	certCmd.Flags().StringVar(&caPassphraseEnv, "ca-passphrase-env", "", "Environment variable holding the CA key passphrase")
+	certCmd.Flags().StringVar(&caPassphraseFile, "ca-passphrase-file", "", "File holding the CA key passphrase")
+	certCmd.Flags().StringVar(&keystoreAlias, "alias", "", "Alias of the key entry in a jks keystore (default: file name)")
+	certCmd.Flags().StringVar(&keystorePasswordEnv, "keystore-password-env", "", "Environment variable holding the jks keystore password (default: the passphrase)")
+	certCmd.Flags().StringVar(&keystorePasswordFile, "keystore-password-file", "", "File holding the jks keystore password")
+	certCmd.Flags().BoolVar(&fullChain, "full-chain", true, "Write fullchain.crt with the leaf and issuing CAs")
+	certCmd.Flags().BoolVar(&fullChainRoot, "full-chain-root", false, "Include the self-signed root CA in the full chain")
+	certCmd.Flags().BoolVar(&stdout, "stdout", false, "Write the certificate and its chain as PEM to stdout instead of files")
+	certCmd.Flags().BoolVar(&stdoutKey, "stdout-key", false, "Also write the private key to stdout (implies --stdout)")
+	certCmd.Flags().BoolVar(&noVerify, "no-verify", false, "Skip verifying the issued certificate against its CA")
+	certCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print what would be generated without writing any files")
+	certCmd.Flags().BoolVar(&insecureFastKeys, insecureFastKeysFlag, false, "INSECURE: allow 1024-bit RSA and P256 keys below the class requirements, for tests only")
+	certCmd.Flags().BoolVar(&force, "force", false, "Overwrite existing output files")
+	registerDirMode(certCmd)
+
+	// CSR command
+	var (
+		csrFlags                            certFlags
+		csrDNSNames                         []string
+		csrKeyPath                          string
+		csrKeyPassphraseEnv, csrKeyPassFile string
+	)
+	csrCmd := &cobra.Command{
+		Use:   "csr",
+		Short: "Generate a certificate signing request",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := &cert.CSRConfig{
+				NoProgress: noProgress,
+				Quiet:      quiet,
+				Force:      force,
 			}
 			if err := loadConfig(configFile, config); err != nil {
 				return err
 			}
-			return cert.GenerateCertificate(config)
+			if err := csrFlags.applyCSR(cmd, config); err != nil {
+				return err
+			}
+			flags := cmd.Flags()
+			if flags.Changed("dns-names") {
+				config.DNSNames = csrDNSNames
+			}
+			if flags.Changed("ip-addresses") {
+				config.IPAddresses = ipAddresses
+			}
+			if flags.Changed("email-addresses") {
+				config.EmailAddresses = emailAddresses
+			}
+			if flags.Changed("uris") {
+				config.URIs = uris
+			}
+			if flags.Changed("key") {
+				config.KeyPath = csrKeyPath
+			}
+			if flags.Changed("key-passphrase-env") {
+				config.KeyPassphraseEnv = csrKeyPassphraseEnv
+			}
+			if flags.Changed("key-passphrase-file") {
+				config.KeyPassphraseFile = csrKeyPassFile
+			}
+			applyDirMode(cmd, &config.DirMode, &config.EnforceDirMode)
+			return cert.GenerateCSR(config)
 		},
 	}
+	csrFlags.register(csrCmd)
+	csrCmd.Flags().StringSliceVar(&csrDNSNames, "dns-names", nil, "Comma-separated DNS names (default: common name)")
+	csrCmd.Flags().StringSliceVar(&ipAddresses, "ip-addresses", nil, "Comma-separated IP addresses")
+	csrCmd.Flags().StringSliceVar(&emailAddresses, "email-addresses", nil, "Comma-separated email addresses")
+	csrCmd.Flags().StringSliceVar(&uris, "uris", nil, "Comma-separated URIs")
+	csrCmd.Flags().StringVar(&csrKeyPath, "key", "", "Existing private key to use instead of generating one")
+	csrCmd.Flags().StringVar(&csrKeyPassphraseEnv, "key-passphrase-env", "", "Environment variable holding the existing key passphrase")
+	csrCmd.Flags().StringVar(&csrKeyPassFile, "key-passphrase-file", "", "File holding the existing key passphrase")
+	csrCmd.Flags().BoolVar(&force, "force", false, "Overwrite existing output files")
+	registerDirMode(csrCmd)
 
 	// Sign command
 	signCmd := &cobra.Command{
@@ -192,13 +703,309 @@ It supports generating CA certificates, server certificates, and client certific
 		RunE: func(cmd *cobra.Command, args []string) error {
 			config := &cert.SignConfig{
 				NoProgress: noProgress,
+				Quiet:      quiet,
+				Force:      force,
+				DryRun:     dryRun,
 			}
 			if err := loadConfig(configFile, config); err != nil {
 				return err
 			}
+			flags := cmd.Flags()
+			if flags.Changed("cert") {
+				config.CertPath = certPath
+			}
+			if flags.Changed("csr") {
+				config.CSRPath = signCSRPath
+			}
+			if flags.Changed("key") {
+				config.KeyPath = keyPath
+			}
+			if flags.Changed("validity") {
+				config.ValidityDays = signValidityDays
+			}
+			if flags.Changed("is-ca") {
+				config.IsCA = signIsCA
+			}
+			if flags.Changed("serial-mode") {
+				config.SerialMode = cert.SerialMode(serialMode)
+			}
+			if cmd.Flags().Changed("signature-algorithm") {
+				config.SignatureAlgorithm = signSignatureAlgorithm
+			}
+			if cmd.Flags().Changed("report") {
+				if err := checkReportFormat(signReport); err != nil {
+					return err
+				}
+				config.ReportOutput, config.ProgressOutput = os.Stdout, os.Stderr
+			}
+			if flags.Changed("ca-cert") {
+				config.CACertPath = caCertPath
+			}
+			if flags.Changed("ca-key") {
+				config.CAKeyPath = caKeyPath
+			}
+			if flags.Changed("output-dir") {
+				config.OutputDir = signOutputDir
+			}
+			if flags.Changed("file-name") {
+				config.FileName = signFileName
+			}
+			if flags.Changed("fingerprint") {
+				config.Fingerprint = signFingerprint
+			}
+			if flags.Changed("fingerprint-file") {
+				config.WriteFingerprint = signFingerprintFile
+			}
+			if flags.Changed("key-passphrase-env") {
+				config.KeyPassphraseEnv = keyPassphraseEnv
+			}
+			if flags.Changed("key-passphrase-file") {
+				config.KeyPassphraseFile = keyPassphraseFile
+			}
+			if flags.Changed("ca-passphrase-env") {
+				config.CAPassphraseEnv = caPassphraseEnv
+			}
+			if flags.Changed("ca-passphrase-file") {
+				config.CAPassphraseFile = caPassphraseFile
+			}
+			applyDirMode(cmd, &config.DirMode, &config.EnforceDirMode)
 			return cert.SignCertificate(config)
 		},
 	}
+	signCmd.Flags().StringVar(&certPath, "cert", "", "Path to the certificate to re-issue")
+	signCmd.Flags().StringVar(&signCSRPath, "csr", "", "Path to a certificate request to sign instead of --cert")
+	signCmd.Flags().StringVar(&keyPath, "key", "", "Path to the certificate's private key, checked against it (optional)")
+	signCmd.Flags().IntVar(&signValidityDays, "validity", 0, "Validity period in days (default: original validity, 365 for CSRs)")
+	signCmd.Flags().BoolVar(&signIsCA, "is-ca", false, "Issue a CA certificate")
+	signCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print what would be signed without writing any files")
+	signCmd.Flags().StringVar(&serialMode, "serial-mode", "", "Serial numbers: random or sequential, kept next to the CA key (default: random)")
+	signCmd.Flags().StringVar(&signSignatureAlgorithm, "signature-algorithm", "", "Signature algorithm, e.g. SHA384WithRSA or ECDSAWithSHA512 (default: SHA-256, or the curve's hash for ECDSA)")
+	signCmd.Flags().StringVar(&caCertPath, "ca-cert", "", "Path to the CA certificate")
+	signCmd.Flags().StringVar(&caKeyPath, "ca-key", "", "Path to the CA private key")
+	signCmd.Flags().StringVar(&signOutputDir, "output-dir", "", "Output directory for the signed certificate (default: certs)")
+	signCmd.Flags().StringVar(&signFileName, "file-name", "", "Base name of the signed certificate (default: signed)")
+	signCmd.Flags().BoolVar(&signFingerprint, "fingerprint", false, "Print the SHA-1 and SHA-256 fingerprints of the certificate")
+	signCmd.Flags().BoolVar(&signFingerprintFile, "fingerprint-file", false, "Write the SHA-256 fingerprint to a .sha256 file")
+	signCmd.Flags().StringVar(&signReport, "report", "", "Print a report of the certificate and written files to stdout: json")
+	signCmd.Flags().StringVar(&keyPassphraseEnv, "key-passphrase-env", "", "Environment variable holding the certificate key passphrase")
+	signCmd.Flags().StringVar(&keyPassphraseFile, "key-passphrase-file", "", "File holding the certificate key passphrase")
+	signCmd.Flags().StringVar(&caPassphraseEnv, "ca-passphrase-env", "", "Environment variable holding the CA key passphrase")
+	signCmd.Flags().StringVar(&caPassphraseFile, "ca-passphrase-file", "", "File holding the CA key passphrase")
+	signCmd.Flags().BoolVar(&force, "force", false, "Overwrite existing output files")
+	registerDirMode(signCmd)
+
+	// Renew command
+	var (
+		renewCertPath, renewKeyPath     string
+		renewCACertPath, renewCAKeyPath string
+		renewOutputDir                  string
+		renewValidityDays               int
+		renewSignatureAlgorithm         string
+	)
+	renewCmd := &cobra.Command{
+		Use:   "renew",
+		Short: "Renew a certificate, reusing its private key",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := &cert.RenewConfig{
+				NoProgress: noProgress,
+				Quiet:      quiet,
+				Force:      force,
+			}
+			if err := loadConfig(configFile, config); err != nil {
+				return err
+			}
+			flags := cmd.Flags()
+			if flags.Changed("cert") {
+				config.CertPath = renewCertPath
+			}
+			if flags.Changed("key") {
+				config.KeyPath = renewKeyPath
+			}
+			if flags.Changed("ca") {
+				config.CACertPath = renewCACertPath
+			}
+			if flags.Changed("ca-key") {
+				config.CAKeyPath = renewCAKeyPath
+			}
+			if flags.Changed("output-dir") {
+				config.OutputDir = renewOutputDir
+			}
+			if flags.Changed("validity") {
+				config.ValidityDays = renewValidityDays
+			}
+			if flags.Changed("serial-mode") {
+				config.SerialMode = cert.SerialMode(serialMode)
+			}
+			if flags.Changed("signature-algorithm") {
+				config.SignatureAlgorithm = renewSignatureAlgorithm
+			}
+			if flags.Changed("no-verify") {
+				config.NoVerify = noVerify
+			}
+			if flags.Changed("key-passphrase-env") {
+				config.KeyPassphraseEnv = keyPassphraseEnv
+			}
+			if flags.Changed("key-passphrase-file") {
+				config.KeyPassphraseFile = keyPassphraseFile
+			}
+			if flags.Changed("ca-passphrase-env") {
+				config.CAPassphraseEnv = caPassphraseEnv
+			}
+			if flags.Changed("ca-passphrase-file") {
+				config.CAPassphraseFile = caPassphraseFile
+			}
+			applyDirMode(cmd, &config.DirMode, &config.EnforceDirMode)
+			return cert.RenewCertificate(config)
+		},
+	}
+	renewCmd.Flags().StringVar(&renewCertPath, "cert", "", "Path to the certificate to renew")
+	renewCmd.Flags().StringVar(&renewKeyPath, "key", "", "Path to the certificate's existing private key")
+	renewCmd.Flags().StringVar(&renewCACertPath, "ca", "", "Path to the CA certificate")
+	renewCmd.Flags().StringVar(&renewCAKeyPath, "ca-key", "", "Path to the CA private key")
+	renewCmd.Flags().StringVar(&renewOutputDir, "output-dir", "", "Output directory for the renewed certificate (default: certs)")
+	renewCmd.Flags().IntVar(&renewValidityDays, "validity", 0, "Validity period in days (default: original validity)")
+	renewCmd.Flags().StringVar(&serialMode, "serial-mode", "", "Serial numbers: random or sequential, kept next to the CA key (default: random)")
+	renewCmd.Flags().StringVar(&renewSignatureAlgorithm, "signature-algorithm", "", "Signature algorithm, e.g. SHA384WithRSA or ECDSAWithSHA512 (default: SHA-256, or the curve's hash for ECDSA)")
+	renewCmd.Flags().BoolVar(&noVerify, "no-verify", false, "Skip verifying the renewed certificate against its CA")
+	renewCmd.Flags().StringVar(&keyPassphraseEnv, "key-passphrase-env", "", "Environment variable holding the certificate key passphrase")
+	renewCmd.Flags().StringVar(&keyPassphraseFile, "key-passphrase-file", "", "File holding the certificate key passphrase")
+	renewCmd.Flags().StringVar(&caPassphraseEnv, "ca-passphrase-env", "", "Environment variable holding the CA key passphrase")
+	renewCmd.Flags().StringVar(&caPassphraseFile, "ca-passphrase-file", "", "File holding the CA key passphrase")
+	renewCmd.Flags().BoolVar(&force, "force", false, "Overwrite existing output files")
+	registerDirMode(renewCmd)
+
+	// Revoke command
+	var (
+		revokeCACertPath, revokeCAKeyPath string
+		revokeCertPath, revokeSerial      string
+		revokeReason, revokeStorePath     string
+		revokeOutputDir                   string
+		crlValidityDays                   int
+	)
+	revokeCmd := &cobra.Command{
+		Use:   "revoke",
+		Short: "Revoke a certificate and regenerate the CA's CRL",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := &cert.RevokeConfig{
+				NoProgress: noProgress,
+				Quiet:      quiet,
+			}
+			if err := loadConfig(configFile, config); err != nil {
+				return err
+			}
+			flags := cmd.Flags()
+			if flags.Changed("ca") {
+				config.CACertPath = revokeCACertPath
+			}
+			if flags.Changed("ca-key") {
+				config.CAKeyPath = revokeCAKeyPath
+			}
+			if flags.Changed("cert") {
+				config.CertPath = revokeCertPath
+			}
+			if flags.Changed("serial") {
+				config.Serial = revokeSerial
+			}
+			if flags.Changed("reason") {
+				config.Reason = revokeReason
+			}
+			if flags.Changed("store") {
+				config.StorePath = revokeStorePath
+			}
+			if flags.Changed("crl-validity") {
+				config.CRLValidityDays = crlValidityDays
+			}
+			if flags.Changed("output-dir") {
+				config.OutputDir = revokeOutputDir
+			}
+			if flags.Changed("ca-passphrase-env") {
+				config.CAPassphraseEnv = caPassphraseEnv
+			}
+			if flags.Changed("ca-passphrase-file") {
+				config.CAPassphraseFile = caPassphraseFile
+			}
+			applyDirMode(cmd, &config.DirMode, &config.EnforceDirMode)
+			return cert.RevokeCertificate(config)
+		},
+	}
+	revokeCmd.Flags().StringVar(&revokeCACertPath, "ca", "", "Path to the CA certificate")
+	revokeCmd.Flags().StringVar(&revokeCAKeyPath, "ca-key", "", "Path to the CA private key")
+	revokeCmd.Flags().StringVar(&revokeCertPath, "cert", "", "Path to the certificate to revoke")
+	revokeCmd.Flags().StringVar(&revokeSerial, "serial", "", "Serial number to revoke instead of --cert, decimal or hex like cert --serial")
+	revokeCmd.Flags().StringVar(&revokeReason, "reason", "", "Revocation reason, e.g. keyCompromise or superseded (default: unspecified)")
+	revokeCmd.Flags().StringVar(&revokeStorePath, "store", "", "Revocation store (default: <output-dir>/revocations.json)")
+	revokeCmd.Flags().IntVar(&crlValidityDays, "crl-validity", 0, "Days until the CRL's next update (default: 7)")
+	revokeCmd.Flags().StringVar(&revokeOutputDir, "output-dir", "", "Output directory for crl.pem (default: certs)")
+	revokeCmd.Flags().StringVar(&caPassphraseEnv, "ca-passphrase-env", "", "Environment variable holding the CA key passphrase")
+	revokeCmd.Flags().StringVar(&caPassphraseFile, "ca-passphrase-file", "", "File holding the CA key passphrase")
+	registerDirMode(revokeCmd)
+
+	// OCSP responder command
+	var (
+		ocspCACertPath, ocspCAKeyPath string
+		ocspSignerCert, ocspSignerKey string
+		ocspStorePath, ocspListen     string
+		ocspResponseValidity          int
+	)
+	ocspCmd := &cobra.Command{
+		Use:   "ocsp-respond",
+		Short: "Answer OCSP requests for a CA from its revocation store",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := &cert.OCSPConfig{
+				NoProgress: noProgress,
+				Quiet:      quiet,
+			}
+			if err := loadConfig(configFile, config); err != nil {
+				return err
+			}
+			flags := cmd.Flags()
+			if flags.Changed("ca") {
+				config.CACertPath = ocspCACertPath
+			}
+			if flags.Changed("ca-key") {
+				config.CAKeyPath = ocspCAKeyPath
+			}
+			if flags.Changed("signer-cert") {
+				config.SignerCertPath = ocspSignerCert
+			}
+			if flags.Changed("signer-key") {
+				config.SignerKeyPath = ocspSignerKey
+			}
+			if flags.Changed("index") {
+				config.StorePath = ocspStorePath
+			}
+			if flags.Changed("listen") {
+				config.Listen = ocspListen
+			}
+			if flags.Changed("response-validity") {
+				config.ResponseValidityHours = ocspResponseValidity
+			}
+			if flags.Changed("key-passphrase-env") {
+				config.KeyPassphraseEnv = keyPassphraseEnv
+			}
+			if flags.Changed("key-passphrase-file") {
+				config.KeyPassphraseFile = keyPassphraseFile
+			}
+			if flags.Changed("ca-passphrase-env") {
+				config.CAPassphraseEnv = caPassphraseEnv
+			}
+			if flags.Changed("ca-passphrase-file") {
+				config.CAPassphraseFile = caPassphraseFile
+			}
+			return cert.ServeOCSP(config)
+		},
+	}
+	ocspCmd.Flags().StringVar(&ocspCACertPath, "ca", "", "Path to the CA certificate")
+	ocspCmd.Flags().StringVar(&ocspCAKeyPath, "ca-key", "", "Path to the CA private key, unless --signer-cert is used")
+	ocspCmd.Flags().StringVar(&ocspSignerCert, "signer-cert", "", "Delegated OCSP signing certificate issued by the CA")
+	ocspCmd.Flags().StringVar(&ocspSignerKey, "signer-key", "", "Private key of the delegated OCSP signer")
+	ocspCmd.Flags().StringVar(&ocspStorePath, "index", "", "Revocation store maintained by revoke (default: revocations.json next to the CA certificate)")
+	ocspCmd.Flags().StringVar(&ocspListen, "listen", "", "Address to listen on (default: :8080)")
+	ocspCmd.Flags().IntVar(&ocspResponseValidity, "response-validity", 0, "Hours until a response's next update (default: 24)")
+	ocspCmd.Flags().StringVar(&keyPassphraseEnv, "key-passphrase-env", "", "Environment variable holding the signer key passphrase")
+	ocspCmd.Flags().StringVar(&keyPassphraseFile, "key-passphrase-file", "", "File holding the signer key passphrase")
+	ocspCmd.Flags().StringVar(&caPassphraseEnv, "ca-passphrase-env", "", "Environment variable holding the CA key passphrase")
+	ocspCmd.Flags().StringVar(&caPassphraseFile, "ca-passphrase-file", "", "File holding the CA key passphrase")
 
 	// Trust command
 	trustCmd := &cobra.Command{
@@ -207,15 +1014,357 @@ It supports generating CA certificates, server certificates, and client certific
 		RunE: func(cmd *cobra.Command, args []string) error {
 			config := &cert.TrustConfig{
 				NoProgress: noProgress,
+				Quiet:      quiet,
+				Force:      force,
 			}
 			if err := loadConfig(configFile, config); err != nil {
 				return err
 			}
+			if cmd.Flags().Changed("cert") {
+				config.CertPath = certPath
+			}
+			if cmd.Flags().Changed("output-dir") {
+				config.OutputDir = trustOutputDir
+			}
+			if cmd.Flags().Changed("file-name") {
+				config.FileName = trustFileName
+			}
+			if cmd.Flags().Changed("nss") {
+				config.NSS = nss
+			}
+			if cmd.Flags().Changed("user") {
+				config.Scope = "system"
+				if userScope {
+					config.Scope = "user"
+				}
+			}
+			if cmd.Flags().Changed("escalation") {
+				config.Escalation = escalation
+			}
+			if noSudo {
+				config.Escalation = "none"
+			}
+			applyDirMode(cmd, &config.DirMode, &config.EnforceDirMode)
 			return cert.TrustCertificate(config)
 		},
 	}
+	trustCmd.Flags().StringVar(&certPath, "cert", "", "Path to the certificate to trust")
+	trustCmd.Flags().StringVar(&trustOutputDir, "output-dir", "", "Output directory for the trusted certificate (default: certs)")
+	trustCmd.Flags().StringVar(&trustFileName, "file-name", "", "Base name of the copied certificate (default: trusted)")
+	trustCmd.Flags().BoolVar(&nss, "nss", false, "Also trust the certificate in Firefox/Chromium NSS databases")
+	trustCmd.Flags().BoolVar(&userScope, "user", false, "Trust for the current user only, without admin rights (macOS, Windows)")
+	trustCmd.Flags().BoolVar(&noSudo, "no-sudo", false, "Run trust store commands without privilege escalation and print them if they fail")
+	trustCmd.Flags().StringVar(&escalation, "escalation", "", "Command granting administrator rights, e.g. doas or pkexec (default: sudo)")
+	trustCmd.Flags().BoolVar(&force, "force", false, "Install even if already trusted and overwrite an existing copy")
+	registerDirMode(trustCmd)
+
+	// Scan command
+	var (
+		scanDir  string
+		scanWarn string
+		scanJSON bool
+	)
+	scanCmd := &cobra.Command{
+		Use:          "scan",
+		Short:        "Report certificates in a directory that expire soon or have expired",
+		SilenceUsage: true, // Expiring certificates are not a usage error
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := &cert.ScanConfig{}
+			if err := loadConfig(configFile, config); err != nil {
+				return err
+			}
+			flags := cmd.Flags()
+			if flags.Changed("dir") {
+				config.Dir = scanDir
+			}
+			if flags.Changed("warn") {
+				config.Warn = scanWarn
+			}
+			if flags.Changed("json") {
+				config.JSON = scanJSON
+			}
+			_, err := cert.ScanCertificates(config)
+			return err
+		},
+	}
+	scanCmd.Flags().StringVar(&scanDir, "dir", "", "Directory scanned recursively for .crt and .pem files (default: certs)")
+	scanCmd.Flags().StringVar(&scanWarn, "warn", "", "Report certificates expiring within this period, e.g. 30d or 2w (default: 30d)")
+	scanCmd.Flags().BoolVar(&scanJSON, "json", false, "Write every certificate with its status as JSON")
+
+	// List trusted command
+	var listAll bool
+	listTrustedCmd := &cobra.Command{
+		Use:   "list-trusted",
+		Short: "List the CA certificates in the system trust store",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := &cert.ListTrustedConfig{Quiet: quiet}
+			if err := loadConfig(configFile, config); err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("user") {
+				config.Scope = "system"
+				if userScope {
+					config.Scope = "user"
+				}
+			}
+			if cmd.Flags().Changed("all") {
+				config.All = listAll
+			}
+			return cert.ListTrustedCertificates(config)
+		},
+	}
+	listTrustedCmd.Flags().BoolVar(&userScope, "user", false, "List the current user's trusted CAs instead of the system's (macOS, Windows)")
+	listTrustedCmd.Flags().BoolVar(&listAll, "all", false, "Also list the CAs shipped with the operating system")
+
+	// Untrust command
+	var untrustCertPath string
+	untrustCmd := &cobra.Command{
+		Use:   "untrust",
+		Short: "Remove a previously trusted CA certificate",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := &cert.UntrustConfig{
+				NoProgress: noProgress,
+				Quiet:      quiet,
+			}
+			if err := loadConfig(configFile, config); err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("cert") {
+				config.CertPath = untrustCertPath
+			}
+			if cmd.Flags().Changed("nss") {
+				config.NSS = nss
+			}
+			if cmd.Flags().Changed("user") {
+				config.Scope = "system"
+				if userScope {
+					config.Scope = "user"
+				}
+			}
+			if cmd.Flags().Changed("escalation") {
+				config.Escalation = escalation
+			}
+			if noSudo {
+				config.Escalation = "none"
+			}
+			return cert.UntrustCertificate(config)
+		},
+	}
+	untrustCmd.Flags().StringVar(&untrustCertPath, "cert", "", "Path to the certificate to remove from the trust store")
+	untrustCmd.Flags().BoolVar(&nss, "nss", false, "Also remove the certificate from Firefox/Chromium NSS databases")
+	untrustCmd.Flags().BoolVar(&userScope, "user", false, "Remove the current user's trust instead of the system's (macOS, Windows)")
+	untrustCmd.Flags().BoolVar(&noSudo, "no-sudo", false, "Run trust store commands without privilege escalation and print them if they fail")
+	untrustCmd.Flags().StringVar(&escalation, "escalation", "", "Command granting administrator rights, e.g. doas or pkexec (default: sudo)")
+
+	// PKI command
+	var pkiOutputDir string
+	pkiCmd := &cobra.Command{
+		Use:   "pki",
+		Short: "Generate a root CA, intermediates and leaf certificates in one step",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if configFile == "" {
+				return fmt.Errorf("a hierarchy configuration file is required (--config)")
+			}
+			config := &cert.HierarchyConfig{
+				NoProgress: noProgress,
+				Quiet:      quiet,
+				Force:      force,
+			}
+			if err := loadConfig(configFile, config); err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("output-dir") {
+				config.OutputDir = pkiOutputDir
+			}
+			return cert.GenerateHierarchy(config)
+		},
+	}
+	pkiCmd.Flags().StringVar(&pkiOutputDir, "output-dir", "", "Output directory for the hierarchy (default: pki)")
+	pkiCmd.Flags().BoolVar(&force, "force", false, "Overwrite existing output files")
+
+	// Batch command
+	var (
+		batchOutputDir, batchCACert, batchCAKey     string
+		batchCAPassphraseEnv, batchCAPassphraseFile string
+		workers                                     int
+	)
+	batchCmd := &cobra.Command{
+		Use:   "batch",
+		Short: "Generate many certificates from one CA concurrently",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if configFile == "" {
+				return fmt.Errorf("a batch configuration file is required (--config)")
+			}
+			config := &cert.BatchConfig{
+				NoProgress: noProgress,
+				Quiet:      quiet,
+				Force:      force,
+			}
+			if err := loadConfig(configFile, config); err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("output-dir") {
+				config.OutputDir = batchOutputDir
+			}
+			if cmd.Flags().Changed("ca-cert") {
+				config.CACert = batchCACert
+			}
+			if cmd.Flags().Changed("ca-key") {
+				config.CAKey = batchCAKey
+			}
+			if cmd.Flags().Changed("ca-passphrase-env") {
+				config.CAPassphraseEnv = batchCAPassphraseEnv
+			}
+			if cmd.Flags().Changed("ca-passphrase-file") {
+				config.CAPassphraseFile = batchCAPassphraseFile
+			}
+			if cmd.Flags().Changed("workers") {
+				config.Workers = workers
+			}
+			_, err := cert.GenerateBatch(config)
+			return err
+		},
+	}
+	batchCmd.Flags().StringVar(&batchOutputDir, "output-dir", "", "Output directory for the certificates (default: certs)")
+	batchCmd.Flags().StringVar(&batchCACert, "ca-cert", "", "Path to the CA certificate")
+	batchCmd.Flags().StringVar(&batchCAKey, "ca-key", "", "Path to the CA private key")
+	batchCmd.Flags().StringVar(&batchCAPassphraseEnv, "ca-passphrase-env", "", "Environment variable holding the CA key passphrase")
+	batchCmd.Flags().StringVar(&batchCAPassphraseFile, "ca-passphrase-file", "", "File holding the CA key passphrase")
+	batchCmd.Flags().IntVar(&workers, "workers", 0, "Certificates issued at once (default: number of CPUs)")
+	batchCmd.Flags().BoolVar(&force, "force", false, "Overwrite existing output files")
+
+	// Verify command
+	var (
+		verifyCertPath, verifyCAPath string
+		dnsName, usage               string
+	)
+	verifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verify a certificate against a CA chain",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := &cert.VerifyConfig{Quiet: quiet}
+			if err := loadConfig(configFile, config); err != nil {
+				return err
+			}
+			flags := cmd.Flags()
+			if flags.Changed("cert") {
+				config.CertPath = verifyCertPath
+			}
+			if flags.Changed("ca") {
+				config.CACertPath = verifyCAPath
+			}
+			if flags.Changed("dns-name") {
+				config.DNSName = dnsName
+			}
+			if flags.Changed("usage") {
+				config.Usage = usage
+			}
+			return cert.VerifyCertificate(config)
+		},
+	}
+	verifyCmd.Flags().StringVar(&verifyCertPath, "cert", "", "Path to the certificate to verify")
+	verifyCmd.Flags().StringVar(&verifyCAPath, "ca", "", "Path to the CA certificate(s)")
+	verifyCmd.Flags().StringVar(&dnsName, "dns-name", "", "DNS name the certificate must be valid for")
+	verifyCmd.Flags().StringVar(&usage, "usage", "", "Required key usage: any, server, client, email or code (default: any)")
+
+	// Bundle command
+	var (
+		bundleInputs []string
+		bundleOut    string
+	)
+	bundleCmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Order certificates from leaf to root into a chain bundle",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := &cert.BundleConfig{
+				NoProgress: noProgress,
+				Quiet:      quiet,
+				Force:      force,
+			}
+			if err := loadConfig(configFile, config); err != nil {
+				return err
+			}
+			flags := cmd.Flags()
+			if flags.Changed("in") {
+				config.Inputs = bundleInputs
+			}
+			if flags.Changed("out") {
+				config.OutputPath = bundleOut
+			}
+			applyDirMode(cmd, &config.DirMode, &config.EnforceDirMode)
+			return cert.BundleCertificates(config)
+		},
+	}
+	bundleCmd.Flags().StringArrayVar(&bundleInputs, "in", nil, "Certificate file to bundle, in any order (repeatable)")
+	bundleCmd.Flags().StringVar(&bundleOut, "out", "", "Path of the ordered bundle (default: chain.pem)")
+	bundleCmd.Flags().BoolVar(&force, "force", false, "Overwrite an existing bundle")
+	registerDirMode(bundleCmd)
+
+	// Convert command
+	var (
+		convertIn, convertOut string
+		convertTo, convertKey string
+		convertKeyFormat      string
+		convertEncryptKey     bool
+		convertPassphraseEnv  string
+		convertPassphraseFile string
+	)
+	convertCmd := &cobra.Command{
+		Use:   "convert",
+		Short: "Convert certificates and keys between PEM, DER and PKCS#12",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := &cert.ConvertConfig{
+				NoProgress: noProgress,
+				Quiet:      quiet,
+				Force:      force,
+			}
+			if err := loadConfig(configFile, config); err != nil {
+				return err
+			}
+			flags := cmd.Flags()
+			if flags.Changed("in") {
+				config.Input = convertIn
+			}
+			if flags.Changed("out") {
+				config.Output = convertOut
+			}
+			if flags.Changed("to") {
+				config.Format = cert.OutputFormat(convertTo)
+			}
+			if flags.Changed("key") {
+				config.KeyPath = convertKey
+			}
+			if flags.Changed("key-format") {
+				config.KeyFormat = cert.KeyFormat(convertKeyFormat)
+			}
+			if flags.Changed("encrypt-key") {
+				config.EncryptKey = convertEncryptKey
+			}
+			if flags.Changed("passphrase-env") {
+				config.PassphraseEnv = convertPassphraseEnv
+			}
+			if flags.Changed("passphrase-file") {
+				config.PassphraseFile = convertPassphraseFile
+			}
+			applyDirMode(cmd, &config.DirMode, &config.EnforceDirMode)
+			return cert.ConvertCertificate(config)
+		},
+	}
+	convertCmd.Flags().StringVar(&convertIn, "in", "", "PEM, DER or PKCS#12 file to convert, the format is detected")
+	convertCmd.Flags().StringVar(&convertOut, "out", "", "File to write, its extension picks the format unless --to is set")
+	convertCmd.Flags().StringVar(&convertTo, "to", "", "Output format: pem, der or pkcs12 (default: from the --out extension)")
+	convertCmd.Flags().StringVar(&convertKey, "key", "", "Private key to add to the input, e.g. for PKCS#12 output")
+	convertCmd.Flags().StringVar(&convertKeyFormat, "key-format", "", "Written private key format: pkcs8, or pkcs1 for rsa keys (default: pkcs8)")
+	convertCmd.Flags().BoolVar(&convertEncryptKey, "encrypt-key", false, "Encrypt a PEM or DER private key with the passphrase")
+	convertCmd.Flags().StringVar(&convertPassphraseEnv, "passphrase-env", "", "Environment variable holding the passphrase of encrypted input and output")
+	convertCmd.Flags().StringVar(&convertPassphraseFile, "passphrase-file", "", "File holding the passphrase of encrypted input and output")
+	convertCmd.Flags().BoolVar(&force, "force", false, "Overwrite an existing output file")
+	registerDirMode(convertCmd)
 
-	rootCmd.AddCommand(caCmd, certCmd, signCmd, trustCmd)
+	rootCmd.Version = versionString()
+	rootCmd.SetVersionTemplate("certgen {{.Version}}\n")
+	rootCmd.AddCommand(helpCmd, completeHelpCmd, versionCmd)
+	rootCmd.AddCommand(initCmd, caCmd, certCmd, csrCmd, signCmd, renewCmd, revokeCmd, pkiCmd, batchCmd, trustCmd, untrustCmd, listTrustedCmd, scanCmd, verifyCmd, bundleCmd, convertCmd, ocspCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
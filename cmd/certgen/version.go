@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/spf13/cobra"
+)
+
+// Build information, set at link time with
+// -ldflags "-X main.version=v1.2.3 -X main.commit=abc1234 -X main.date=2024-01-02T03:04:05Z"
+var (
+	version = ""
+	commit  = ""
+	date    = ""
+)
+
+// buildInfo fills in the build information that was not set at link time
+// from what the Go toolchain recorded in the binary
+func buildInfo() (v, c, d string) {
+	v, c, d = version, commit, date
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return orUnknown(v), orUnknown(c), orUnknown(d)
+	}
+	if v == "" && info.Main.Version != "" {
+		v = info.Main.Version
+	}
+	modified := false
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if c == "" {
+				c = setting.Value
+			}
+		case "vcs.time":
+			if d == "" {
+				d = setting.Value
+			}
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if modified && commit == "" && c != "" {
+		c += "-dirty"
+	}
+	return orUnknown(v), orUnknown(c), orUnknown(d)
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
+
+// versionString is the version printed by --version and the version command
+func versionString() string {
+	v, c, d := buildInfo()
+	return fmt.Sprintf("%s (commit %s, built %s, %s %s/%s)", v, c, d, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the version, commit and build date",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Fprintf(cmd.OutOrStdout(), "certgen %s\n", versionString())
+	},
+}
@@ -0,0 +1,120 @@
+package cert
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// BatchResult is the outcome of one certificate of a batch
+type BatchResult struct {
+	Name      string
+	OutputDir string
+	Result    *Result // Nil if the certificate failed
+	Err       error
+}
+
+// GenerateBatch issues the certificates of a BatchConfig concurrently, with
+// at most Workers of them in flight. The CA is loaded once and shared by all
+// workers, since signing does not modify it. Every certificate is attempted
+// even if others fail; the results are returned in configuration order,
+// together with an error if any certificate failed.
+func GenerateBatch(config *BatchConfig) ([]BatchResult, error) {
+	progress := NewGenerationProgress("Certificate Batch", progressOutput(config.ProgressOutput, config.Quiet), !config.NoProgress)
+	defer progress.Complete()
+
+	if err := config.Validate(); err != nil {
+		return nil, withKind(ErrValidation, fmt.Errorf("invalid batch configuration: %w", err))
+	}
+	results, output, err := issueBatch(config, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	// Report every certificate in configuration order
+	failed := 0
+	for i, result := range results {
+		if result.Err != nil {
+			failed++
+			progress.Summary(fmt.Sprintf("✗ %s: %v", result.Name, result.Err))
+		} else {
+			progress.Summary(fmt.Sprintf("✓ %s: %s (%s)", result.Name, result.Result.Certificate.Subject, result.OutputDir))
+		}
+		summarizeOutput(progress, &output[i])
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("%d of %d certificates failed", failed, len(results))
+	}
+	return results, nil
+}
+
+// issueBatch issues the certificates of a validated BatchConfig and returns
+// their results with the warnings and summary lines each one reported
+func issueBatch(config *BatchConfig, progress *GenerationProgress) ([]BatchResult, []bytes.Buffer, error) {
+
+	progress.StartCALoading()
+	ca, err := LoadCABundle(config.CACert, config.CAKey, []byte(config.CAPassphrase))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load CA: %w", err)
+	}
+	progress.CompleteCALoading()
+
+	workers := min(config.Workers, len(config.Certificates))
+	progress.StartProgress(fmt.Sprintf("Issuing %d certificates with %d workers", len(config.Certificates), workers))
+
+	// Each certificate reports its warnings and summary to its own buffer so
+	// that concurrent output does not interleave
+	results := make([]BatchResult, len(config.Certificates))
+	output := make([]bytes.Buffer, len(config.Certificates))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = generateBatchEntry(config, &config.Certificates[i], ca, &output[i])
+			}
+		}()
+	}
+	for i := range config.Certificates {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results, output, nil
+}
+
+// summarizeOutput adds the lines a certificate of a batch reported to the
+// summary, indented below its status
+func summarizeOutput(progress *GenerationProgress, output *bytes.Buffer) {
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		if line != "" {
+			progress.Summary("  " + line)
+		}
+	}
+}
+
+// generateBatchEntry generates one certificate of a batch signed by ca,
+// reporting warnings and summary lines to out
+func generateBatchEntry(config *BatchConfig, spec *BatchSpec, ca *CABundle, out *bytes.Buffer) BatchResult {
+	leaf := spec.CertConfig
+	leaf.NoProgress = true
+	leaf.ProgressOutput = out
+	leaf.Quiet = config.Quiet
+	leaf.Force = config.Force
+	leaf.DryRun = false
+	if leaf.OutputDir == "" {
+		leaf.OutputDir = filepath.Join(config.OutputDir, spec.Name)
+	}
+	// The shared CA replaces any CA of the entry. Its paths, filled in by
+	// GenerateCertificateWithCA, locate the sequential serial numbers, next to
+	// the certificate for a signer key.
+	leaf.CACert = ""
+	leaf.CAKey = ""
+
+	result, err := GenerateCertificateWithCA(&leaf, ca)
+	return BatchResult{Name: spec.Name, OutputDir: leaf.OutputDir, Result: result, Err: err}
+}
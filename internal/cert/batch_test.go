@@ -0,0 +1,51 @@
+package cert
+
+import (
+	"crypto"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBatchSequentialSerialsWithSignerKey(t *testing.T) {
+	dir := t.TempDir()
+	caCert, caKey := writeTestCA(t, filepath.Join(dir, "ca"))
+	RegisterSigner("batchtest", func(ref string) (crypto.Signer, error) {
+		return loadPrivateKey(caKey, nil)
+	})
+
+	entry := func(name string) BatchSpec {
+		return BatchSpec{Name: name, CertConfig: CertConfig{
+			Class:        Class2,
+			CommonName:   name + ".example.com",
+			Organization: StringList{"Test"},
+			Country:      StringList{"US"},
+			KeyType:      KeyTypeECDSA,
+			SerialMode:   SerialSequential,
+		}}
+	}
+	results, err := GenerateBatch(&BatchConfig{
+		CACert:       caCert,
+		CAKey:        "batchtest:ca",
+		OutputDir:    filepath.Join(dir, "out"),
+		Workers:      1,
+		Quiet:        true,
+		Certificates: []BatchSpec{entry("a"), entry("b")},
+	})
+	if err != nil {
+		for _, result := range results {
+			t.Logf("%s: %v", result.Name, result.Err)
+		}
+		t.Fatalf("GenerateBatch: %v", err)
+	}
+
+	// The serials are kept next to the CA certificate, as the key has no file
+	for i, result := range results {
+		if got, want := result.Result.Certificate.SerialNumber.Int64(), int64(i+1); got != want {
+			t.Errorf("%s: serial = %d, want %d", result.Name, got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ca", serialFileName)); err != nil {
+		t.Errorf("serial file next to the CA certificate: %v", err)
+	}
+}
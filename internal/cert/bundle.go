@@ -0,0 +1,109 @@
+package cert
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"path/filepath"
+	"slices"
+)
+
+// BundleCertificates reads the certificates of the input files, orders them
+// from the leaf to the root and writes them as a single PEM chain. It fails
+// if the certificates do not form one connected chain.
+func BundleCertificates(config *BundleConfig) error {
+	progress := NewGenerationProgress("Certificate Bundle", progressOutput(config.ProgressOutput, config.Quiet), !config.NoProgress)
+	defer progress.Complete()
+
+	if err := config.Validate(); err != nil {
+		return withKind(ErrValidation, fmt.Errorf("invalid bundle configuration: %w", err))
+	}
+	if err := checkOverwrite([]string{config.OutputPath}, config.Force); err != nil {
+		return err
+	}
+
+	// Load the certificates, dropping duplicates
+	progress.StartProgress("Loading certificates")
+	var certs []*x509.Certificate
+	for _, path := range config.Inputs {
+		loaded, err := readCertificates(path)
+		if err != nil {
+			return fmt.Errorf("failed to load certificate: %w", err)
+		}
+		for _, cert := range loaded {
+			if !slices.ContainsFunc(certs, func(c *x509.Certificate) bool { return bytes.Equal(c.Raw, cert.Raw) }) {
+				certs = append(certs, cert)
+			}
+		}
+	}
+
+	progress.StartProgress("Ordering certificates")
+	chain, err := orderChain(certs)
+	if err != nil {
+		return err
+	}
+
+	progress.StartProgress("Saving bundle")
+	if dir := filepath.Dir(config.OutputPath); dir != "." {
+		if err := makeOutputDir(dir, config.DirMode, config.EnforceDirMode); err != nil {
+			return withKind(ErrIO, fmt.Errorf("failed to create output directory: %w", err))
+		}
+	}
+	if err := writeChain(config.OutputPath, chain[0].Raw, chain[1:], config.Force); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	for i, cert := range chain {
+		progress.Summary(fmt.Sprintf("  %d: %s", i, cert.Subject))
+	}
+	return nil
+}
+
+// orderChain sorts certificates from the leaf to the root. The leaf is the
+// only certificate that issued none of the others, and every other
+// certificate must be the issuer of the one before it.
+func orderChain(certs []*x509.Certificate) ([]*x509.Certificate, error) {
+	var leaves []*x509.Certificate
+	for _, cert := range certs {
+		if !slices.ContainsFunc(certs, func(c *x509.Certificate) bool { return issuedBy(c, cert) }) {
+			leaves = append(leaves, cert)
+		}
+	}
+	switch {
+	case len(leaves) == 0:
+		return nil, fmt.Errorf("certificates do not form a chain: no leaf certificate found")
+	case len(leaves) > 1:
+		return nil, fmt.Errorf("certificates do not form a single chain: %s and %s are both leaf certificates", leaves[0].Subject, leaves[1].Subject)
+	}
+
+	chain := []*x509.Certificate{leaves[0]}
+	for len(chain) < len(certs) {
+		last := chain[len(chain)-1]
+		i := slices.IndexFunc(certs, func(c *x509.Certificate) bool { return issuedBy(last, c) })
+		if i < 0 {
+			break
+		}
+		if slices.Contains(chain, certs[i]) {
+			return nil, fmt.Errorf("certificates do not form a chain: %s is part of a loop", certs[i].Subject)
+		}
+		chain = append(chain, certs[i])
+	}
+	for _, cert := range certs {
+		if !slices.Contains(chain, cert) {
+			return nil, fmt.Errorf("certificates do not form a single chain: %s is not connected to %s", cert.Subject, chain[0].Subject)
+		}
+	}
+	return chain, nil
+}
+
+// issuedBy reports whether cert was signed by issuer. Self-signed
+// certificates are not considered issued by themselves.
+func issuedBy(cert, issuer *x509.Certificate) bool {
+	if cert == issuer || !bytes.Equal(cert.RawIssuer, issuer.RawSubject) {
+		return false
+	}
+	if len(cert.AuthorityKeyId) > 0 && len(issuer.SubjectKeyId) > 0 && !bytes.Equal(cert.AuthorityKeyId, issuer.SubjectKeyId) {
+		return false
+	}
+	return cert.CheckSignatureFrom(issuer) == nil
+}
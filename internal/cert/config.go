@@ -1,9 +1,27 @@
 package cert
 
 import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
 )
 
 // CertificateClass represents the class of certificate
@@ -30,55 +48,636 @@ const (
 	Leaf
 )
 
+// OutputFormat represents the file format of generated certificates and keys
+type OutputFormat string
+
+const (
+	// FormatPEM writes PEM encoded certificate and key files
+	FormatPEM OutputFormat = "pem"
+	// FormatPKCS12 also writes a passphrase protected PKCS#12 (.p12) bundle
+	// holding the certificate, its private key and the issuing CA chain
+	FormatPKCS12 OutputFormat = "pkcs12"
+	// FormatDER also writes the certificate (.der) and private key (.key.der)
+	// as raw DER, the key as PKCS#8
+	FormatDER OutputFormat = "der"
+	// FormatPKCS7 also writes the certificate and its issuing CA chain as a
+	// DER PKCS#7 (.p7b) bundle, as imported by Windows certificate stores
+	FormatPKCS7 OutputFormat = "pkcs7"
+	// FormatJKS also writes a Java KeyStore (.jks) holding the private key,
+	// certificate and issuing CA chain under a configurable alias
+	FormatJKS OutputFormat = "jks"
+)
+
+// KeyType represents the public key algorithm used for a certificate
+type KeyType string
+
+const (
+	// KeyTypeRSA generates RSA keys sized by keySize
+	KeyTypeRSA KeyType = "rsa"
+	// KeyTypeECDSA generates ECDSA keys on the configured curve
+	KeyTypeECDSA KeyType = "ecdsa"
+	// KeyTypeEd25519 generates Ed25519 keys, which have a fixed size
+	KeyTypeEd25519 KeyType = "ed25519"
+)
+
+// KeyFormat represents the encoding of written private keys
+type KeyFormat string
+
+const (
+	// KeyFormatPKCS8 writes keys as PKCS#8 (PRIVATE KEY)
+	KeyFormatPKCS8 KeyFormat = "pkcs8"
+	// KeyFormatPKCS1 writes RSA keys as PKCS#1 (RSA PRIVATE KEY), as
+	// expected by some older software
+	KeyFormatPKCS1 KeyFormat = "pkcs1"
+)
+
+// Curve represents the named elliptic curve used for ECDSA keys
+type Curve string
+
+const (
+	// CurveP256 is NIST P-256 (secp256r1)
+	CurveP256 Curve = "P256"
+	// CurveP384 is NIST P-384 (secp384r1)
+	CurveP384 Curve = "P384"
+	// CurveP521 is NIST P-521 (secp521r1)
+	CurveP521 Curve = "P521"
+)
+
+// SerialMode selects how serial numbers are assigned to issued certificates
+type SerialMode string
+
+const (
+	// SerialRandom assigns random 128-bit serial numbers
+	SerialRandom SerialMode = "random"
+	// SerialSequential assigns increasing serial numbers kept in a serial
+	// file next to the issuing CA's private key
+	SerialSequential SerialMode = "sequential"
+)
+
+// CertPurpose selects the key usages of a leaf certificate
+type CertPurpose string
+
+const (
+	// PurposeClass uses the extended key usages of the certificate class
+	PurposeClass CertPurpose = "class"
+	// PurposeOCSPSigning issues a delegated OCSP signing certificate with only
+	// the OCSP signing extended key usage and the OCSP no-check extension
+	PurposeOCSPSigning CertPurpose = "ocsp-signing"
+)
+
+// CommonNameSAN selects what happens when a leaf's common name is a hostname
+// that none of its DNS names match. Clients only match hostnames against the
+// SANs, so such a certificate is not valid for its common name.
+type CommonNameSAN string
+
+const (
+	// CommonNameSANAdd adds the common name to the DNS names
+	CommonNameSANAdd CommonNameSAN = "add"
+	// CommonNameSANWarn issues the certificate as configured with a warning
+	CommonNameSANWarn CommonNameSAN = "warn"
+	// CommonNameSANError rejects the configuration
+	CommonNameSANError CommonNameSAN = "error"
+)
+
+// StringList holds the values of a multi-valued subject attribute. In YAML
+// it is either a single string or a list of strings.
+type StringList []string
+
+// UnmarshalYAML accepts a single string as well as a list
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		var single string
+		if err := value.Decode(&single); err != nil {
+			return err
+		}
+		*l = StringList{single}
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// MarshalYAML writes a single value as a string and others as a list
+func (l StringList) MarshalYAML() (interface{}, error) {
+	if len(l) == 1 {
+		return l[0], nil
+	}
+	return []string(l), nil
+}
+
+// values returns the non-empty values of the list, or nil if there are
+// none, so that unset attributes are omitted rather than encoded as empty
+// RDNs
+func (l StringList) values() []string {
+	var values []string
+	for _, value := range l {
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Profile layers additional policy checks onto a leaf configuration
+type Profile string
+
+const (
+	// ProfileCABF enforces the CA/Browser Forum baseline requirements for
+	// publicly trusted TLS server certificates
+	ProfileCABF Profile = "cabf"
+	// ProfileSPIFFE issues an X.509-SVID identifying a workload by its
+	// SPIFFE ID
+	ProfileSPIFFE Profile = "spiffe"
+	// ProfileClient issues a certificate that only authenticates a client
+	// for mutual TLS, its common name naming the user
+	ProfileClient Profile = "client"
+	// ProfileCodeSign issues a certificate that only signs code, its common
+	// name naming the publisher
+	ProfileCodeSign Profile = "codesign"
+	// ProfileTimestamping issues the certificate of an RFC 3161 time
+	// stamping authority
+	ProfileTimestamping Profile = "timestamping"
+)
+
+// cabfMaxValidityDays is the longest validity the CA/Browser Forum allows
+// for a TLS server certificate
+const cabfMaxValidityDays = 398
+
+// Extension is a custom X.509 extension added verbatim to a certificate
+type Extension struct {
+	OID      string `yaml:"oid"`      // Dotted-decimal extension OID
+	Critical bool   `yaml:"critical"` // Mark the extension critical
+	Value    string `yaml:"value"`    // DER encoded extension value
+	Encoding string `yaml:"encoding"` // Encoding of value: base64 (default) or hex
+}
+
 // CAConfig holds the configuration for a Certificate Authority
 type CAConfig struct {
-	CommonName         string           `yaml:"commonName"`
-	Organization       string           `yaml:"organization"`
-	OrganizationalUnit string           `yaml:"organizationalUnit"`
-	Country            string           `yaml:"country"`
-	Province           string           `yaml:"province"`
-	Locality           string           `yaml:"locality"`
-	ValidityDays       int              `yaml:"validityDays"`
-	KeySize            int              `yaml:"keySize"`
-	OutputDir          string           `yaml:"outputDir"`
-	NoProgress         bool             `yaml:"-"` // Not serialized to YAML
-	Class              CertificateClass `yaml:"class"`
-	Type               CertificateType  `yaml:"type"`
+	CommonName              string           `yaml:"commonName"`
+	Organization            StringList       `yaml:"organization"`
+	OrganizationalUnit      StringList       `yaml:"organizationalUnit"`
+	Country                 StringList       `yaml:"country"`
+	Province                StringList       `yaml:"province"`
+	Locality                StringList       `yaml:"locality"`
+	StreetAddress           StringList       `yaml:"streetAddress"`
+	PostalCode              StringList       `yaml:"postalCode"`
+	SerialNumber            string           `yaml:"serialNumber"` // Subject serialNumber attribute, e.g. a company registration number
+	ValidityDays            int              `yaml:"validityDays"`
+	Validity                string           `yaml:"validity"`  // Validity period such as 90d, 1y or 8760h, overrides validityDays
+	NotBefore               string           `yaml:"notBefore"` // Start of the validity period, RFC 3339 (default: now)
+	NotAfter                string           `yaml:"notAfter"`  // End of the validity period, RFC 3339, overrides validity
+	Backdate                string           `yaml:"backdate"`  // Duration NotBefore is moved back by, e.g. 5m
+	KeySize                 int              `yaml:"keySize"`
+	KeyType                 KeyType          `yaml:"keyType"`          // rsa (default), ecdsa or ed25519
+	Curve                   Curve            `yaml:"curve"`            // ECDSA curve: P256, P384 or P521
+	KeyFormat               KeyFormat        `yaml:"keyFormat"`        // pkcs8 (default) or pkcs1 (rsa only)
+	CertFileMode            string           `yaml:"certFileMode"`     // Octal permissions of written certificates (default: 0644)
+	KeyFileMode             string           `yaml:"keyFileMode"`      // Octal permissions of written private keys and keystores (default: 0600)
+	WorldReadableKey        bool             `yaml:"worldReadableKey"` // Allow a keyFileMode that gives other users access
+	OutputDir               string           `yaml:"outputDir"`
+	DirMode                 string           `yaml:"dirMode"`                // Octal permissions of created output directories (default: 0755)
+	EnforceDirMode          bool             `yaml:"enforceDirMode"`         // Also apply dirMode to an existing output directory
+	FileName                string           `yaml:"fileName"`               // Base name of the output files (default: ca)
+	FileNameFromCommonName  bool             `yaml:"fileNameFromCommonName"` // Derive fileName from the common name
+	NoProgress              bool             `yaml:"-"`                      // Not serialized to YAML
+	Quiet                   bool             `yaml:"quiet"`                  // Suppress all progress output and warnings
+	Force                   bool             `yaml:"-"`                      // Overwrite existing output files, never read from YAML
+	ProgressOutput          io.Writer        `yaml:"-"`                      // Destination of progress messages (default: stdout)
+	DryRun                  bool             `yaml:"-"`                      // Validate and summarize without signing or writing files
+	InsecureFastKeys        bool             `yaml:"-"`                      // Allow small, insecure keys that generate quickly, for tests only
+	ReportOutput            io.Writer        `yaml:"-"`                      // Write a JSON report of the certificate and written files here
+	Class                   CertificateClass `yaml:"class"`
+	Format                  OutputFormat     `yaml:"format"`                  // pem (default), pkcs12, der or pkcs7
+	WritePublicKey          bool             `yaml:"writePublicKey"`          // Also write the public key to fileName.pub
+	Fingerprint             bool             `yaml:"fingerprint"`             // Print the SHA-1 and SHA-256 fingerprints
+	WriteFingerprint        bool             `yaml:"writeFingerprint"`        // Write the SHA-256 fingerprint to fileName.sha256
+	SerialMode              SerialMode       `yaml:"serialMode"`              // random (default) or sequential (intermediate only)
+	Serial                  string           `yaml:"serial"`                  // Explicit serial number, decimal or hex with a 0x prefix, hex letters or colons (default: random)
+	EncryptKey              bool             `yaml:"encryptKey"`              // Encrypt the private key with a passphrase
+	Passphrase              string           `yaml:"-"`                       // Passphrase value, never read from YAML
+	PassphraseEnv           string           `yaml:"passphraseEnv"`           // Environment variable holding the passphrase
+	PassphraseFile          string           `yaml:"passphraseFile"`          // File holding the passphrase
+	KeyFile                 string           `yaml:"keyFile"`                 // Existing private key to use instead of generating one
+	CreateKMSKey            string           `yaml:"createKMSKey"`            // Create the key in a key management service under this reference instead of generating one
+	KeyPassphrase           string           `yaml:"-"`                       // Existing key passphrase value, never read from YAML
+	KeyPassphraseEnv        string           `yaml:"keyPassphraseEnv"`        // Environment variable holding the existing key passphrase
+	KeyPassphraseFile       string           `yaml:"keyPassphraseFile"`       // File holding the existing key passphrase
+	CRLDistributionPoints   []string         `yaml:"crlDistributionPoints"`   // CRL URLs, inherited by certificates it issues
+	OCSPServers             []string         `yaml:"ocspServers"`             // OCSP responder URLs, inherited by certificates it issues
+	IssuingCertificateURL   StringList       `yaml:"issuingCertificateURL"`   // Where the CA certificate is published, inherited by certificates it issues
+	PolicyOIDs              []string         `yaml:"policyOIDs"`              // Certificate policy OIDs added to the class defaults
+	ExtraExtensions         []Extension      `yaml:"extraExtensions"`         // Custom extensions added verbatim
+	KeyUsages               []string         `yaml:"keyUsages"`               // Key usages replacing the defaults, must include keyCertSign
+	SignatureAlgorithm      string           `yaml:"signatureAlgorithm"`      // e.g. SHA384WithRSA (default: SHA-256, or the curve's hash for ECDSA)
+	DNSNames                []string         `yaml:"dnsNames"`                // DNS name SANs identifying the CA itself
+	EmailAddresses          []string         `yaml:"emailAddresses"`          // Email SANs identifying the CA itself
+	URIs                    []string         `yaml:"uris"`                    // URI SANs identifying the CA itself, e.g. a SPIFFE trust domain
+	PermittedDNSDomains     []string         `yaml:"permittedDNSDomains"`     // Name constraints: DNS domains the CA may issue for
+	ExcludedDNSDomains      []string         `yaml:"excludedDNSDomains"`      // Name constraints: DNS domains the CA may not issue for
+	PermittedIPRanges       []string         `yaml:"permittedIPRanges"`       // Name constraints: CIDR ranges the CA may issue for
+	ExcludedIPRanges        []string         `yaml:"excludedIPRanges"`        // Name constraints: CIDR ranges the CA may not issue for
+	PermittedEmailAddresses []string         `yaml:"permittedEmailAddresses"` // Name constraints: mailboxes or domains the CA may issue for
+	ExcludedEmailAddresses  []string         `yaml:"excludedEmailAddresses"`  // Name constraints: mailboxes or domains the CA may not issue for
+	ParentCert              string           `yaml:"parentCert"`              // Path to the parent CA certificate (intermediate only)
+	ParentKey               string           `yaml:"parentKey"`               // Path to the parent CA private key (intermediate only)
+	ParentPassphrase        string           `yaml:"-"`                       // Parent key passphrase value, never read from YAML
+	ParentPassphraseEnv     string           `yaml:"parentPassphraseEnv"`     // Environment variable holding the parent key passphrase
+	ParentPassphraseFile    string           `yaml:"parentPassphraseFile"`    // File holding the parent key passphrase
+	Type                    CertificateType  `yaml:"type"`
 }
 
 // CertConfig holds the configuration for a certificate
 type CertConfig struct {
+	CommonName             string           `yaml:"commonName"`
+	Organization           StringList       `yaml:"organization"`
+	OrganizationalUnit     StringList       `yaml:"organizationalUnit"`
+	Country                StringList       `yaml:"country"`
+	Province               StringList       `yaml:"province"`
+	Locality               StringList       `yaml:"locality"`
+	StreetAddress          StringList       `yaml:"streetAddress"`
+	PostalCode             StringList       `yaml:"postalCode"`
+	SerialNumber           string           `yaml:"serialNumber"` // Subject serialNumber attribute, e.g. a company registration number
+	ValidityDays           int              `yaml:"validityDays"`
+	Validity               string           `yaml:"validity"`  // Validity period such as 90d, 1y or 8760h, overrides validityDays
+	NotBefore              string           `yaml:"notBefore"` // Start of the validity period, RFC 3339 (default: now)
+	NotAfter               string           `yaml:"notAfter"`  // End of the validity period, RFC 3339, overrides validity
+	Backdate               string           `yaml:"backdate"`  // Duration NotBefore is moved back by, e.g. 5m
+	KeySize                int              `yaml:"keySize"`
+	KeyType                KeyType          `yaml:"keyType"`          // rsa (default), ecdsa or ed25519
+	Curve                  Curve            `yaml:"curve"`            // ECDSA curve: P256, P384 or P521
+	KeyFormat              KeyFormat        `yaml:"keyFormat"`        // pkcs8 (default) or pkcs1 (rsa only)
+	CertFileMode           string           `yaml:"certFileMode"`     // Octal permissions of written certificates (default: 0644)
+	KeyFileMode            string           `yaml:"keyFileMode"`      // Octal permissions of written private keys and keystores (default: 0600)
+	WorldReadableKey       bool             `yaml:"worldReadableKey"` // Allow a keyFileMode that gives other users access
+	DNSNames               []string         `yaml:"dnsNames"`
+	IPAddresses            []string         `yaml:"ipAddresses"`
+	EmailAddresses         []string         `yaml:"emailAddresses"`
+	URIs                   []string         `yaml:"uris"`
+	CRLDistributionPoints  []string         `yaml:"crlDistributionPoints"` // CRL URLs (default: the CA's)
+	OCSPServers            []string         `yaml:"ocspServers"`           // OCSP responder URLs (default: the CA's)
+	IssuingCertificateURL  StringList       `yaml:"issuingCertificateURL"` // CA Issuers URLs of the issuing CA certificate (default: the CA's)
+	PolicyOIDs             []string         `yaml:"policyOIDs"`            // Certificate policy OIDs added to the class defaults
+	ExtraExtensions        []Extension      `yaml:"extraExtensions"`       // Custom extensions added verbatim
+	ExtKeyUsages           []string         `yaml:"extKeyUsages"`          // Extended key usages replacing the class defaults, e.g. clientAuth
+	KeyUsages              []string         `yaml:"keyUsages"`             // Key usages replacing the defaults, e.g. digitalSignature
+	SignatureAlgorithm     string           `yaml:"signatureAlgorithm"`    // e.g. SHA384WithRSA (default: SHA-256, or the curve's hash for ECDSA)
+	CertPurpose            CertPurpose      `yaml:"certPurpose"`           // class (default) or ocsp-signing
+	CommonNameSAN          CommonNameSAN    `yaml:"commonNameSAN"`         // add (default), warn or error when a hostname common name is not a DNS name
+	Profile                Profile          `yaml:"profile"`               // cabf to enforce the CA/Browser Forum baseline requirements, spiffe, client, codesign or timestamping (default: none)
+	SPIFFEID               string           `yaml:"spiffeID"`              // SPIFFE ID of an X.509-SVID, e.g. spiffe://example.org/web (profile spiffe)
+	OutputDir              string           `yaml:"outputDir"`
+	DirMode                string           `yaml:"dirMode"`                // Octal permissions of created output directories (default: 0755)
+	EnforceDirMode         bool             `yaml:"enforceDirMode"`         // Also apply dirMode to an existing output directory
+	FileName               string           `yaml:"fileName"`               // Base name of the output files (default: cert)
+	FileNameFromCommonName bool             `yaml:"fileNameFromCommonName"` // Derive fileName from the common name
+	NoProgress             bool             `yaml:"-"`                      // Not serialized to YAML
+	Quiet                  bool             `yaml:"quiet"`                  // Suppress all progress output and warnings
+	Force                  bool             `yaml:"-"`                      // Overwrite existing output files, never read from YAML
+	ProgressOutput         io.Writer        `yaml:"-"`                      // Destination of progress messages (default: stdout)
+	DryRun                 bool             `yaml:"-"`                      // Validate and summarize without signing or writing files
+	InsecureFastKeys       bool             `yaml:"-"`                      // Allow small, insecure keys that generate quickly, for tests only
+	ReportOutput           io.Writer        `yaml:"-"`                      // Write a JSON report of the certificate and written files here
+	PEMOutput              io.Writer        `yaml:"-"`                      // Write the PEM certificate and chain here instead of files
+	PEMOutputKey           bool             `yaml:"-"`                      // Also write the private key to PEMOutput
+	Class                  CertificateClass `yaml:"class"`
+	CACert                 string           `yaml:"caCert"`               // Path to CA certificate
+	CAKey                  string           `yaml:"caKey"`                // Path to CA private key
+	CAChain                StringList       `yaml:"caChain"`              // Bundle file or files with the CAs above caCert
+	Format                 OutputFormat     `yaml:"format"`               // pem (default), pkcs12, der, pkcs7 or jks
+	Alias                  string           `yaml:"alias"`                // Keystore alias of the key entry (default: fileName)
+	SerialMode             SerialMode       `yaml:"serialMode"`           // random (default) or sequential
+	Serial                 string           `yaml:"serial"`               // Explicit serial number, decimal or hex with a 0x prefix, hex letters or colons (default: random)
+	EncryptKey             bool             `yaml:"encryptKey"`           // Encrypt the private key with a passphrase
+	Passphrase             string           `yaml:"-"`                    // Passphrase value, never read from YAML
+	PassphraseEnv          string           `yaml:"passphraseEnv"`        // Environment variable holding the passphrase
+	PassphraseFile         string           `yaml:"passphraseFile"`       // File holding the passphrase
+	KeyFile                string           `yaml:"keyFile"`              // Existing private key to use instead of generating one
+	KeyPassphrase          string           `yaml:"-"`                    // Existing key passphrase value, never read from YAML
+	KeyPassphraseEnv       string           `yaml:"keyPassphraseEnv"`     // Environment variable holding the existing key passphrase
+	KeyPassphraseFile      string           `yaml:"keyPassphraseFile"`    // File holding the existing key passphrase
+	CAPassphrase           string           `yaml:"-"`                    // CA key passphrase value, never read from YAML
+	CAPassphraseEnv        string           `yaml:"caPassphraseEnv"`      // Environment variable holding the CA key passphrase
+	CAPassphraseFile       string           `yaml:"caPassphraseFile"`     // File holding the CA key passphrase
+	KeystorePassword       string           `yaml:"-"`                    // Keystore password value, never read from YAML (default: the passphrase)
+	KeystorePasswordEnv    string           `yaml:"keystorePasswordEnv"`  // Environment variable holding the keystore password
+	KeystorePasswordFile   string           `yaml:"keystorePasswordFile"` // File holding the keystore password
+	WriteFullChain         *bool            `yaml:"writeFullChain"`       // Write fullchain.crt (default: true)
+	FullChainRoot          bool             `yaml:"fullChainRoot"`        // Include the self-signed root in the full chain
+	WritePublicKey         bool             `yaml:"writePublicKey"`       // Also write the public key to fileName.pub
+	Fingerprint            bool             `yaml:"fingerprint"`          // Print the SHA-1 and SHA-256 fingerprints
+	WriteFingerprint       bool             `yaml:"writeFingerprint"`     // Write the SHA-256 fingerprint to fileName.sha256
+	NoVerify               bool             `yaml:"noVerify"`             // Skip verifying the issued certificate against its CA
+}
+
+// CSRConfig holds the configuration for a certificate signing request
+type CSRConfig struct {
 	CommonName         string           `yaml:"commonName"`
-	Organization       string           `yaml:"organization"`
-	OrganizationalUnit string           `yaml:"organizationalUnit"`
-	Country            string           `yaml:"country"`
-	Province           string           `yaml:"province"`
-	Locality           string           `yaml:"locality"`
-	ValidityDays       int              `yaml:"validityDays"`
+	Organization       StringList       `yaml:"organization"`
+	OrganizationalUnit StringList       `yaml:"organizationalUnit"`
+	Country            StringList       `yaml:"country"`
+	Province           StringList       `yaml:"province"`
+	Locality           StringList       `yaml:"locality"`
+	StreetAddress      StringList       `yaml:"streetAddress"`
+	PostalCode         StringList       `yaml:"postalCode"`
+	SerialNumber       string           `yaml:"serialNumber"` // Subject serialNumber attribute, e.g. a company registration number
 	KeySize            int              `yaml:"keySize"`
+	KeyType            KeyType          `yaml:"keyType"`          // rsa (default), ecdsa or ed25519
+	Curve              Curve            `yaml:"curve"`            // ECDSA curve: P256, P384 or P521
+	KeyFormat          KeyFormat        `yaml:"keyFormat"`        // pkcs8 (default) or pkcs1 (rsa only)
+	CertFileMode       string           `yaml:"certFileMode"`     // Octal permissions of written certificates (default: 0644)
+	KeyFileMode        string           `yaml:"keyFileMode"`      // Octal permissions of written private keys and keystores (default: 0600)
+	WorldReadableKey   bool             `yaml:"worldReadableKey"` // Allow a keyFileMode that gives other users access
 	DNSNames           []string         `yaml:"dnsNames"`
+	IPAddresses        []string         `yaml:"ipAddresses"`
+	EmailAddresses     []string         `yaml:"emailAddresses"`
+	URIs               []string         `yaml:"uris"`
 	OutputDir          string           `yaml:"outputDir"`
-	NoProgress         bool             `yaml:"-"` // Not serialized to YAML
+	DirMode            string           `yaml:"dirMode"`        // Octal permissions of created output directories (default: 0755)
+	EnforceDirMode     bool             `yaml:"enforceDirMode"` // Also apply dirMode to an existing output directory
+	NoProgress         bool             `yaml:"-"`              // Not serialized to YAML
+	Quiet              bool             `yaml:"quiet"`          // Suppress all progress output and warnings
+	Force              bool             `yaml:"-"`              // Overwrite existing output files, never read from YAML
+	ProgressOutput     io.Writer        `yaml:"-"`              // Destination of progress messages (default: stdout)
 	Class              CertificateClass `yaml:"class"`
-	CACert             string           `yaml:"caCert"` // Path to CA certificate
-	CAKey              string           `yaml:"caKey"`  // Path to CA private key
+	KeyPath            string           `yaml:"keyPath"`           // Existing private key to use instead of generating one
+	KeyPassphrase      string           `yaml:"-"`                 // Existing key passphrase value, never read from YAML
+	KeyPassphraseEnv   string           `yaml:"keyPassphraseEnv"`  // Environment variable holding the existing key passphrase
+	KeyPassphraseFile  string           `yaml:"keyPassphraseFile"` // File holding the existing key passphrase
+	EncryptKey         bool             `yaml:"encryptKey"`        // Encrypt the generated private key with a passphrase
+	Passphrase         string           `yaml:"-"`                 // Passphrase value, never read from YAML
+	PassphraseEnv      string           `yaml:"passphraseEnv"`     // Environment variable holding the passphrase
+	PassphraseFile     string           `yaml:"passphraseFile"`    // File holding the passphrase
+}
+
+// HierarchyConfig describes a complete PKI: a root CA, intermediates signed
+// by the root or another intermediate, and leaf certificates
+type HierarchyConfig struct {
+	OutputDir      string             `yaml:"outputDir"` // Each CA and leaf is written to its own subdirectory
+	NoProgress     bool               `yaml:"-"`         // Not serialized to YAML
+	Quiet          bool               `yaml:"quiet"`     // Suppress all progress output and warnings
+	Force          bool               `yaml:"-"`         // Overwrite existing output files, never read from YAML
+	ProgressOutput io.Writer          `yaml:"-"`         // Destination of progress messages (default: stdout)
+	Root           CAConfig           `yaml:"root"`
+	Intermediates  []IntermediateSpec `yaml:"intermediates"`
+	Leaves         []LeafSpec         `yaml:"leaves"`
+}
+
+// IntermediateSpec is an intermediate CA within a HierarchyConfig
+type IntermediateSpec struct {
+	Name     string `yaml:"name"`   // Subdirectory and reference name
+	Parent   string `yaml:"parent"` // Name of the signing CA (default: root)
+	CAConfig `yaml:",inline"`
+}
+
+// LeafSpec is a leaf certificate within a HierarchyConfig
+type LeafSpec struct {
+	Name       string `yaml:"name"`   // Subdirectory name
+	Issuer     string `yaml:"issuer"` // Name of the issuing CA (default: the last intermediate, or root)
+	CertConfig `yaml:",inline"`
+}
+
+// BatchConfig lists leaf certificates issued concurrently by one CA
+type BatchConfig struct {
+	CACert           string      `yaml:"caCert"`           // Path to CA certificate
+	CAKey            string      `yaml:"caKey"`            // Path to CA private key
+	CAPassphrase     string      `yaml:"-"`                // CA key passphrase value, never read from YAML
+	CAPassphraseEnv  string      `yaml:"caPassphraseEnv"`  // Environment variable holding the CA key passphrase
+	CAPassphraseFile string      `yaml:"caPassphraseFile"` // File holding the CA key passphrase
+	OutputDir        string      `yaml:"outputDir"`        // Each certificate is written to its own subdirectory
+	Workers          int         `yaml:"workers"`          // Certificates issued at once (default: number of CPUs)
+	NoProgress       bool        `yaml:"-"`                // Not serialized to YAML
+	Quiet            bool        `yaml:"quiet"`            // Suppress all progress output and warnings
+	Force            bool        `yaml:"-"`                // Overwrite existing output files, never read from YAML
+	ProgressOutput   io.Writer   `yaml:"-"`                // Destination of progress messages (default: stdout)
+	Certificates     []BatchSpec `yaml:"certificates"`
+}
+
+// BatchSpec is a leaf certificate within a BatchConfig
+type BatchSpec struct {
+	Name       string `yaml:"name"` // Subdirectory name
+	CertConfig `yaml:",inline"`
+}
+
+// MeshConfig lists the leaf certificates of a service mesh, all issued by one
+// CA. CA holds the CA paths and the settings shared by every certificate; an
+// entry of Certificates overrides each shared setting it sets.
+type MeshConfig struct {
+	OutputDir      string      `yaml:"outputDir"` // Each certificate is written to its own subdirectory
+	Workers        int         `yaml:"workers"`   // Certificates issued at once (default: number of CPUs)
+	NoProgress     bool        `yaml:"-"`         // Not serialized to YAML
+	Quiet          bool        `yaml:"quiet"`     // Suppress all progress output and warnings
+	Force          bool        `yaml:"-"`         // Overwrite existing output files, never read from YAML
+	ProgressOutput io.Writer   `yaml:"-"`         // Destination of progress messages (default: stdout)
+	CA             CertConfig  `yaml:"ca"`
+	Certificates   []BatchSpec `yaml:"certificates"`
 }
 
 // SignConfig holds the configuration for signing a certificate
 type SignConfig struct {
-	CertPath   string `yaml:"certPath"`   // Path to the certificate to sign
-	KeyPath    string `yaml:"keyPath"`    // Path to the certificate's private key
-	CACertPath string `yaml:"caCertPath"` // Path to the CA certificate
-	CAKeyPath  string `yaml:"caKeyPath"`  // Path to the CA private key
-	OutputDir  string `yaml:"outputDir"`  // Output directory for the signed certificate
-	NoProgress bool   `yaml:"-"`          // Not serialized to YAML
+	CertPath           string     `yaml:"certPath"`           // Path to the certificate to re-issue
+	CSRPath            string     `yaml:"csrPath"`            // Path to a certificate request, instead of certPath
+	KeyPath            string     `yaml:"keyPath"`            // Optional path to the certificate's private key, checked against it
+	CACertPath         string     `yaml:"caCertPath"`         // Path to the CA certificate
+	CAKeyPath          string     `yaml:"caKeyPath"`          // Path to the CA private key
+	OutputDir          string     `yaml:"outputDir"`          // Output directory for the signed certificate
+	DirMode            string     `yaml:"dirMode"`            // Octal permissions of created output directories (default: 0755)
+	EnforceDirMode     bool       `yaml:"enforceDirMode"`     // Also apply dirMode to an existing output directory
+	FileName           string     `yaml:"fileName"`           // Base name of the signed certificate (default: signed)
+	ValidityDays       int        `yaml:"validityDays"`       // Validity period, defaults to the original certificate's or 365 for CSRs
+	IsCA               bool       `yaml:"isCA"`               // Issue a CA certificate
+	SerialMode         SerialMode `yaml:"serialMode"`         // random (default) or sequential
+	SignatureAlgorithm string     `yaml:"signatureAlgorithm"` // e.g. SHA384WithRSA (default: SHA-256, or the curve's hash for ECDSA)
+	Fingerprint        bool       `yaml:"fingerprint"`        // Print the SHA-1 and SHA-256 fingerprints
+	WriteFingerprint   bool       `yaml:"writeFingerprint"`   // Write the SHA-256 fingerprint to fileName.sha256
+	NoProgress         bool       `yaml:"-"`                  // Not serialized to YAML
+	Quiet              bool       `yaml:"quiet"`              // Suppress all progress output and warnings
+	Force              bool       `yaml:"-"`                  // Overwrite existing output files, never read from YAML
+	ProgressOutput     io.Writer  `yaml:"-"`                  // Destination of progress messages (default: stdout)
+	DryRun             bool       `yaml:"-"`                  // Validate and summarize without signing or writing files
+	ReportOutput       io.Writer  `yaml:"-"`                  // Write a JSON report of the certificate and written files here
+
+	KeyPassphrase     string `yaml:"-"`                 // Certificate key passphrase value, never read from YAML
+	KeyPassphraseEnv  string `yaml:"keyPassphraseEnv"`  // Environment variable holding the certificate key passphrase
+	KeyPassphraseFile string `yaml:"keyPassphraseFile"` // File holding the certificate key passphrase
+	CAPassphrase      string `yaml:"-"`                 // CA key passphrase value, never read from YAML
+	CAPassphraseEnv   string `yaml:"caPassphraseEnv"`   // Environment variable holding the CA key passphrase
+	CAPassphraseFile  string `yaml:"caPassphraseFile"`  // File holding the CA key passphrase
+}
+
+// RenewConfig holds the configuration for renewing a certificate with its
+// existing private key
+type RenewConfig struct {
+	CertPath           string     `yaml:"certPath"`           // Path to the certificate to renew
+	KeyPath            string     `yaml:"keyPath"`            // Path to the certificate's existing private key
+	CACertPath         string     `yaml:"caCertPath"`         // Path to the CA certificate
+	CAKeyPath          string     `yaml:"caKeyPath"`          // Path to the CA private key
+	OutputDir          string     `yaml:"outputDir"`          // Output directory for the renewed certificate
+	DirMode            string     `yaml:"dirMode"`            // Octal permissions of created output directories (default: 0755)
+	EnforceDirMode     bool       `yaml:"enforceDirMode"`     // Also apply dirMode to an existing output directory
+	ValidityDays       int        `yaml:"validityDays"`       // Validity period, defaults to the original certificate's
+	SerialMode         SerialMode `yaml:"serialMode"`         // random (default) or sequential
+	SignatureAlgorithm string     `yaml:"signatureAlgorithm"` // e.g. SHA384WithRSA (default: SHA-256, or the curve's hash for ECDSA)
+	NoVerify           bool       `yaml:"noVerify"`           // Skip verifying the renewed certificate against its CA
+	NoProgress         bool       `yaml:"-"`                  // Not serialized to YAML
+	Quiet              bool       `yaml:"quiet"`              // Suppress all progress output and warnings
+	Force              bool       `yaml:"-"`                  // Overwrite existing output files, never read from YAML
+	ProgressOutput     io.Writer  `yaml:"-"`                  // Destination of progress messages (default: stdout)
+
+	KeyPassphrase     string `yaml:"-"`                 // Certificate key passphrase value, never read from YAML
+	KeyPassphraseEnv  string `yaml:"keyPassphraseEnv"`  // Environment variable holding the certificate key passphrase
+	KeyPassphraseFile string `yaml:"keyPassphraseFile"` // File holding the certificate key passphrase
+	CAPassphrase      string `yaml:"-"`                 // CA key passphrase value, never read from YAML
+	CAPassphraseEnv   string `yaml:"caPassphraseEnv"`   // Environment variable holding the CA key passphrase
+	CAPassphraseFile  string `yaml:"caPassphraseFile"`  // File holding the CA key passphrase
+}
+
+// VerifyConfig holds the configuration for verifying a certificate chain
+type VerifyConfig struct {
+	CertPath       string    `yaml:"certPath"`   // Path to the certificate to verify, may include intermediates
+	CACertPath     string    `yaml:"caCertPath"` // Path to the CA certificate(s)
+	DNSName        string    `yaml:"dnsName"`    // Optional DNS name the certificate must be valid for
+	Usage          string    `yaml:"usage"`      // Required extended key usage: any (default), server, client, email or code
+	Quiet          bool      `yaml:"quiet"`      // Print nothing on success, only the error on failure
+	ProgressOutput io.Writer `yaml:"-"`          // Destination of the verified chain (default: stdout)
+}
+
+// ListTrustedConfig holds the configuration for listing trusted CA
+// certificates
+type ListTrustedConfig struct {
+	Scope          string    `yaml:"scope"` // system (default) or user
+	All            bool      `yaml:"all"`   // Also list the CAs shipped with the operating system
+	Quiet          bool      `yaml:"quiet"` // Print nothing
+	ProgressOutput io.Writer `yaml:"-"`     // Destination of the list (default: stdout)
+}
+
+// ScanConfig holds the configuration for scanning a directory for expiring
+// certificates
+type ScanConfig struct {
+	Dir    string    `yaml:"dir"`  // Directory scanned recursively for .crt and .pem files (default: certs)
+	Warn   string    `yaml:"warn"` // Report certificates expiring within this period, e.g. 30d (default: 30d)
+	JSON   bool      `yaml:"json"` // Write every certificate as JSON instead of a table of the expiring ones
+	Output io.Writer `yaml:"-"`    // Destination of the results (default: stdout)
+}
+
+// BundleConfig holds the configuration for ordering certificates into a
+// chain bundle
+type BundleConfig struct {
+	Inputs         []string  `yaml:"inputs"`         // PEM files holding the certificates, in any order
+	OutputPath     string    `yaml:"outputPath"`     // Path of the ordered bundle (default: chain.pem)
+	DirMode        string    `yaml:"dirMode"`        // Octal permissions of created output directories (default: 0755)
+	EnforceDirMode bool      `yaml:"enforceDirMode"` // Also apply dirMode to an existing output directory
+	NoProgress     bool      `yaml:"-"`              // Not serialized to YAML
+	Quiet          bool      `yaml:"quiet"`          // Suppress all progress output and warnings
+	Force          bool      `yaml:"-"`              // Overwrite an existing bundle
+	ProgressOutput io.Writer `yaml:"-"`              // Destination of progress messages (default: stdout)
+}
+
+// ConvertConfig holds the configuration for converting certificates and
+// keys between PEM, DER and PKCS#12
+type ConvertConfig struct {
+	Input          string       `yaml:"input"`          // PEM, DER or PKCS#12 file, the format is detected from its content
+	Output         string       `yaml:"output"`         // File to write
+	DirMode        string       `yaml:"dirMode"`        // Octal permissions of created output directories (default: 0755)
+	EnforceDirMode bool         `yaml:"enforceDirMode"` // Also apply dirMode to an existing output directory
+	Format         OutputFormat `yaml:"format"`         // pem, der or pkcs12 (default: from the output extension)
+	KeyPath        string       `yaml:"keyPath"`        // Private key to add to the input, e.g. for a PKCS#12 output
+	KeyFormat      KeyFormat    `yaml:"keyFormat"`      // pkcs8 (default) or pkcs1 for written rsa keys
+	EncryptKey     bool         `yaml:"encryptKey"`     // Encrypt a written PEM or DER key with the passphrase
+	NoProgress     bool         `yaml:"-"`              // Not serialized to YAML
+	Quiet          bool         `yaml:"quiet"`          // Suppress all progress output and warnings
+	Force          bool         `yaml:"-"`              // Overwrite an existing output file
+	ProgressOutput io.Writer    `yaml:"-"`              // Destination of progress messages (default: stdout)
+
+	Passphrase     string `yaml:"-"`              // Passphrase value, never read from YAML
+	PassphraseEnv  string `yaml:"passphraseEnv"`  // Environment variable holding the passphrase of encrypted input and output
+	PassphraseFile string `yaml:"passphraseFile"` // File holding the passphrase
+}
+
+// RevokeConfig holds the configuration for revoking a certificate and
+// regenerating the CA's certificate revocation list
+type RevokeConfig struct {
+	CACertPath      string    `yaml:"caCertPath"`      // Path to the CA certificate
+	CAKeyPath       string    `yaml:"caKeyPath"`       // Path to the CA private key
+	CertPath        string    `yaml:"certPath"`        // Path to the certificate to revoke
+	Serial          string    `yaml:"serial"`          // Serial number to revoke instead of certPath, read like CertConfig.Serial
+	Reason          string    `yaml:"reason"`          // Revocation reason (default: unspecified)
+	StorePath       string    `yaml:"storePath"`       // Revocation store (default: <outputDir>/revocations.json)
+	CRLValidityDays int       `yaml:"crlValidityDays"` // Days until the CRL's next update (default: 7)
+	OutputDir       string    `yaml:"outputDir"`       // Output directory for crl.pem
+	DirMode         string    `yaml:"dirMode"`         // Octal permissions of created output directories (default: 0755)
+	EnforceDirMode  bool      `yaml:"enforceDirMode"`  // Also apply dirMode to an existing output directory
+	NoProgress      bool      `yaml:"-"`               // Not serialized to YAML
+	Quiet           bool      `yaml:"quiet"`           // Suppress all progress output and warnings
+	ProgressOutput  io.Writer `yaml:"-"`               // Destination of progress messages (default: stdout)
+
+	CAPassphrase     string `yaml:"-"`                // CA key passphrase value, never read from YAML
+	CAPassphraseEnv  string `yaml:"caPassphraseEnv"`  // Environment variable holding the CA key passphrase
+	CAPassphraseFile string `yaml:"caPassphraseFile"` // File holding the CA key passphrase
+}
+
+// OCSPConfig holds the configuration for answering OCSP requests for a CA
+// from the revocation store maintained by revoke
+type OCSPConfig struct {
+	CACertPath            string    `yaml:"caCertPath"`            // Path to the CA certificate
+	CAKeyPath             string    `yaml:"caKeyPath"`             // Path to the CA private key, unless a delegated signer is used
+	SignerCertPath        string    `yaml:"signerCertPath"`        // Delegated OCSP signing certificate issued by the CA
+	SignerKeyPath         string    `yaml:"signerKeyPath"`         // Private key of the delegated OCSP signer
+	StorePath             string    `yaml:"storePath"`             // Revocation store (default: revocations.json next to the CA certificate)
+	Listen                string    `yaml:"listen"`                // Address to listen on (default: :8080)
+	ResponseValidityHours int       `yaml:"responseValidityHours"` // Hours until a response's next update (default: 24)
+	NoProgress            bool      `yaml:"-"`                     // Not serialized to YAML
+	Quiet                 bool      `yaml:"quiet"`                 // Suppress all progress output and warnings
+	ProgressOutput        io.Writer `yaml:"-"`                     // Destination of progress messages (default: stdout)
+
+	KeyPassphrase     string `yaml:"-"`                 // Signer key passphrase value, never read from YAML
+	KeyPassphraseEnv  string `yaml:"keyPassphraseEnv"`  // Environment variable holding the signer key passphrase
+	KeyPassphraseFile string `yaml:"keyPassphraseFile"` // File holding the signer key passphrase
+	CAPassphrase      string `yaml:"-"`                 // CA key passphrase value, never read from YAML
+	CAPassphraseEnv   string `yaml:"caPassphraseEnv"`   // Environment variable holding the CA key passphrase
+	CAPassphraseFile  string `yaml:"caPassphraseFile"`  // File holding the CA key passphrase
+}
+
+// revocationReasons maps reason names to RFC 5280 CRLReason codes
+var revocationReasons = map[string]int{
+	"unspecified":          0,
+	"keyCompromise":        1,
+	"caCompromise":         2,
+	"affiliationChanged":   3,
+	"superseded":           4,
+	"cessationOfOperation": 5,
+	"certificateHold":      6,
+	"privilegeWithdrawn":   9,
 }
 
 // TrustConfig holds the configuration for trusting a certificate
 type TrustConfig struct {
-	CertPath   string `yaml:"certPath"`  // Path to the certificate to trust
-	OutputDir  string `yaml:"outputDir"` // Output directory for the trusted certificate
-	NoProgress bool   `yaml:"-"`         // Not serialized to YAML
+	CertPath       string    `yaml:"certPath"`       // Path to the certificate to trust
+	OutputDir      string    `yaml:"outputDir"`      // Output directory for the trusted certificate
+	DirMode        string    `yaml:"dirMode"`        // Octal permissions of created output directories (default: 0755)
+	EnforceDirMode bool      `yaml:"enforceDirMode"` // Also apply dirMode to an existing output directory
+	FileName       string    `yaml:"fileName"`       // Base name of the copied certificate (default: trusted)
+	NSS            bool      `yaml:"nss"`            // Also trust it in Firefox/Chromium NSS databases
+	Scope          string    `yaml:"scope"`          // system (default) or user
+	Escalation     string    `yaml:"escalation"`     // Command granting administrator rights: sudo (default), doas, pkexec or none
+	Force          bool      `yaml:"-"`              // Install even if already trusted and overwrite the copy, never read from YAML
+	NoProgress     bool      `yaml:"-"`              // Not serialized to YAML
+	Quiet          bool      `yaml:"quiet"`          // Suppress all progress output and warnings
+	ProgressOutput io.Writer `yaml:"-"`              // Destination of progress messages (default: stdout)
+}
+
+// UntrustConfig holds the configuration for removing a trusted certificate
+type UntrustConfig struct {
+	CertPath       string    `yaml:"certPath"`   // Path to the certificate to remove from the trust store
+	NSS            bool      `yaml:"nss"`        // Also remove it from Firefox/Chromium NSS databases
+	Scope          string    `yaml:"scope"`      // system (default) or user
+	Escalation     string    `yaml:"escalation"` // Command granting administrator rights: sudo (default), doas, pkexec or none
+	NoProgress     bool      `yaml:"-"`          // Not serialized to YAML
+	Quiet          bool      `yaml:"quiet"`      // Suppress all progress output and warnings
+	ProgressOutput io.Writer `yaml:"-"`          // Destination of progress messages (default: stdout)
 }
 
 // getClassRequirements returns the requirements for a certificate class
@@ -95,181 +694,2160 @@ func getClassRequirements(class CertificateClass) (minKeySize int, maxValidityDa
 	}
 }
 
-// Validate checks and sets default values for CAConfig
-func (c *CAConfig) Validate() error {
-	if c.CommonName == "" {
-		return fmt.Errorf("commonName is required")
+// ClassRequirements returns the minimum RSA key size and the maximum validity
+// in days of a certificate class
+func ClassRequirements(class CertificateClass) (minKeySize int, maxValidityDays int) {
+	return getClassRequirements(class)
+}
+
+// extKeyUsages maps usage names to extended key usages
+var extKeyUsages = map[string]x509.ExtKeyUsage{
+	"any":    x509.ExtKeyUsageAny,
+	"server": x509.ExtKeyUsageServerAuth,
+	"client": x509.ExtKeyUsageClientAuth,
+	"email":  x509.ExtKeyUsageEmailProtection,
+	"code":   x509.ExtKeyUsageCodeSigning,
+}
+
+// extKeyUsageNames maps the configurable extended key usage names, in lower
+// case, to extended key usages
+var extKeyUsageNames = map[string]x509.ExtKeyUsage{
+	"serverauth":      x509.ExtKeyUsageServerAuth,
+	"clientauth":      x509.ExtKeyUsageClientAuth,
+	"codesigning":     x509.ExtKeyUsageCodeSigning,
+	"emailprotection": x509.ExtKeyUsageEmailProtection,
+	"timestamping":    x509.ExtKeyUsageTimeStamping,
+	"ocspsigning":     x509.ExtKeyUsageOCSPSigning,
+}
+
+// checkTimeStampingUsage rejects timeStamping mixed with other extended key
+// usages, since RFC 3161 requires it to be the only one of a time stamping
+// authority
+func checkTimeStampingUsage(names []string) error {
+	usages, err := parseExtKeyUsages(names)
+	if err != nil {
+		return nil // Reported by the caller's own check
 	}
-	if c.Organization == "" {
-		return fmt.Errorf("organization is required")
+	if len(usages) > 1 && slices.Contains(usages, x509.ExtKeyUsageTimeStamping) {
+		return fmt.Errorf("extKeyUsages timeStamping cannot be combined with other extended key usages; use profile timestamping for a time stamping authority")
 	}
-	if c.Country == "" {
-		return fmt.Errorf("country is required")
+	return nil
+}
+
+// protectsEmail reports whether the extended key usages of a certificate
+// include emailProtection. Profiles replace the class defaults, which do for
+// Class 1, with usages of their own that do not.
+func (c *CertConfig) protectsEmail() bool {
+	if c.Profile != "" {
+		return false
 	}
+	if len(c.ExtKeyUsages) == 0 {
+		return c.Class == Class1
+	}
+	usages, err := parseExtKeyUsages(c.ExtKeyUsages)
+	return err == nil && slices.Contains(usages, x509.ExtKeyUsageEmailProtection)
+}
 
-	// Set default class if not specified
-	if c.Class == 0 {
-		c.Class = Class1
+// parseExtKeyUsages converts extended key usage names, ignoring case and
+// repeated names
+func parseExtKeyUsages(names []string) ([]x509.ExtKeyUsage, error) {
+	usages := make([]x509.ExtKeyUsage, 0, len(names))
+	for _, name := range names {
+		usage, ok := extKeyUsageNames[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported extended key usage %q (must be serverAuth, clientAuth, codeSigning, emailProtection, timeStamping or ocspSigning)", name)
+		}
+		if !slices.Contains(usages, usage) {
+			usages = append(usages, usage)
+		}
 	}
+	return usages, nil
+}
 
-	// Get class requirements
-	minKeySize, maxValidityDays := getClassRequirements(c.Class)
+// keyUsageNames maps the configurable key usage names, in lower case, to
+// key usage bits
+var keyUsageNames = map[string]x509.KeyUsage{
+	"digitalsignature":  x509.KeyUsageDigitalSignature,
+	"contentcommitment": x509.KeyUsageContentCommitment,
+	"nonrepudiation":    x509.KeyUsageContentCommitment, // Former name of contentCommitment
+	"keyencipherment":   x509.KeyUsageKeyEncipherment,
+	"dataencipherment":  x509.KeyUsageDataEncipherment,
+	"keyagreement":      x509.KeyUsageKeyAgreement,
+	"keycertsign":       x509.KeyUsageCertSign,
+	"crlsign":           x509.KeyUsageCRLSign,
+	"encipheronly":      x509.KeyUsageEncipherOnly,
+	"decipheronly":      x509.KeyUsageDecipherOnly,
+}
 
-	// Validate key size
-	if c.KeySize <= 0 {
-		c.KeySize = minKeySize // Default to minimum for class
-	} else if c.KeySize < minKeySize {
-		return fmt.Errorf("keySize must be at least %d bits for Class %d CA", minKeySize, c.Class)
+// parseKeyUsages combines key usage names, ignoring case, into key usage
+// bits
+func parseKeyUsages(names []string) (x509.KeyUsage, error) {
+	var keyUsage x509.KeyUsage
+	for _, name := range names {
+		usage, ok := keyUsageNames[strings.ToLower(name)]
+		if !ok {
+			return 0, fmt.Errorf("unsupported key usage %q (must be digitalSignature, contentCommitment, keyEncipherment, dataEncipherment, keyAgreement, keyCertSign, cRLSign, encipherOnly or decipherOnly)", name)
+		}
+		keyUsage |= usage
 	}
+	return keyUsage, nil
+}
 
-	// Validate validity period
-	if c.ValidityDays <= 0 {
-		c.ValidityDays = maxValidityDays // Default to maximum for class
+// signatureChoice is a signatureAlgorithm choice and the signing key
+// type it requires
+type signatureChoice struct {
+	algorithm x509.SignatureAlgorithm
+	keyType   KeyType
+}
+
+// signatureAlgorithms maps the lowercased signatureAlgorithm names to their
+// algorithms. SHA-1 and MD5 based algorithms are not offered.
+var signatureAlgorithms = map[string]signatureChoice{
+	"sha256withrsa":    {x509.SHA256WithRSA, KeyTypeRSA},
+	"sha384withrsa":    {x509.SHA384WithRSA, KeyTypeRSA},
+	"sha512withrsa":    {x509.SHA512WithRSA, KeyTypeRSA},
+	"sha256withrsapss": {x509.SHA256WithRSAPSS, KeyTypeRSA},
+	"sha384withrsapss": {x509.SHA384WithRSAPSS, KeyTypeRSA},
+	"sha512withrsapss": {x509.SHA512WithRSAPSS, KeyTypeRSA},
+	"ecdsawithsha256":  {x509.ECDSAWithSHA256, KeyTypeECDSA},
+	"ecdsawithsha384":  {x509.ECDSAWithSHA384, KeyTypeECDSA},
+	"ecdsawithsha512":  {x509.ECDSAWithSHA512, KeyTypeECDSA},
+	"pureed25519":      {x509.PureEd25519, KeyTypeEd25519},
+}
+
+// parseSignatureAlgorithm looks up a signatureAlgorithm name. An empty name
+// returns a zero value, which selects the default for the signing key.
+func parseSignatureAlgorithm(name string) (signatureChoice, error) {
+	if name == "" {
+		return signatureChoice{}, nil
+	}
+	algorithm, ok := signatureAlgorithms[strings.ToLower(name)]
+	if !ok {
+		return signatureChoice{}, fmt.Errorf("unsupported signature algorithm %q (must be SHA256WithRSA, SHA384WithRSA, SHA512WithRSA, SHA256WithRSAPSS, SHA384WithRSAPSS, SHA512WithRSAPSS, ECDSAWithSHA256, ECDSAWithSHA384, ECDSAWithSHA512 or PureEd25519)", name)
 	}
+	return algorithm, nil
+}
 
-	// Root certificate specific validations
-	if c.Type == Root {
-		// Root certificates must be Class 2 or higher
-		if c.Class < Class2 {
-			return fmt.Errorf("root certificates must be Class 2 or higher")
+// parseIPAddresses parses IP address SANs, rejecting invalid entries
+func parseIPAddresses(addrs []string) ([]net.IP, error) {
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address %q", addr)
 		}
-		// Root certificates must use at least 4096-bit keys
-		if c.KeySize < 4096 {
-			return fmt.Errorf("root certificates must use at least 4096-bit keys")
-		}
-		// Root certificates should have longer validity (minimum 5 years)
-		if c.ValidityDays < 365*5 {
-			return fmt.Errorf("root certificates should have at least 5 years validity")
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
+
+// validateDNSNames rejects DNS SANs that are not hostnames. A single
+// leading "*." label makes a wildcard name.
+func validateDNSNames(names []string) error {
+	for _, name := range names {
+		switch {
+		case strings.Contains(name, "://"):
+			return fmt.Errorf("invalid DNS name %q: URLs belong in uris", name)
+		case net.ParseIP(name) != nil:
+			return fmt.Errorf("invalid DNS name %q: IP addresses belong in ipAddresses", name)
+		case strings.HasSuffix(name, "."):
+			return fmt.Errorf("invalid DNS name %q: remove the trailing dot", name)
+		case !validDomainName(strings.TrimPrefix(name, "*.")):
+			return fmt.Errorf("invalid DNS name %q: must be a hostname of letters, digits, hyphens and dots, optionally starting with *.", name)
 		}
-	} else {
-		// For non-root certificates, enforce class-specific validity limits
-		if c.ValidityDays > maxValidityDays {
-			return fmt.Errorf("validity period cannot exceed %d days for Class %d CA", maxValidityDays, c.Class)
+	}
+	return nil
+}
+
+// dedupe removes values that repeat an earlier one with the same key,
+// keeping the order of the first occurrences
+func dedupe(values []string, key func(string) string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := values[:0:0]
+	for _, value := range values {
+		if k := key(value); !seen[k] {
+			seen[k] = true
+			unique = append(unique, value)
 		}
 	}
+	return unique
+}
 
-	// Set default output directory
-	if c.OutputDir == "" {
-		c.OutputDir = "certs"
+// canonicalIP returns the canonical form of an IP address, so that
+// different spellings of an IPv6 address compare equal
+func canonicalIP(addr string) string {
+	if ip := net.ParseIP(addr); ip != nil {
+		return ip.String()
 	}
-	c.OutputDir = filepath.Clean(c.OutputDir)
+	return addr
+}
 
-	return nil
+// canonicalEmail returns an email address with its domain in lower case,
+// which unlike the local part is not case-sensitive
+func canonicalEmail(addr string) string {
+	if local, domain, ok := strings.Cut(addr, "@"); ok {
+		return local + "@" + strings.ToLower(domain)
+	}
+	return addr
 }
 
-// Validate checks and sets default values for CertConfig
-func (c *CertConfig) Validate() error {
-	if c.CommonName == "" {
-		return fmt.Errorf("commonName is required")
+// matchesWildcard reports whether name is covered by a DNS name pattern,
+// either equal to it or one label below a wildcard pattern such as
+// *.example.com, ignoring case
+func matchesWildcard(pattern, name string) bool {
+	if strings.EqualFold(pattern, name) {
+		return true
 	}
-	if c.Organization == "" {
-		return fmt.Errorf("organization is required")
+	suffix, ok := strings.CutPrefix(pattern, "*.")
+	if !ok {
+		return false
 	}
-	if c.Country == "" {
-		return fmt.Errorf("country is required")
+	_, parent, found := strings.Cut(name, ".")
+	return found && strings.EqualFold(parent, suffix)
+}
+
+// validateEmailAddresses rejects email SANs that are not bare addresses
+func validateEmailAddresses(addrs []string) error {
+	for _, addr := range addrs {
+		parsed, err := mail.ParseAddress(addr)
+		if err != nil || parsed.Address != addr {
+			return fmt.Errorf("invalid email address %q", addr)
+		}
 	}
+	return nil
+}
 
-	// Set default class if not specified
-	if c.Class == 0 {
-		c.Class = Class1
+// parseIPRanges parses CIDR IP ranges for name constraints
+func parseIPRanges(ranges []string) ([]*net.IPNet, error) {
+	parsed := make([]*net.IPNet, 0, len(ranges))
+	for _, r := range ranges {
+		_, ipNet, err := net.ParseCIDR(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP range %q (must be CIDR, e.g. 10.0.0.0/8)", r)
+		}
+		parsed = append(parsed, ipNet)
 	}
+	return parsed, nil
+}
 
-	// Get class requirements
-	minKeySize, maxValidityDays := getClassRequirements(c.Class)
+// validDomainName reports whether name is a syntactically valid DNS name of
+// letters, digits and hyphens
+func validDomainName(name string) bool {
+	if name == "" || len(name) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
 
-	// Validate key size
-	if c.KeySize <= 0 {
-		c.KeySize = minKeySize // Default to minimum for class
-	} else if c.KeySize < minKeySize {
-		return fmt.Errorf("keySize must be at least %d bits for Class %d certificate", minKeySize, c.Class)
+// validateDomainConstraints checks DNS domain name constraints. A leading dot
+// matches subdomains only.
+func validateDomainConstraints(domains []string) error {
+	for _, domain := range domains {
+		if !validDomainName(strings.TrimPrefix(domain, ".")) {
+			return fmt.Errorf("invalid DNS domain constraint %q", domain)
+		}
 	}
+	return nil
+}
 
-	// Validate validity period
-	if c.ValidityDays <= 0 {
-		c.ValidityDays = maxValidityDays // Default to maximum for class
-	} else if c.ValidityDays > maxValidityDays {
-		return fmt.Errorf("validity period cannot exceed %d days for Class %d certificate", maxValidityDays, c.Class)
+// validateEmailConstraints checks email name constraints, which are a single
+// mailbox, a domain, or a domain with a leading dot for its subdomains
+func validateEmailConstraints(constraints []string) error {
+	for _, constraint := range constraints {
+		if strings.Contains(constraint, "@") {
+			if err := validateEmailAddresses([]string{constraint}); err != nil {
+				return fmt.Errorf("invalid email constraint %q", constraint)
+			}
+		} else if !validDomainName(strings.TrimPrefix(constraint, ".")) {
+			return fmt.Errorf("invalid email constraint %q", constraint)
+		}
 	}
+	return nil
+}
 
-	// Set default DNS names
-	if len(c.DNSNames) == 0 {
-		c.DNSNames = []string{c.CommonName}
+// hasNameConstraints reports whether the CA restricts the names it issues for
+func (c *CAConfig) hasNameConstraints() bool {
+	return len(c.PermittedDNSDomains) > 0 || len(c.ExcludedDNSDomains) > 0 ||
+		len(c.PermittedIPRanges) > 0 || len(c.ExcludedIPRanges) > 0 ||
+		len(c.PermittedEmailAddresses) > 0 || len(c.ExcludedEmailAddresses) > 0
+}
+
+// parseURIs parses URI SANs, which must be absolute
+func parseURIs(uris []string) ([]*url.URL, error) {
+	parsed := make([]*url.URL, 0, len(uris))
+	for _, uri := range uris {
+		u, err := url.Parse(uri)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URI %q: %w", uri, err)
+		}
+		if !u.IsAbs() {
+			return nil, fmt.Errorf("invalid URI %q: scheme is required", uri)
+		}
+		parsed = append(parsed, u)
 	}
+	return parsed, nil
+}
 
-	// Set default output directory
-	if c.OutputDir == "" {
-		c.OutputDir = "certs"
+// parseValidity parses a validity period such as 90d, 2w, 1y or 8760h. The
+// d, w and y units are days, weeks and 365-day years, other units are those
+// of time.ParseDuration, and a bare number is a number of days.
+func parseValidity(validity string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour, "y": 365 * 24 * time.Hour}
+	number, unit := validity, "d"
+	if i := strings.LastIndexFunc(validity, func(r rune) bool { return r >= '0' && r <= '9' }); i >= 0 && i < len(validity)-1 {
+		number, unit = validity[:i+1], validity[i+1:]
 	}
-	c.OutputDir = filepath.Clean(c.OutputDir)
 
-	// Validate CA certificate and key paths
-	if c.CACert == "" {
-		return fmt.Errorf("caCert path is required")
+	var d time.Duration
+	if scale, ok := units[unit]; ok {
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return 0, fmt.Errorf("invalid validity %q (e.g. 90d, 2w, 1y or 8760h)", validity)
+		}
+		if time.Duration(n) > math.MaxInt64/scale {
+			return 0, fmt.Errorf("invalid validity %q: too long", validity)
+		}
+		d = time.Duration(n) * scale
+	} else {
+		var err error
+		if d, err = time.ParseDuration(validity); err != nil {
+			return 0, fmt.Errorf("invalid validity %q (e.g. 90d, 2w, 1y or 8760h)", validity)
+		}
 	}
-	if c.CAKey == "" {
-		return fmt.Errorf("caKey path is required")
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid validity %q: must be positive", validity)
 	}
+	return d, nil
+}
 
-	// Check if CA certificate exists
-	if _, err := os.Stat(c.CACert); os.IsNotExist(err) {
-		return fmt.Errorf("CA certificate not found at %s", c.CACert)
+// validityDuration returns the validity period, running until notAfter if it
+// is set, taken from validity if it is set and from validityDays otherwise
+func validityDuration(notBefore, notAfter, validity string, validityDays int) (time.Duration, error) {
+	if notAfter != "" {
+		start, err := validityStart(notBefore)
+		if err != nil {
+			return 0, err
+		}
+		until, err := parseNotAfter(notAfter)
+		if err != nil {
+			return 0, err
+		}
+		return until.Sub(start), nil
 	}
-
-	// Check if CA private key exists
-	if _, err := os.Stat(c.CAKey); os.IsNotExist(err) {
-		return fmt.Errorf("CA private key not found at %s", c.CAKey)
+	if validity != "" {
+		return parseValidity(validity)
 	}
-
-	return nil
+	return time.Duration(validityDays) * 24 * time.Hour, nil
 }
 
-// Validate checks and sets default values for SignConfig
-func (c *SignConfig) Validate() error {
-	// Validate certificate paths
-	if c.CertPath == "" {
-		return fmt.Errorf("certPath is required")
+// validityStart returns the start of the validity period, notBefore or now
+// if it is empty
+func validityStart(notBefore string) (time.Time, error) {
+	if notBefore == "" {
+		return time.Now(), nil
 	}
-	if c.KeyPath == "" {
-		return fmt.Errorf("keyPath is required")
+	start, err := time.Parse(time.RFC3339, notBefore)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid notBefore %q (must be RFC 3339, e.g. 2024-01-02T15:04:05Z)", notBefore)
 	}
-	if c.CACertPath == "" {
-		return fmt.Errorf("caCertPath is required")
+	return start, nil
+}
+
+// parseNotAfter parses an absolute end of the validity period, which must be
+// in the future
+func parseNotAfter(notAfter string) (time.Time, error) {
+	until, err := time.Parse(time.RFC3339, notAfter)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid notAfter %q (must be RFC 3339, e.g. 2026-01-02T15:04:05Z)", notAfter)
 	}
-	if c.CAKeyPath == "" {
-		return fmt.Errorf("caKeyPath is required")
+	if !until.After(time.Now()) {
+		return time.Time{}, fmt.Errorf("notAfter %s is not in the future", until.Format(time.RFC3339))
 	}
+	return until, nil
+}
 
-	// Check if certificate exists
-	if _, err := os.Stat(c.CertPath); os.IsNotExist(err) {
-		return fmt.Errorf("certificate not found at %s", c.CertPath)
+// validityPeriod returns the NotBefore and NotAfter times of a certificate
+// valid for validity from notBefore, or from now if it is empty, or until
+// notAfter if it is set, with NotBefore moved back by backdate to tolerate
+// clocks running behind
+func validityPeriod(notBefore, notAfter, backdate string, validity time.Duration) (time.Time, time.Time, error) {
+	start, err := validityStart(notBefore)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	until := start.Add(validity)
+	if notAfter != "" {
+		if until, err = parseNotAfter(notAfter); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	offset, err := parseBackdate(backdate)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
 	}
 
-	// Check if certificate key exists
-	if _, err := os.Stat(c.KeyPath); os.IsNotExist(err) {
-		return fmt.Errorf("certificate key not found at %s", c.KeyPath)
+	from := start.Add(-offset)
+	if !from.Before(until) {
+		return time.Time{}, time.Time{}, fmt.Errorf("notBefore %s is not before notAfter %s", from.Format(time.RFC3339), until.Format(time.RFC3339))
 	}
+	return from, until, nil
+}
 
-	// Check if CA certificate exists
-	if _, err := os.Stat(c.CACertPath); os.IsNotExist(err) {
-		return fmt.Errorf("CA certificate not found at %s", c.CACertPath)
+// parseBackdate parses the duration NotBefore is moved back by, zero if it
+// is empty
+func parseBackdate(backdate string) (time.Duration, error) {
+	if backdate == "" {
+		return 0, nil
+	}
+	offset, err := time.ParseDuration(backdate)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid backdate %q (must be a non-negative duration, e.g. 5m)", backdate)
 	}
+	return offset, nil
+}
 
-	// Check if CA private key exists
-	if _, err := os.Stat(c.CAKeyPath); os.IsNotExist(err) {
-		return fmt.Errorf("CA private key not found at %s", c.CAKeyPath)
+// defaultValidity sets an unset validity to maxValidityDays, less the
+// backdate, which counts towards the maximum
+func defaultValidity(validity *string, validityDays *int, maxValidityDays int, backdate string) {
+	if *validity != "" || *validityDays > 0 {
+		return
+	}
+	offset, err := parseBackdate(backdate)
+	maxValidity := time.Duration(maxValidityDays) * 24 * time.Hour
+	if err != nil || offset == 0 || offset >= maxValidity {
+		*validityDays = maxValidityDays // Invalid backdates are reported by validityPeriod
+		return
 	}
+	*validity = (maxValidity - offset).String()
+}
 
-	// Set default output directory
-	if c.OutputDir == "" {
-		c.OutputDir = "certs"
+// checkMaxValidity rejects a validity period longer than maxValidityDays.
+// The period runs from the backdated NotBefore, so the backdate counts
+// towards the maximum.
+func checkMaxValidity(notBefore, notAfter time.Time, backdate string, maxValidityDays int, kind string) error {
+	if notAfter.Sub(notBefore) <= time.Duration(maxValidityDays)*24*time.Hour {
+		return nil
 	}
-	c.OutputDir = filepath.Clean(c.OutputDir)
+	if backdate != "" {
+		return fmt.Errorf("validity period cannot exceed %d days for %s, including the backdate of %s", maxValidityDays, kind, backdate)
+	}
+	return fmt.Errorf("validity period cannot exceed %d days for %s", maxValidityDays, kind)
+}
 
-	return nil
+// parseOID parses a dotted-decimal object identifier such as
+// 1.3.6.1.4.1.99999.1
+func parseOID(oid string) (asn1.ObjectIdentifier, error) {
+	arcs := strings.Split(oid, ".")
+	if len(arcs) < 2 {
+		return nil, fmt.Errorf("invalid OID %q: at least two arcs are required", oid)
+	}
+	parsed := make(asn1.ObjectIdentifier, len(arcs))
+	for i, arc := range arcs {
+		n, err := strconv.Atoi(arc)
+		if err != nil || n < 0 || arc != strconv.Itoa(n) {
+			return nil, fmt.Errorf("invalid OID %q: arc %q is not a non-negative integer", oid, arc)
+		}
+		parsed[i] = n
+	}
+	// The first arc is 0, 1 or 2, and only 2 allows a second arc above 39
+	if parsed[0] > 2 || parsed[0] < 2 && parsed[1] > 39 {
+		return nil, fmt.Errorf("invalid OID %q: first arcs out of range", oid)
+	}
+	return parsed, nil
+}
+
+// parseOIDs parses dotted-decimal object identifiers
+func parseOIDs(oids []string) ([]asn1.ObjectIdentifier, error) {
+	parsed := make([]asn1.ObjectIdentifier, 0, len(oids))
+	for _, oid := range oids {
+		o, err := parseOID(oid)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, o)
+	}
+	return parsed, nil
+}
+
+// parseExtensions converts custom extensions to their pkix form, rejecting
+// invalid OIDs, undecodable values and repeated OIDs
+func parseExtensions(extensions []Extension) ([]pkix.Extension, error) {
+	parsed := make([]pkix.Extension, 0, len(extensions))
+	for _, ext := range extensions {
+		oid, err := parseOID(ext.OID)
+		if err != nil {
+			return nil, err
+		}
+		for _, other := range parsed {
+			if other.Id.Equal(oid) {
+				return nil, fmt.Errorf("extension %s is given more than once", ext.OID)
+			}
+		}
+
+		var value []byte
+		switch strings.ToLower(ext.Encoding) {
+		case "", "base64":
+			value, err = base64.StdEncoding.DecodeString(ext.Value)
+		case "hex":
+			value, err = hex.DecodeString(strings.ReplaceAll(ext.Value, ":", ""))
+		default:
+			return nil, fmt.Errorf("extension %s: invalid encoding %q (must be base64 or hex)", ext.OID, ext.Encoding)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("extension %s: decoding value: %w", ext.OID, err)
+		}
+		if len(value) == 0 {
+			return nil, fmt.Errorf("extension %s: value is required", ext.OID)
+		}
+		parsed = append(parsed, pkix.Extension{Id: oid, Critical: ext.Critical, Value: value})
+	}
+	return parsed, nil
+}
+
+// validateRevocationURLs checks CRL distribution points and OCSP responder
+// URLs, which must be absolute
+func validateRevocationURLs(crlDistributionPoints, ocspServers []string) error {
+	if _, err := parseURIs(crlDistributionPoints); err != nil {
+		return fmt.Errorf("crlDistributionPoints: %w", err)
+	}
+	if _, err := parseURIs(ocspServers); err != nil {
+		return fmt.Errorf("ocspServers: %w", err)
+	}
+	return nil
+}
+
+// resolvePassphrase returns the passphrase from the first non-empty source:
+// the literal value, the named environment variable, or the file contents
+// with the trailing newline removed
+func resolvePassphrase(value, env, file string) (string, error) {
+	if value != "" {
+		return value, nil
+	}
+	if env != "" {
+		if v := os.Getenv(env); v != "" {
+			return v, nil
+		}
+		return "", fmt.Errorf("passphrase environment variable %s is not set", env)
+	}
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("reading passphrase file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	return "", nil
+}
+
+// validateFormat normalizes the output format and reports whether it
+// needs a passphrase
+func validateFormat(format *OutputFormat) (needsPassphrase bool, err error) {
+	*format = OutputFormat(strings.ToLower(string(*format)))
+	switch *format {
+	case "":
+		*format = FormatPEM
+		return false, nil
+	case FormatPEM, FormatDER, FormatPKCS7, FormatJKS:
+		return false, nil
+	case "keystore":
+		*format = FormatJKS
+		return false, nil
+	case FormatPKCS12:
+		return true, nil
+	default:
+		return false, fmt.Errorf("unsupported format %q (must be pem, pkcs12, der, pkcs7 or jks)", *format)
+	}
+}
+
+// validateKeyFormat checks the format of a generated private key, defaulting
+// to PKCS#8. PKCS#1 only encodes RSA keys and has no modern encryption.
+func validateKeyFormat(format *KeyFormat, keyType KeyType, keyFile string, encryptKey bool) error {
+	*format = KeyFormat(strings.ToLower(string(*format)))
+	if *format != "" && keyFile != "" {
+		return fmt.Errorf("keyFormat does not apply to an existing keyFile, which is not rewritten")
+	}
+	switch *format {
+	case "":
+		*format = KeyFormatPKCS8
+	case KeyFormatPKCS8:
+	case KeyFormatPKCS1:
+		if keyType != KeyTypeRSA {
+			return fmt.Errorf("keyFormat pkcs1 only applies to rsa keys, not %s", keyType)
+		}
+		if encryptKey {
+			return fmt.Errorf("keyFormat pkcs1 cannot be combined with encryptKey, encrypted keys are written as PKCS#8")
+		}
+	default:
+		return fmt.Errorf("unsupported keyFormat %q (must be pkcs8 or pkcs1)", *format)
+	}
+	return nil
+}
+
+// validateFileModes checks the octal permissions of written certificates
+// and keys, defaulting to 0644 and 0600. Keys that other users may access
+// must be allowed explicitly.
+func validateFileModes(certMode, keyMode *string, worldReadableKey bool) error {
+	if *certMode == "" {
+		*certMode = fmt.Sprintf("%04o", certFileMode)
+	}
+	if *keyMode == "" {
+		*keyMode = fmt.Sprintf("%04o", keyFileMode)
+	}
+	cert, err := parseFileMode(*certMode)
+	if err != nil {
+		return fmt.Errorf("certFileMode: %w", err)
+	}
+	key, err := parseFileMode(*keyMode)
+	if err != nil {
+		return fmt.Errorf("keyFileMode: %w", err)
+	}
+	if key&0007 != 0 && !worldReadableKey {
+		return fmt.Errorf("keyFileMode %s gives other users access to the private key, set worldReadableKey to allow it", *keyMode)
+	}
+	*certMode, *keyMode = fmt.Sprintf("%04o", cert), fmt.Sprintf("%04o", key)
+	return nil
+}
+
+// validateDirMode checks the octal permissions of created output
+// directories, defaulting to 0755. The owner needs full access.
+func validateDirMode(mode *string) error {
+	if *mode == "" {
+		*mode = fmt.Sprintf("%04o", dirMode)
+	}
+	perm, err := parseFileMode(*mode)
+	if err != nil {
+		return fmt.Errorf("dirMode: %w", err)
+	}
+	if perm&0700 != 0700 {
+		return fmt.Errorf("dirMode %s does not give the owner full access", *mode)
+	}
+	*mode = fmt.Sprintf("%04o", perm)
+	return nil
+}
+
+// parseFileMode parses octal file permissions, which must let the owner
+// read the file
+func parseFileMode(mode string) (os.FileMode, error) {
+	perm, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || perm > 0777 {
+		return 0, fmt.Errorf("invalid mode %q: expected octal permissions such as 0640", mode)
+	}
+	if perm&0400 == 0 {
+		return 0, fmt.Errorf("mode %s does not let the owner read the file", mode)
+	}
+	return os.FileMode(perm), nil
+}
+
+// validateFileName checks the base name of the output files, defaulting to
+// defaultName or, if fromCommonName is set, to a name derived from commonName
+func validateFileName(name *string, fromCommonName bool, commonName, defaultName string) error {
+	if fromCommonName {
+		if *name != "" {
+			return fmt.Errorf("fileName and fileNameFromCommonName are mutually exclusive")
+		}
+		*name = fileNameFromCommonName(commonName)
+	}
+	if *name == "" {
+		*name = defaultName
+	}
+	if *name == "." || *name == ".." || strings.ContainsAny(*name, `/\`) {
+		return fmt.Errorf("fileName %q must be a file name without a directory", *name)
+	}
+	return nil
+}
+
+// fileNameFromCommonName derives a file name from a common name, writing a
+// leading wildcard label as _wildcard and any character that is unsafe in
+// file names as an underscore
+func fileNameFromCommonName(commonName string) string {
+	if rest, ok := strings.CutPrefix(commonName, "*."); ok {
+		commonName = "_wildcard." + rest
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, commonName)
+}
+
+// fullChainFileName returns the name of the full chain file, fullchain.crt
+// unless a fileName is configured
+func (c *CertConfig) fullChainFileName() string {
+	return chainFileName(c.FileName, "cert", "fullchain.crt")
+}
+
+// pkcs7FileName returns the name of the PKCS#7 chain file, chain.p7b unless
+// a fileName is configured
+func (c *CertConfig) pkcs7FileName() string {
+	return chainFileName(c.FileName, "cert", "chain.p7b")
+}
+
+// writesKey reports whether the CA's private key is generated and written,
+// rather than taken from keyFile or created in a key management service
+func (c *CAConfig) writesKey() bool {
+	return c.KeyFile == "" && (c.CreateKMSKey == "" || c.DryRun)
+}
+
+// pkcs7FileName returns the name of the PKCS#7 chain file, chain.p7b unless
+// a fileName is configured
+func (c *CAConfig) pkcs7FileName() string {
+	return chainFileName(c.FileName, "ca", "chain.p7b")
+}
+
+// chainFileName returns name for output files with their default base name,
+// and name prefixed by the configured fileName otherwise
+func chainFileName(fileName, defaultFileName, name string) string {
+	if fileName == defaultFileName {
+		return name
+	}
+	return fileName + "-" + name
+}
+
+// validateSerialMode normalizes the serial mode, defaulting to random
+func validateSerialMode(mode *SerialMode) error {
+	*mode = SerialMode(strings.ToLower(string(*mode)))
+	switch *mode {
+	case "":
+		*mode = SerialRandom
+	case SerialRandom, SerialSequential:
+	default:
+		return fmt.Errorf("unsupported serialMode %q (must be random or sequential)", *mode)
+	}
+	return nil
+}
+
+// validateSerial checks an explicit serial number, which replaces both
+// random and sequential serials
+func validateSerial(serial string, mode SerialMode) error {
+	if serial == "" {
+		return nil
+	}
+	if mode == SerialSequential {
+		return fmt.Errorf("serial and serialMode sequential are mutually exclusive")
+	}
+	_, err := parseSerial(serial)
+	return err
+}
+
+// validateCommonNameSAN normalizes the common name SAN mode
+func validateCommonNameSAN(mode *CommonNameSAN) error {
+	*mode = CommonNameSAN(strings.ToLower(string(*mode)))
+	switch *mode {
+	case "":
+		*mode = CommonNameSANAdd
+	case CommonNameSANAdd, CommonNameSANWarn, CommonNameSANError:
+	default:
+		return fmt.Errorf("unsupported commonNameSAN %q (must be add, warn or error)", *mode)
+	}
+	return nil
+}
+
+// validateProfile normalizes the policy profile
+func validateProfile(profile *Profile) error {
+	*profile = Profile(strings.ToLower(string(*profile)))
+	switch *profile {
+	case "", ProfileCABF, ProfileSPIFFE, ProfileClient, ProfileCodeSign, ProfileTimestamping:
+	default:
+		return fmt.Errorf("unsupported profile %q (must be cabf, spiffe, client, codesign or timestamping)", *profile)
+	}
+	return nil
+}
+
+// validateCABF checks a leaf configuration, after its defaults are applied,
+// against the CA/Browser Forum baseline requirements for TLS server
+// certificates. The validity limit is applied with the class maximum.
+func (c *CertConfig) validateCABF() error {
+	if c.CertPurpose != PurposeClass {
+		return fmt.Errorf("profile cabf only applies to certPurpose class")
+	}
+	if c.Class < Class2 {
+		return fmt.Errorf("profile cabf requires Class 2 or higher, Class 1 is for email protection")
+	}
+
+	// Only DNS names and IP addresses identify a TLS server
+	if len(c.DNSNames) == 0 && len(c.IPAddresses) == 0 {
+		return fmt.Errorf("profile cabf requires at least one dnsNames or ipAddresses entry")
+	}
+	if len(c.EmailAddresses) > 0 || len(c.URIs) > 0 {
+		return fmt.Errorf("profile cabf does not allow emailAddresses or uris")
+	}
+	if !c.commonNameInSANs() {
+		return fmt.Errorf("profile cabf requires commonName %q to be one of the dnsNames or ipAddresses", c.CommonName)
+	}
+
+	// Subject attributes are limited to the upper bounds of RFC 5280
+	if len(c.OrganizationalUnit.values()) > 0 {
+		return fmt.Errorf("profile cabf does not allow organizationalUnit")
+	}
+	for _, attr := range []struct {
+		name   string
+		values []string
+		max    int
+	}{
+		{"commonName", []string{c.CommonName}, 64},
+		{"organization", c.Organization, 64},
+		{"province", c.Province, 128},
+		{"locality", c.Locality, 128},
+	} {
+		for _, value := range attr.values {
+			if n := utf8.RuneCountInString(value); n > attr.max {
+				return fmt.Errorf("profile cabf limits %s to %d characters, not %d", attr.name, attr.max, n)
+			}
+		}
+	}
+
+	// Only RSA keys and the P256 and P384 curves are allowed
+	switch c.KeyType {
+	case KeyTypeRSA:
+		if c.KeySize%8 != 0 {
+			return fmt.Errorf("profile cabf requires an RSA key size divisible by 8")
+		}
+	case KeyTypeECDSA:
+		if c.Curve != CurveP256 && c.Curve != CurveP384 {
+			return fmt.Errorf("profile cabf only allows the P256 and P384 curves, not %s", c.Curve)
+		}
+	default:
+		return fmt.Errorf("profile cabf does not allow %s keys", c.KeyType)
+	}
+	return nil
+}
+
+// validateSPIFFE checks a leaf configuration against the SPIFFE X.509-SVID
+// profile and makes the SPIFFE ID its only URI SAN
+func (c *CertConfig) validateSPIFFE() error {
+	if c.CertPurpose != PurposeClass {
+		return fmt.Errorf("profile spiffe only applies to certPurpose class")
+	}
+	if c.SPIFFEID == "" {
+		return fmt.Errorf("profile spiffe requires spiffeID")
+	}
+	if err := validateSPIFFEID(c.SPIFFEID); err != nil {
+		return err
+	}
+	if len(c.URIs) > 0 && !slices.Equal(c.URIs, []string{c.SPIFFEID}) {
+		return fmt.Errorf("profile spiffe does not allow uris, the spiffeID is the only URI SAN")
+	}
+	c.URIs = []string{c.SPIFFEID}
+
+	// Signing and key agreement are all an SVID is used for
+	keyUsage, err := parseKeyUsages(c.KeyUsages)
+	if err != nil {
+		return fmt.Errorf("keyUsages: %w", err)
+	}
+	if len(c.KeyUsages) > 0 && keyUsage&x509.KeyUsageDigitalSignature == 0 {
+		return fmt.Errorf("profile spiffe requires keyUsages to include digitalSignature")
+	}
+	return nil
+}
+
+// validateClient checks a leaf configuration against the mutual TLS client
+// profile, which never lets the certificate authenticate a server
+func (c *CertConfig) validateClient() error {
+	if c.CertPurpose != PurposeClass {
+		return fmt.Errorf("profile client only applies to certPurpose class")
+	}
+	usages, err := parseExtKeyUsages(c.ExtKeyUsages)
+	if err != nil {
+		return fmt.Errorf("extKeyUsages: %w", err)
+	}
+	if slices.Contains(usages, x509.ExtKeyUsageServerAuth) {
+		return fmt.Errorf("profile client does not allow the serverAuth extended key usage")
+	}
+	keyUsage, err := parseKeyUsages(c.KeyUsages)
+	if err != nil {
+		return fmt.Errorf("keyUsages: %w", err)
+	}
+	if len(c.KeyUsages) > 0 && keyUsage&x509.KeyUsageDigitalSignature == 0 {
+		return fmt.Errorf("profile client requires keyUsages to include digitalSignature")
+	}
+	return nil
+}
+
+// validateCodeSign checks a leaf configuration against the code signing
+// profile, which keeps the certificate from authenticating TLS peers
+func (c *CertConfig) validateCodeSign() error {
+	if c.CertPurpose != PurposeClass {
+		return fmt.Errorf("profile codesign only applies to certPurpose class")
+	}
+	usages, err := parseExtKeyUsages(c.ExtKeyUsages)
+	if err != nil {
+		return fmt.Errorf("extKeyUsages: %w", err)
+	}
+	if len(usages) > 0 && !slices.Equal(usages, []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning}) {
+		return fmt.Errorf("profile codesign only allows the codeSigning extended key usage")
+	}
+	keyUsage, err := parseKeyUsages(c.KeyUsages)
+	if err != nil {
+		return fmt.Errorf("keyUsages: %w", err)
+	}
+	if len(c.KeyUsages) > 0 && keyUsage&x509.KeyUsageDigitalSignature == 0 {
+		return fmt.Errorf("profile codesign requires keyUsages to include digitalSignature")
+	}
+	return nil
+}
+
+// validateTimestamping checks a leaf configuration against the time stamping
+// authority profile of RFC 3161, which allows no other extended key usage
+// and only signing key usages
+func (c *CertConfig) validateTimestamping() error {
+	if c.CertPurpose != PurposeClass {
+		return fmt.Errorf("profile timestamping only applies to certPurpose class")
+	}
+	usages, err := parseExtKeyUsages(c.ExtKeyUsages)
+	if err != nil {
+		return fmt.Errorf("extKeyUsages: %w", err)
+	}
+	if len(usages) > 0 && !slices.Equal(usages, []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping}) {
+		return fmt.Errorf("profile timestamping only allows the timeStamping extended key usage")
+	}
+	keyUsage, err := parseKeyUsages(c.KeyUsages)
+	if err != nil {
+		return fmt.Errorf("keyUsages: %w", err)
+	}
+	if len(c.KeyUsages) > 0 {
+		if keyUsage&x509.KeyUsageDigitalSignature == 0 {
+			return fmt.Errorf("profile timestamping requires keyUsages to include digitalSignature")
+		}
+		if keyUsage&^(x509.KeyUsageDigitalSignature|x509.KeyUsageContentCommitment) != 0 {
+			return fmt.Errorf("profile timestamping only allows the digitalSignature and contentCommitment key usages")
+		}
+	}
+	return nil
+}
+
+// validateSPIFFEID checks that id is a SPIFFE ID naming a workload: the
+// spiffe scheme, a trust domain of lower case letters, digits, dots, dashes
+// and underscores, and a non-empty path without empty, "." or ".." segments
+func validateSPIFFEID(id string) error {
+	if len(id) > 2048 {
+		return fmt.Errorf("invalid spiffeID: longer than 2048 bytes")
+	}
+	rest, ok := strings.CutPrefix(id, "spiffe://")
+	if !ok {
+		return fmt.Errorf("invalid spiffeID %q: must start with spiffe://", id)
+	}
+	trustDomain, path, ok := strings.Cut(rest, "/")
+	if trustDomain == "" {
+		return fmt.Errorf("invalid spiffeID %q: trust domain is missing", id)
+	}
+	for _, r := range trustDomain {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_') {
+			return fmt.Errorf("invalid spiffeID %q: trust domain may only contain lower case letters, digits, dots, dashes and underscores", id)
+		}
+	}
+	if !ok || path == "" {
+		return fmt.Errorf("invalid spiffeID %q: a workload path such as /ns/default/sa/web is required", id)
+	}
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("invalid spiffeID %q: path segments may not be empty, \".\" or \"..\"", id)
+		}
+		for _, r := range segment {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_') {
+				return fmt.Errorf("invalid spiffeID %q: path may only contain letters, digits, dots, dashes and underscores", id)
+			}
+		}
+	}
+	return nil
+}
+
+// commonNameInSANs reports whether the common name is one of the DNS names,
+// ignoring case, or one of the IP addresses
+func (c *CertConfig) commonNameInSANs() bool {
+	for _, name := range c.DNSNames {
+		if strings.EqualFold(name, c.CommonName) {
+			return true
+		}
+	}
+	if ip := net.ParseIP(c.CommonName); ip != nil {
+		addresses, _ := parseIPAddresses(c.IPAddresses)
+		for _, address := range addresses {
+			if address.Equal(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// commonNameMissingFromSANs reports whether the common name is a hostname
+// that none of the DNS names, including wildcards, matches
+func (c *CertConfig) commonNameMissingFromSANs() bool {
+	if !strings.Contains(c.CommonName, ".") || validateDNSNames([]string{c.CommonName}) != nil {
+		return false
+	}
+	for _, name := range c.DNSNames {
+		if matchesWildcard(name, c.CommonName) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateCertPurpose normalizes the certificate purpose and defaults it to
+// the class preset
+func validateCertPurpose(purpose *CertPurpose) error {
+	*purpose = CertPurpose(strings.ToLower(string(*purpose)))
+	switch *purpose {
+	case "":
+		*purpose = PurposeClass
+	case PurposeClass, PurposeOCSPSigning:
+	default:
+		return fmt.Errorf("unsupported certPurpose %q (must be class or ocsp-signing)", *purpose)
+	}
+	return nil
+}
+
+// getClassCurve returns the minimum ECDSA curve for a certificate class
+func getClassCurve(class CertificateClass) Curve {
+	if class == Class3 {
+		return CurveP384
+	}
+	return CurveP256
+}
+
+// curveBits returns the field size of a curve, or 0 if the curve is unknown
+func curveBits(curve Curve) int {
+	switch curve {
+	case CurveP256:
+		return 256
+	case CurveP384:
+		return 384
+	case CurveP521:
+		return 521
+	default:
+		return 0
+	}
+}
+
+// keyFileParams loads an existing private key, replacing keyType, keySize
+// and curve with its parameters so they are held to the class requirements.
+// It returns the resolved key passphrase.
+func keyFileParams(path, passphrase, env, file string, keyType *KeyType, keySize *int, curve *Curve) (string, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) && !isSignerKey(path) {
+		return "", fmt.Errorf("private key not found at %s", path)
+	}
+	passphrase, err := resolvePassphrase(passphrase, env, file)
+	if err != nil {
+		return "", fmt.Errorf("keyFile: %w", err)
+	}
+	key, err := loadPrivateKey(path, []byte(passphrase))
+	if err != nil {
+		return "", fmt.Errorf("keyFile: %w", err)
+	}
+	*keyType, *keySize, *curve = keyParams(key.Public())
+	return passphrase, nil
+}
+
+// keyFileError attributes a key validation error to the existing key file
+func keyFileError(keyFile string, err error) error {
+	if keyFile == "" {
+		return err
+	}
+	return fmt.Errorf("keyFile %s: %w", keyFile, err)
+}
+
+// insecureKeySize is the RSA key size used when insecure fast keys are
+// allowed. Such keys can be factored and must never protect anything real.
+const insecureKeySize = 1024
+
+// validateKey checks the key type, size and curve against the class
+// requirements and fills in defaults. kind names the certificate in errors.
+// With insecure set the class minimums are replaced by 1024-bit RSA and P256
+// keys, which generate quickly for tests.
+func validateKey(keyType *KeyType, keySize *int, curve *Curve, class CertificateClass, kind string, insecure bool) error {
+	*keyType = KeyType(strings.ToLower(string(*keyType)))
+	if *keyType == "" {
+		*keyType = KeyTypeRSA
+	}
+
+	switch *keyType {
+	case KeyTypeRSA:
+		if *curve != "" {
+			return fmt.Errorf("curve is only valid for ecdsa keys")
+		}
+		minKeySize, _ := getClassRequirements(class)
+		if insecure {
+			minKeySize = insecureKeySize
+		}
+		if *keySize <= 0 {
+			*keySize = minKeySize // Default to minimum for class
+		} else if *keySize < minKeySize {
+			return fmt.Errorf("keySize must be at least %d bits for Class %d %s", minKeySize, class, kind)
+		}
+	case KeyTypeECDSA:
+		if *keySize > 0 {
+			return fmt.Errorf("keySize is not used for ecdsa keys, set curve instead")
+		}
+		*curve = Curve(strings.ToUpper(strings.ReplaceAll(string(*curve), "-", "")))
+		minCurve := getClassCurve(class)
+		if insecure {
+			minCurve = CurveP256
+		}
+		if *curve == "" {
+			*curve = minCurve // Default to minimum for class
+		}
+		if curveBits(*curve) == 0 {
+			return fmt.Errorf("unsupported curve %q (must be P256, P384 or P521)", *curve)
+		}
+		if curveBits(*curve) < curveBits(minCurve) {
+			return fmt.Errorf("curve must be at least %s for Class %d %s", minCurve, class, kind)
+		}
+	case KeyTypeEd25519:
+		if *keySize > 0 {
+			return fmt.Errorf("keySize is not used for ed25519 keys")
+		}
+		if *curve != "" {
+			return fmt.Errorf("curve is only valid for ecdsa keys")
+		}
+	default:
+		return fmt.Errorf("unsupported keyType %q (must be rsa, ecdsa or ed25519)", *keyType)
+	}
+
+	return nil
+}
+
+// validateCreateKMSKey checks a CA key to be created in a key management
+// service. The key never leaves it, so nothing may encrypt or export it.
+func (c *CAConfig) validateCreateKMSKey() error {
+	if c.KeyFile != "" {
+		return fmt.Errorf("keyFile and createKMSKey are mutually exclusive")
+	}
+	if _, ok := keyCreator(c.CreateKMSKey); !ok {
+		return fmt.Errorf("createKMSKey %s: no key management service is registered for its scheme", c.CreateKMSKey)
+	}
+	if c.SignatureAlgorithm != "" {
+		return fmt.Errorf("signatureAlgorithm cannot be combined with createKMSKey, the key is created for the default algorithm of its type")
+	}
+	if c.EncryptKey {
+		return fmt.Errorf("encryptKey does not apply to a key created with createKMSKey")
+	}
+	if c.Format == FormatPKCS12 {
+		return fmt.Errorf("pkcs12 format needs the private key, which stays in the key management service")
+	}
+	return nil
+}
+
+// Validate checks and sets default values for CAConfig
+func (c *CAConfig) Validate() error {
+	return c.validate(true)
+}
+
+// validate checks and sets default values for CAConfig. The parent CA
+// paths of an intermediate are only checked if loadParent is set.
+func (c *CAConfig) validate(loadParent bool) error {
+	if err := validateDirMode(&c.DirMode); err != nil {
+		return err
+	}
+
+	if c.CommonName == "" {
+		return fmt.Errorf("commonName is required")
+	}
+	if len(c.Organization.values()) == 0 {
+		return fmt.Errorf("organization is required")
+	}
+	if len(c.Country.values()) == 0 {
+		return fmt.Errorf("country is required")
+	}
+	if err := validateCountries(c.Country.values()); err != nil {
+		return err
+	}
+
+	// Set default class if not specified
+	if c.Class == 0 {
+		c.Class = Class1
+	}
+
+	// Get class requirements
+	_, maxValidityDays := getClassRequirements(c.Class)
+
+	// Take the key parameters from an existing key
+	if c.KeyFile != "" {
+		passphrase, err := keyFileParams(c.KeyFile, c.KeyPassphrase, c.KeyPassphraseEnv, c.KeyPassphraseFile, &c.KeyType, &c.KeySize, &c.Curve)
+		if err != nil {
+			return err
+		}
+		c.KeyPassphrase = passphrase
+	}
+
+	// Validate key type, size and curve
+	if err := validateKey(&c.KeyType, &c.KeySize, &c.Curve, c.Class, "CA", c.InsecureFastKeys); err != nil {
+		return keyFileError(c.KeyFile, err)
+	}
+	if err := validateKeyFormat(&c.KeyFormat, c.KeyType, c.KeyFile, c.EncryptKey); err != nil {
+		return err
+	}
+	if err := validateFileModes(&c.CertFileMode, &c.KeyFileMode, c.WorldReadableKey); err != nil {
+		return err
+	}
+
+	// Validate validity period, defaulting to the maximum for the class
+	if c.Type == Root {
+		defaultValidity(&c.Validity, &c.ValidityDays, maxValidityDays, "")
+	} else {
+		defaultValidity(&c.Validity, &c.ValidityDays, maxValidityDays, c.Backdate)
+	}
+	validity, err := validityDuration(c.NotBefore, c.NotAfter, c.Validity, c.ValidityDays)
+	if err != nil {
+		return err
+	}
+	notBefore, notAfter, err := validityPeriod(c.NotBefore, c.NotAfter, c.Backdate, validity)
+	if err != nil {
+		return err
+	}
+
+	// Root certificate specific validations
+	if c.Type == Root {
+		// Root certificates must be Class 2 or higher
+		if c.Class < Class2 {
+			return fmt.Errorf("root certificates must be Class 2 or higher")
+		}
+		// Root certificates must use at least 4096-bit RSA or P384 ECDSA keys
+		if c.KeyType == KeyTypeRSA && c.KeySize < 4096 && !c.InsecureFastKeys {
+			return fmt.Errorf("root certificates must use at least 4096-bit keys")
+		}
+		if c.KeyType == KeyTypeECDSA && curveBits(c.Curve) < curveBits(CurveP384) && !c.InsecureFastKeys {
+			return fmt.Errorf("root certificates must use at least a P384 curve")
+		}
+		// Root certificates should have longer validity (minimum 5 years)
+		if validity < 5*365*24*time.Hour {
+			return fmt.Errorf("root certificates should have at least 5 years validity")
+		}
+	} else {
+		// For non-root certificates, enforce class-specific validity limits
+		if err := checkMaxValidity(notBefore, notAfter, c.Backdate, maxValidityDays, fmt.Sprintf("Class %d CA", c.Class)); err != nil {
+			return err
+		}
+
+		// Intermediates are signed by a parent CA
+		if loadParent {
+			if c.ParentCert == "" {
+				return fmt.Errorf("parentCert is required for intermediate certificates")
+			}
+			if c.ParentKey == "" {
+				return fmt.Errorf("parentKey is required for intermediate certificates")
+			}
+			if _, err := os.Stat(c.ParentCert); os.IsNotExist(err) {
+				return fmt.Errorf("parent CA certificate not found at %s", c.ParentCert)
+			}
+			if _, err := os.Stat(c.ParentKey); os.IsNotExist(err) && !isSignerKey(c.ParentKey) {
+				return fmt.Errorf("parent CA private key not found at %s", c.ParentKey)
+			}
+			parentPassphrase, err := resolvePassphrase(c.ParentPassphrase, c.ParentPassphraseEnv, c.ParentPassphraseFile)
+			if err != nil {
+				return fmt.Errorf("parent CA key: %w", err)
+			}
+			c.ParentPassphrase = parentPassphrase
+		}
+	}
+
+	// Validate revocation URLs
+	if err := validateRevocationURLs(c.CRLDistributionPoints, c.OCSPServers); err != nil {
+		return err
+	}
+	if _, err := parseURIs(c.IssuingCertificateURL.values()); err != nil {
+		return fmt.Errorf("issuingCertificateURL: %w", err)
+	}
+
+	if _, err := parseOIDs(c.PolicyOIDs); err != nil {
+		return fmt.Errorf("policyOIDs: %w", err)
+	}
+	if _, err := parseExtensions(c.ExtraExtensions); err != nil {
+		return fmt.Errorf("extraExtensions: %w", err)
+	}
+	if len(c.KeyUsages) > 0 {
+		keyUsage, err := parseKeyUsages(c.KeyUsages)
+		if err != nil {
+			return fmt.Errorf("keyUsages: %w", err)
+		}
+		if keyUsage&x509.KeyUsageCertSign == 0 {
+			return fmt.Errorf("keyUsages of a CA must include keyCertSign")
+		}
+	}
+	signature, err := parseSignatureAlgorithm(c.SignatureAlgorithm)
+	if err != nil {
+		return err
+	}
+	// A new root signs itself, so its key must match before it is generated
+	if signature.keyType != "" && c.Type == Root && c.KeyFile == "" && signature.keyType != c.KeyType {
+		return fmt.Errorf("signature algorithm %s requires keyType %s", c.SignatureAlgorithm, signature.keyType)
+	}
+
+	// Validate the CA's own DNS, email and URI SANs
+	if err := validateDNSNames(c.DNSNames); err != nil {
+		return err
+	}
+	if err := validateEmailAddresses(c.EmailAddresses); err != nil {
+		return err
+	}
+	if _, err := parseURIs(c.URIs); err != nil {
+		return err
+	}
+	c.DNSNames = dedupe(c.DNSNames, strings.ToLower)
+	c.EmailAddresses = dedupe(c.EmailAddresses, canonicalEmail)
+
+	// Validate name constraints
+	if err := validateDomainConstraints(slices.Concat(c.PermittedDNSDomains, c.ExcludedDNSDomains)); err != nil {
+		return err
+	}
+	if _, err := parseIPRanges(slices.Concat(c.PermittedIPRanges, c.ExcludedIPRanges)); err != nil {
+		return err
+	}
+	if err := validateEmailConstraints(slices.Concat(c.PermittedEmailAddresses, c.ExcludedEmailAddresses)); err != nil {
+		return err
+	}
+
+	// Validate output format and serial mode
+	needsPassphrase, err := validateFormat(&c.Format)
+	if err != nil {
+		return err
+	}
+	if c.Format == FormatJKS {
+		return fmt.Errorf("jks format only applies to certificates")
+	}
+	if c.CreateKMSKey != "" {
+		if err := c.validateCreateKMSKey(); err != nil {
+			return err
+		}
+	}
+	if err := validateSerialMode(&c.SerialMode); err != nil {
+		return err
+	}
+	if err := validateSerial(c.Serial, c.SerialMode); err != nil {
+		return err
+	}
+
+	// Resolve the passphrase for the private key
+	if c.EncryptKey && c.KeyFile != "" {
+		return fmt.Errorf("encryptKey does not apply to an existing keyFile, which is not rewritten")
+	}
+	if c.EncryptKey || needsPassphrase {
+		passphrase, err := resolvePassphrase(c.Passphrase, c.PassphraseEnv, c.PassphraseFile)
+		if err != nil {
+			return err
+		}
+		if passphrase == "" {
+			return fmt.Errorf("encryptKey and pkcs12 format require a passphrase, passphraseEnv or passphraseFile")
+		}
+		c.Passphrase = passphrase
+	}
+
+	// Set default output directory
+	if c.OutputDir == "" {
+		c.OutputDir = "certs"
+	}
+	c.OutputDir = filepath.Clean(c.OutputDir)
+	if err := validateFileName(&c.FileName, c.FileNameFromCommonName, c.CommonName, "ca"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Validate checks and sets default values for CertConfig
+func (c *CertConfig) Validate() error {
+	return c.validate(true)
+}
+
+// validate checks and sets default values for CertConfig. The CA paths are
+// only checked if loadCA is set.
+func (c *CertConfig) validate(loadCA bool) error {
+	if err := validateDirMode(&c.DirMode); err != nil {
+		return err
+	}
+
+	if c.CommonName == "" {
+		return fmt.Errorf("commonName is required")
+	}
+	if len(c.Organization.values()) == 0 {
+		return fmt.Errorf("organization is required")
+	}
+	if len(c.Country.values()) == 0 {
+		return fmt.Errorf("country is required")
+	}
+	if err := validateCountries(c.Country.values()); err != nil {
+		return err
+	}
+
+	// Set default class if not specified
+	if c.Class == 0 {
+		c.Class = Class1
+	}
+
+	// Get class requirements
+	_, maxValidityDays := getClassRequirements(c.Class)
+
+	// Take the key parameters from an existing key
+	if c.KeyFile != "" {
+		passphrase, err := keyFileParams(c.KeyFile, c.KeyPassphrase, c.KeyPassphraseEnv, c.KeyPassphraseFile, &c.KeyType, &c.KeySize, &c.Curve)
+		if err != nil {
+			return err
+		}
+		c.KeyPassphrase = passphrase
+	}
+
+	// Validate key type, size and curve
+	if err := validateKey(&c.KeyType, &c.KeySize, &c.Curve, c.Class, "certificate", c.InsecureFastKeys); err != nil {
+		return keyFileError(c.KeyFile, err)
+	}
+	if err := validateKeyFormat(&c.KeyFormat, c.KeyType, c.KeyFile, c.EncryptKey); err != nil {
+		return err
+	}
+	if err := validateFileModes(&c.CertFileMode, &c.KeyFileMode, c.WorldReadableKey); err != nil {
+		return err
+	}
+
+	// Validate validity period
+	if err := validateProfile(&c.Profile); err != nil {
+		return err
+	}
+	if c.Profile == ProfileCABF {
+		maxValidityDays = min(maxValidityDays, cabfMaxValidityDays)
+	}
+	defaultValidity(&c.Validity, &c.ValidityDays, maxValidityDays, c.Backdate) // Default to maximum for class
+	validity, err := validityDuration(c.NotBefore, c.NotAfter, c.Validity, c.ValidityDays)
+	if err != nil {
+		return err
+	}
+	notBefore, notAfter, err := validityPeriod(c.NotBefore, c.NotAfter, c.Backdate, validity)
+	if err != nil {
+		return err
+	}
+	if err := checkMaxValidity(notBefore, notAfter, c.Backdate, maxValidityDays, fmt.Sprintf("Class %d certificate", c.Class)); err != nil {
+		return err
+	}
+
+	// Validate DNS, IP, email and URI SANs
+	if err := validateDNSNames(c.DNSNames); err != nil {
+		return err
+	}
+	if _, err := parseIPAddresses(c.IPAddresses); err != nil {
+		return err
+	}
+	if err := validateEmailAddresses(c.EmailAddresses); err != nil {
+		return err
+	}
+	if _, err := parseURIs(c.URIs); err != nil {
+		return err
+	}
+	c.DNSNames = dedupe(c.DNSNames, strings.ToLower)
+	c.IPAddresses = dedupe(c.IPAddresses, canonicalIP)
+	c.EmailAddresses = dedupe(c.EmailAddresses, canonicalEmail)
+
+	// Validate revocation URLs
+	if err := validateRevocationURLs(c.CRLDistributionPoints, c.OCSPServers); err != nil {
+		return err
+	}
+	if _, err := parseURIs(c.IssuingCertificateURL.values()); err != nil {
+		return fmt.Errorf("issuingCertificateURL: %w", err)
+	}
+
+	if _, err := parseOIDs(c.PolicyOIDs); err != nil {
+		return fmt.Errorf("policyOIDs: %w", err)
+	}
+	if _, err := parseExtensions(c.ExtraExtensions); err != nil {
+		return fmt.Errorf("extraExtensions: %w", err)
+	}
+
+	if err := validateCertPurpose(&c.CertPurpose); err != nil {
+		return err
+	}
+	if err := validateCommonNameSAN(&c.CommonNameSAN); err != nil {
+		return err
+	}
+	if _, err := parseExtKeyUsages(c.ExtKeyUsages); err != nil {
+		return fmt.Errorf("extKeyUsages: %w", err)
+	}
+	if len(c.ExtKeyUsages) > 0 && c.CertPurpose == PurposeOCSPSigning {
+		return fmt.Errorf("extKeyUsages cannot be combined with certPurpose ocsp-signing")
+	}
+	if err := checkTimeStampingUsage(c.ExtKeyUsages); err != nil {
+		return err
+	}
+	keyUsage, err := parseKeyUsages(c.KeyUsages)
+	if err != nil {
+		return fmt.Errorf("keyUsages: %w", err)
+	}
+	if keyUsage&(x509.KeyUsageCertSign|x509.KeyUsageCRLSign) != 0 {
+		return fmt.Errorf("keyUsages keyCertSign and cRLSign only apply to CA certificates")
+	}
+	if _, err := parseSignatureAlgorithm(c.SignatureAlgorithm); err != nil {
+		return err
+	}
+
+	// OCSP signers are identified by their issuer, not by a name, and SVIDs
+	// by their SPIFFE ID, so they skip the class SAN requirements and the
+	// common name default
+	if c.CertPurpose == PurposeClass && c.Profile != ProfileSPIFFE {
+		// Class 1 certificates are for email protection and need an email
+		// SAN, unless a profile or extKeyUsages put them to another use
+		if c.Class == Class1 && c.protectsEmail() && len(c.EmailAddresses) == 0 {
+			return fmt.Errorf("emailAddresses requires at least one entry for Class 1 certificates with the emailProtection extended key usage")
+		}
+
+		// Default to the common name when no SANs are configured, if it is a
+		// hostname rather than an IP address or a descriptive name. The
+		// common name of a client or code signing certificate names a user
+		// or publisher, not a host.
+		namesHost := c.Profile != ProfileClient && c.Profile != ProfileCodeSign && c.Profile != ProfileTimestamping
+		noSANs := len(c.DNSNames) == 0 && len(c.IPAddresses) == 0 && len(c.EmailAddresses) == 0 && len(c.URIs) == 0
+		if noSANs && namesHost && validateDNSNames([]string{c.CommonName}) == nil {
+			c.DNSNames = []string{c.CommonName}
+		}
+
+		// A hostname common name must also be a DNS name to be matched
+		if namesHost && c.commonNameMissingFromSANs() {
+			switch c.CommonNameSAN {
+			case CommonNameSANAdd:
+				c.DNSNames = append([]string{c.CommonName}, c.DNSNames...)
+			case CommonNameSANError:
+				return fmt.Errorf("commonName %q is not among the dnsNames and will not be matched by clients; add it to dnsNames or set commonNameSAN to add", c.CommonName)
+			}
+		}
+	}
+	if c.Profile == ProfileCABF {
+		if err := c.validateCABF(); err != nil {
+			return err
+		}
+	}
+	if c.Profile == ProfileClient {
+		if err := c.validateClient(); err != nil {
+			return err
+		}
+	}
+	if c.Profile == ProfileCodeSign {
+		if err := c.validateCodeSign(); err != nil {
+			return err
+		}
+	}
+	if c.Profile == ProfileTimestamping {
+		if err := c.validateTimestamping(); err != nil {
+			return err
+		}
+	}
+	if c.Profile == ProfileSPIFFE {
+		if err := c.validateSPIFFE(); err != nil {
+			return err
+		}
+	} else if c.SPIFFEID != "" {
+		return fmt.Errorf("spiffeID requires profile spiffe")
+	}
+
+	// Write the full chain unless disabled
+	if c.WriteFullChain == nil {
+		writeFullChain := true
+		c.WriteFullChain = &writeFullChain
+	}
+
+	// Set default output directory
+	if c.OutputDir == "" {
+		c.OutputDir = "certs"
+	}
+	c.OutputDir = filepath.Clean(c.OutputDir)
+	if err := validateFileName(&c.FileName, c.FileNameFromCommonName, c.CommonName, "cert"); err != nil {
+		return err
+	}
+
+	// Validate CA certificate and key paths
+	if loadCA {
+		if c.CACert == "" {
+			return fmt.Errorf("caCert path is required")
+		}
+		if c.CAKey == "" {
+			return fmt.Errorf("caKey path is required")
+		}
+
+		// Check if CA certificate exists
+		if _, err := os.Stat(c.CACert); os.IsNotExist(err) {
+			return fmt.Errorf("CA certificate not found at %s", c.CACert)
+		}
+
+		// Check if CA private key exists
+		if _, err := os.Stat(c.CAKey); os.IsNotExist(err) && !isSignerKey(c.CAKey) {
+			return fmt.Errorf("CA private key not found at %s", c.CAKey)
+		}
+		for _, path := range c.CAChain.values() {
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				return fmt.Errorf("CA chain file not found at %s", path)
+			}
+		}
+	}
+
+	// Validate output format and serial mode
+	needsPassphrase, err := validateFormat(&c.Format)
+	if err != nil {
+		return err
+	}
+	if c.PEMOutput != nil && (c.Format != FormatPEM || c.WritePublicKey || c.WriteFingerprint) {
+		return fmt.Errorf("writing PEM to an output stream cannot be combined with format %s, writePublicKey or writeFingerprint", c.Format)
+	}
+	if err := validateSerialMode(&c.SerialMode); err != nil {
+		return err
+	}
+	if err := validateSerial(c.Serial, c.SerialMode); err != nil {
+		return err
+	}
+
+	// Resolve the passphrases for the new and CA private keys
+	if c.EncryptKey && c.KeyFile != "" {
+		return fmt.Errorf("encryptKey does not apply to an existing keyFile, which is not rewritten")
+	}
+	if c.EncryptKey || needsPassphrase {
+		passphrase, err := resolvePassphrase(c.Passphrase, c.PassphraseEnv, c.PassphraseFile)
+		if err != nil {
+			return err
+		}
+		if passphrase == "" {
+			return fmt.Errorf("encryptKey and pkcs12 format require a passphrase, passphraseEnv or passphraseFile")
+		}
+		c.Passphrase = passphrase
+	}
+
+	// Resolve the keystore password, falling back to the key passphrase
+	if c.Format == FormatJKS {
+		password, err := resolvePassphrase(c.KeystorePassword, c.KeystorePasswordEnv, c.KeystorePasswordFile)
+		if err != nil {
+			return fmt.Errorf("keystore: %w", err)
+		}
+		if password == "" {
+			if password, err = resolvePassphrase(c.Passphrase, c.PassphraseEnv, c.PassphraseFile); err != nil {
+				return err
+			}
+		}
+		if len(password) < 6 {
+			return fmt.Errorf("jks format requires a keystore password of at least 6 characters (keystorePasswordEnv, keystorePasswordFile or passphrase)")
+		}
+		c.KeystorePassword = password
+		if c.Alias == "" {
+			c.Alias = c.FileName
+		}
+	}
+	if loadCA {
+		caPassphrase, err := resolvePassphrase(c.CAPassphrase, c.CAPassphraseEnv, c.CAPassphraseFile)
+		if err != nil {
+			return fmt.Errorf("CA key: %w", err)
+		}
+		c.CAPassphrase = caPassphrase
+	}
+
+	return nil
+}
+
+// Validate checks and sets default values for CSRConfig
+func (c *CSRConfig) Validate() error {
+	if err := validateDirMode(&c.DirMode); err != nil {
+		return err
+	}
+
+	if c.CommonName == "" {
+		return fmt.Errorf("commonName is required")
+	}
+	if len(c.Organization.values()) == 0 {
+		return fmt.Errorf("organization is required")
+	}
+	if len(c.Country.values()) == 0 {
+		return fmt.Errorf("country is required")
+	}
+	if err := validateCountries(c.Country.values()); err != nil {
+		return err
+	}
+
+	// Set default class if not specified
+	if c.Class == 0 {
+		c.Class = Class1
+	}
+
+	if c.KeyPath != "" {
+		// Key parameters come from the existing key, checked when it is loaded
+		if _, err := os.Stat(c.KeyPath); os.IsNotExist(err) {
+			return fmt.Errorf("private key not found at %s", c.KeyPath)
+		}
+		if c.KeyFormat != "" {
+			return fmt.Errorf("keyFormat does not apply to an existing keyPath, which is not rewritten")
+		}
+		if c.EncryptKey {
+			return fmt.Errorf("encryptKey does not apply to an existing keyPath, which is not rewritten")
+		}
+		passphrase, err := resolvePassphrase(c.KeyPassphrase, c.KeyPassphraseEnv, c.KeyPassphraseFile)
+		if err != nil {
+			return err
+		}
+		c.KeyPassphrase = passphrase
+	} else {
+		// Validate key type, size and curve
+		if err := validateKey(&c.KeyType, &c.KeySize, &c.Curve, c.Class, "certificate", false); err != nil {
+			return err
+		}
+		if err := validateKeyFormat(&c.KeyFormat, c.KeyType, "", c.EncryptKey); err != nil {
+			return err
+		}
+
+		// Resolve the passphrase for the generated private key
+		if c.EncryptKey {
+			passphrase, err := resolvePassphrase(c.Passphrase, c.PassphraseEnv, c.PassphraseFile)
+			if err != nil {
+				return err
+			}
+			if passphrase == "" {
+				return fmt.Errorf("encryptKey requires a passphrase, passphraseEnv or passphraseFile")
+			}
+			c.Passphrase = passphrase
+		}
+	}
+	if err := validateFileModes(&c.CertFileMode, &c.KeyFileMode, c.WorldReadableKey); err != nil {
+		return err
+	}
+
+	// Validate DNS, IP, email and URI SANs
+	if err := validateDNSNames(c.DNSNames); err != nil {
+		return err
+	}
+	if _, err := parseIPAddresses(c.IPAddresses); err != nil {
+		return err
+	}
+	if err := validateEmailAddresses(c.EmailAddresses); err != nil {
+		return err
+	}
+	if _, err := parseURIs(c.URIs); err != nil {
+		return err
+	}
+	c.DNSNames = dedupe(c.DNSNames, strings.ToLower)
+	c.IPAddresses = dedupe(c.IPAddresses, canonicalIP)
+	c.EmailAddresses = dedupe(c.EmailAddresses, canonicalEmail)
+
+	// Default to the common name when no SANs are configured, if it is a
+	// hostname rather than an IP address or a descriptive name
+	noSANs := len(c.DNSNames) == 0 && len(c.IPAddresses) == 0 && len(c.EmailAddresses) == 0 && len(c.URIs) == 0
+	if noSANs && validateDNSNames([]string{c.CommonName}) == nil {
+		c.DNSNames = []string{c.CommonName}
+	}
+
+	// Set default output directory
+	if c.OutputDir == "" {
+		c.OutputDir = "certs"
+	}
+	c.OutputDir = filepath.Clean(c.OutputDir)
+
+	return nil
+}
+
+// Validate checks the hierarchy's names and references and sets default
+// values. The individual certificates are validated when they are generated.
+func (c *HierarchyConfig) Validate() error {
+	// Set default output directory
+	if c.OutputDir == "" {
+		c.OutputDir = "pki"
+	}
+	c.OutputDir = filepath.Clean(c.OutputDir)
+
+	caNames := map[string]bool{"root": true}
+	for _, spec := range c.Intermediates {
+		if spec.Name == "" {
+			return fmt.Errorf("intermediate name is required")
+		}
+		if caNames[spec.Name] {
+			return fmt.Errorf("duplicate name %q", spec.Name)
+		}
+		caNames[spec.Name] = true
+	}
+	for i := range c.Intermediates {
+		spec := &c.Intermediates[i]
+		if spec.Parent == "" {
+			spec.Parent = "root"
+		}
+		if !caNames[spec.Parent] || spec.Parent == spec.Name {
+			return fmt.Errorf("intermediate %q: unknown parent %q", spec.Name, spec.Parent)
+		}
+	}
+
+	defaultIssuer := "root"
+	if len(c.Intermediates) > 0 {
+		defaultIssuer = c.Intermediates[len(c.Intermediates)-1].Name
+	}
+	leafNames := map[string]bool{}
+	for i := range c.Leaves {
+		spec := &c.Leaves[i]
+		if spec.Name == "" {
+			return fmt.Errorf("leaf name is required")
+		}
+		if caNames[spec.Name] || leafNames[spec.Name] {
+			return fmt.Errorf("duplicate name %q", spec.Name)
+		}
+		leafNames[spec.Name] = true
+		if spec.Issuer == "" {
+			spec.Issuer = defaultIssuer
+		}
+		if !caNames[spec.Issuer] {
+			return fmt.Errorf("leaf %q: unknown issuer %q", spec.Name, spec.Issuer)
+		}
+	}
+
+	return nil
+}
+
+// Validate checks the batch's CA and names and sets default values. The
+// individual certificates are validated when they are generated.
+func (c *BatchConfig) Validate() error {
+	if c.CACert == "" {
+		return fmt.Errorf("caCert path is required")
+	}
+	if c.CAKey == "" {
+		return fmt.Errorf("caKey path is required")
+	}
+	if _, err := os.Stat(c.CACert); os.IsNotExist(err) {
+		return fmt.Errorf("CA certificate not found at %s", c.CACert)
+	}
+	if _, err := os.Stat(c.CAKey); os.IsNotExist(err) && !isSignerKey(c.CAKey) {
+		return fmt.Errorf("CA private key not found at %s", c.CAKey)
+	}
+	caPassphrase, err := resolvePassphrase(c.CAPassphrase, c.CAPassphraseEnv, c.CAPassphraseFile)
+	if err != nil {
+		return fmt.Errorf("CA key: %w", err)
+	}
+	c.CAPassphrase = caPassphrase
+
+	// Set default output directory and worker count
+	if c.OutputDir == "" {
+		c.OutputDir = "certs"
+	}
+	c.OutputDir = filepath.Clean(c.OutputDir)
+	if c.Workers < 0 {
+		return fmt.Errorf("workers must not be negative")
+	}
+	if c.Workers == 0 {
+		c.Workers = runtime.NumCPU()
+	}
+
+	if len(c.Certificates) == 0 {
+		return fmt.Errorf("at least one certificate is required")
+	}
+	names := map[string]bool{}
+	for _, spec := range c.Certificates {
+		if spec.Name == "" {
+			return fmt.Errorf("certificate name is required")
+		}
+		if names[spec.Name] {
+			return fmt.Errorf("duplicate name %q", spec.Name)
+		}
+		names[spec.Name] = true
+	}
+
+	return nil
+}
+
+// Validate checks and sets default values for SignConfig
+func (c *SignConfig) Validate() error {
+	return c.validate(true)
+}
+
+// validate checks and sets default values for SignConfig. The CA paths are
+// only checked if loadCA is set.
+func (c *SignConfig) validate(loadCA bool) error {
+	if err := validateDirMode(&c.DirMode); err != nil {
+		return err
+	}
+
+	// Validate certificate paths
+	if c.CertPath == "" && c.CSRPath == "" {
+		return fmt.Errorf("certPath or csrPath is required")
+	}
+	if c.CertPath != "" && c.CSRPath != "" {
+		return fmt.Errorf("certPath and csrPath are mutually exclusive")
+	}
+	if loadCA && c.CACertPath == "" {
+		return fmt.Errorf("caCertPath is required")
+	}
+	if loadCA && c.CAKeyPath == "" {
+		return fmt.Errorf("caKeyPath is required")
+	}
+
+	// Check if certificate or request exists
+	if c.CertPath != "" {
+		if _, err := os.Stat(c.CertPath); os.IsNotExist(err) {
+			return fmt.Errorf("certificate not found at %s", c.CertPath)
+		}
+	}
+	if c.CSRPath != "" {
+		if _, err := os.Stat(c.CSRPath); os.IsNotExist(err) {
+			return fmt.Errorf("certificate request not found at %s", c.CSRPath)
+		}
+	}
+
+	// Check if certificate key exists
+	if c.KeyPath != "" {
+		if _, err := os.Stat(c.KeyPath); os.IsNotExist(err) {
+			return fmt.Errorf("certificate key not found at %s", c.KeyPath)
+		}
+	}
+
+	if c.ValidityDays < 0 {
+		return fmt.Errorf("validityDays must not be negative")
+	}
+	if err := validateSerialMode(&c.SerialMode); err != nil {
+		return err
+	}
+	if _, err := parseSignatureAlgorithm(c.SignatureAlgorithm); err != nil {
+		return err
+	}
+
+	if loadCA {
+		// Check if CA certificate exists
+		if _, err := os.Stat(c.CACertPath); os.IsNotExist(err) {
+			return fmt.Errorf("CA certificate not found at %s", c.CACertPath)
+		}
+
+		// Check if CA private key exists
+		if _, err := os.Stat(c.CAKeyPath); os.IsNotExist(err) && !isSignerKey(c.CAKeyPath) {
+			return fmt.Errorf("CA private key not found at %s", c.CAKeyPath)
+		}
+	}
+
+	// Resolve the passphrases for encrypted keys
+	keyPassphrase, err := resolvePassphrase(c.KeyPassphrase, c.KeyPassphraseEnv, c.KeyPassphraseFile)
+	if err != nil {
+		return fmt.Errorf("certificate key: %w", err)
+	}
+	c.KeyPassphrase = keyPassphrase
+	if loadCA {
+		caPassphrase, err := resolvePassphrase(c.CAPassphrase, c.CAPassphraseEnv, c.CAPassphraseFile)
+		if err != nil {
+			return fmt.Errorf("CA key: %w", err)
+		}
+		c.CAPassphrase = caPassphrase
+	}
+
+	// Set default output directory
+	if c.OutputDir == "" {
+		c.OutputDir = "certs"
+	}
+	c.OutputDir = filepath.Clean(c.OutputDir)
+	if err := validateFileName(&c.FileName, false, "", "signed"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Validate checks and sets default values for RenewConfig
+func (c *RenewConfig) Validate() error {
+	if err := validateDirMode(&c.DirMode); err != nil {
+		return err
+	}
+
+	// Validate certificate paths
+	if c.CertPath == "" {
+		return fmt.Errorf("certPath is required")
+	}
+	if c.KeyPath == "" {
+		return fmt.Errorf("keyPath is required")
+	}
+	if c.CACertPath == "" {
+		return fmt.Errorf("caCertPath is required")
+	}
+	if c.CAKeyPath == "" {
+		return fmt.Errorf("caKeyPath is required")
+	}
+
+	// Check if certificate exists
+	if _, err := os.Stat(c.CertPath); os.IsNotExist(err) {
+		return fmt.Errorf("certificate not found at %s", c.CertPath)
+	}
+
+	// Check if certificate key exists
+	if _, err := os.Stat(c.KeyPath); os.IsNotExist(err) {
+		return fmt.Errorf("certificate key not found at %s", c.KeyPath)
+	}
+
+	// Check if CA certificate exists
+	if _, err := os.Stat(c.CACertPath); os.IsNotExist(err) {
+		return fmt.Errorf("CA certificate not found at %s", c.CACertPath)
+	}
+
+	// Check if CA private key exists
+	if _, err := os.Stat(c.CAKeyPath); os.IsNotExist(err) && !isSignerKey(c.CAKeyPath) {
+		return fmt.Errorf("CA private key not found at %s", c.CAKeyPath)
+	}
+
+	if c.ValidityDays < 0 {
+		return fmt.Errorf("validityDays must not be negative")
+	}
+	if err := validateSerialMode(&c.SerialMode); err != nil {
+		return err
+	}
+	if _, err := parseSignatureAlgorithm(c.SignatureAlgorithm); err != nil {
+		return err
+	}
+
+	// Resolve the passphrases for encrypted keys
+	keyPassphrase, err := resolvePassphrase(c.KeyPassphrase, c.KeyPassphraseEnv, c.KeyPassphraseFile)
+	if err != nil {
+		return fmt.Errorf("certificate key: %w", err)
+	}
+	c.KeyPassphrase = keyPassphrase
+	caPassphrase, err := resolvePassphrase(c.CAPassphrase, c.CAPassphraseEnv, c.CAPassphraseFile)
+	if err != nil {
+		return fmt.Errorf("CA key: %w", err)
+	}
+	c.CAPassphrase = caPassphrase
+
+	// Set default output directory
+	if c.OutputDir == "" {
+		c.OutputDir = "certs"
+	}
+	c.OutputDir = filepath.Clean(c.OutputDir)
+
+	return nil
+}
+
+// Validate checks and sets default values for RevokeConfig
+func (c *RevokeConfig) Validate() error {
+	if err := validateDirMode(&c.DirMode); err != nil {
+		return err
+	}
+
+	// Validate CA paths
+	if c.CACertPath == "" {
+		return fmt.Errorf("caCertPath is required")
+	}
+	if c.CAKeyPath == "" {
+		return fmt.Errorf("caKeyPath is required")
+	}
+	if _, err := os.Stat(c.CACertPath); os.IsNotExist(err) {
+		return fmt.Errorf("CA certificate not found at %s", c.CACertPath)
+	}
+	if _, err := os.Stat(c.CAKeyPath); os.IsNotExist(err) && !isSignerKey(c.CAKeyPath) {
+		return fmt.Errorf("CA private key not found at %s", c.CAKeyPath)
+	}
+
+	// An empty certPath and serial only regenerates the CRL
+	if c.CertPath != "" && c.Serial != "" {
+		return fmt.Errorf("certPath and serial are mutually exclusive")
+	}
+	if c.CertPath != "" {
+		if _, err := os.Stat(c.CertPath); os.IsNotExist(err) {
+			return fmt.Errorf("certificate not found at %s", c.CertPath)
+		}
+	}
+	if c.Serial != "" {
+		// Read the serial the way cert and ca take it, so the number given
+		// at issuance revokes that certificate; the store keeps it in hex
+		serial, err := parseSerial(c.Serial)
+		if err != nil {
+			return err
+		}
+		c.Serial = serial.Text(16)
+	}
+
+	// Set default reason
+	if c.Reason == "" {
+		c.Reason = "unspecified"
+	}
+	if _, ok := revocationReasons[c.Reason]; !ok {
+		return fmt.Errorf("unsupported revocation reason %q", c.Reason)
+	}
+
+	if c.CRLValidityDays < 0 {
+		return fmt.Errorf("crlValidityDays must not be negative")
+	}
+	if c.CRLValidityDays == 0 {
+		c.CRLValidityDays = 7
+	}
+
+	// Resolve the passphrase for an encrypted CA key
+	caPassphrase, err := resolvePassphrase(c.CAPassphrase, c.CAPassphraseEnv, c.CAPassphraseFile)
+	if err != nil {
+		return fmt.Errorf("CA key: %w", err)
+	}
+	c.CAPassphrase = caPassphrase
+
+	// Set default output directory and store
+	if c.OutputDir == "" {
+		c.OutputDir = "certs"
+	}
+	c.OutputDir = filepath.Clean(c.OutputDir)
+	if c.StorePath == "" {
+		c.StorePath = filepath.Join(c.OutputDir, "revocations.json")
+	}
+
+	return nil
+}
+
+// Validate checks and sets default values for OCSPConfig
+func (c *OCSPConfig) Validate() error {
+	// Validate CA and signer paths
+	if c.CACertPath == "" {
+		return fmt.Errorf("caCertPath is required")
+	}
+	if _, err := os.Stat(c.CACertPath); os.IsNotExist(err) {
+		return fmt.Errorf("CA certificate not found at %s", c.CACertPath)
+	}
+	if (c.SignerCertPath == "") != (c.SignerKeyPath == "") {
+		return fmt.Errorf("signerCertPath and signerKeyPath must be set together")
+	}
+	if c.SignerCertPath != "" {
+		if _, err := os.Stat(c.SignerCertPath); os.IsNotExist(err) {
+			return fmt.Errorf("signer certificate not found at %s", c.SignerCertPath)
+		}
+		if _, err := os.Stat(c.SignerKeyPath); os.IsNotExist(err) {
+			return fmt.Errorf("signer private key not found at %s", c.SignerKeyPath)
+		}
+	} else {
+		if c.CAKeyPath == "" {
+			return fmt.Errorf("caKeyPath is required without a delegated signer")
+		}
+		if _, err := os.Stat(c.CAKeyPath); os.IsNotExist(err) && !isSignerKey(c.CAKeyPath) {
+			return fmt.Errorf("CA private key not found at %s", c.CAKeyPath)
+		}
+	}
+
+	// Resolve the passphrases for encrypted keys
+	keyPassphrase, err := resolvePassphrase(c.KeyPassphrase, c.KeyPassphraseEnv, c.KeyPassphraseFile)
+	if err != nil {
+		return fmt.Errorf("signer key: %w", err)
+	}
+	c.KeyPassphrase = keyPassphrase
+	caPassphrase, err := resolvePassphrase(c.CAPassphrase, c.CAPassphraseEnv, c.CAPassphraseFile)
+	if err != nil {
+		return fmt.Errorf("CA key: %w", err)
+	}
+	c.CAPassphrase = caPassphrase
+
+	if c.ResponseValidityHours < 0 {
+		return fmt.Errorf("responseValidityHours must not be negative")
+	}
+	if c.ResponseValidityHours == 0 {
+		c.ResponseValidityHours = 24
+	}
+
+	// Set default store and listen address
+	if c.StorePath == "" {
+		c.StorePath = filepath.Join(filepath.Dir(c.CACertPath), "revocations.json")
+	}
+	if c.Listen == "" {
+		c.Listen = ":8080"
+	}
+
+	return nil
 }
 
 // Validate checks and sets default values for TrustConfig
 func (c *TrustConfig) Validate() error {
+	if err := validateDirMode(&c.DirMode); err != nil {
+		return err
+	}
+
 	// Validate certificate path
 	if c.CertPath == "" {
 		return fmt.Errorf("certPath is required")
@@ -280,11 +2858,211 @@ func (c *TrustConfig) Validate() error {
 		return fmt.Errorf("certificate not found at %s", c.CertPath)
 	}
 
+	if err := validateTrustScope(&c.Scope); err != nil {
+		return err
+	}
+	if err := validateEscalation(&c.Escalation); err != nil {
+		return err
+	}
+
 	// Set default output directory
 	if c.OutputDir == "" {
 		c.OutputDir = "certs"
 	}
 	c.OutputDir = filepath.Clean(c.OutputDir)
+	if err := validateFileName(&c.FileName, false, "", "trusted"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Validate checks and sets default values for UntrustConfig
+func (c *UntrustConfig) Validate() error {
+	// Validate certificate path
+	if c.CertPath == "" {
+		return fmt.Errorf("certPath is required")
+	}
+
+	// Check if certificate exists
+	if _, err := os.Stat(c.CertPath); os.IsNotExist(err) {
+		return fmt.Errorf("certificate not found at %s", c.CertPath)
+	}
+
+	if err := validateTrustScope(&c.Scope); err != nil {
+		return err
+	}
+	return validateEscalation(&c.Escalation)
+}
+
+// validateTrustScope normalizes the trust scope, defaulting to system
+func validateTrustScope(scope *string) error {
+	*scope = strings.ToLower(*scope)
+	switch *scope {
+	case "":
+		*scope = "system"
+	case "system", "user":
+	default:
+		return fmt.Errorf("unsupported scope %q (must be system or user)", *scope)
+	}
+	return nil
+}
+
+// validateEscalation checks the privilege escalation command, defaulting to
+// sudo. Any command that runs its arguments as root, such as doas or pkexec,
+// may be used, and none runs the commands directly.
+func validateEscalation(escalation *string) error {
+	switch {
+	case *escalation == "":
+		*escalation = "sudo"
+	case strings.ContainsAny(*escalation, " \t"):
+		return fmt.Errorf("invalid escalation %q: must be a single command such as sudo, doas or pkexec, or none", *escalation)
+	}
+	return nil
+}
+
+// Validate checks and sets default values for BundleConfig
+func (c *BundleConfig) Validate() error {
+	if err := validateDirMode(&c.DirMode); err != nil {
+		return err
+	}
+
+	if len(c.Inputs) == 0 {
+		return fmt.Errorf("at least one input certificate is required")
+	}
+	for _, path := range c.Inputs {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return fmt.Errorf("certificate not found at %s", path)
+		}
+	}
+
+	// Set default output path
+	if c.OutputPath == "" {
+		c.OutputPath = "chain.pem"
+	}
+
+	return nil
+}
+
+// Validate checks and sets default values for ConvertConfig
+func (c *ConvertConfig) Validate() error {
+	if err := validateDirMode(&c.DirMode); err != nil {
+		return err
+	}
+
+	if c.Input == "" {
+		return fmt.Errorf("input is required")
+	}
+	if _, err := os.Stat(c.Input); os.IsNotExist(err) {
+		return fmt.Errorf("input not found at %s", c.Input)
+	}
+	if c.KeyPath != "" {
+		if _, err := os.Stat(c.KeyPath); os.IsNotExist(err) {
+			return fmt.Errorf("private key not found at %s", c.KeyPath)
+		}
+	}
+	if c.Output == "" {
+		return fmt.Errorf("output is required")
+	}
+
+	// Take the output format from the extension unless it is set
+	if c.Format == "" {
+		switch strings.ToLower(filepath.Ext(c.Output)) {
+		case ".pem", ".crt", ".key":
+			c.Format = FormatPEM
+		case ".der", ".cer":
+			c.Format = FormatDER
+		case ".p12", ".pfx":
+			c.Format = FormatPKCS12
+		default:
+			return fmt.Errorf("cannot tell the output format from %s, set format to pem, der or pkcs12", c.Output)
+		}
+	}
+	if _, err := validateFormat(&c.Format); err != nil {
+		return err
+	}
+	if c.Format != FormatPEM && c.Format != FormatDER && c.Format != FormatPKCS12 {
+		return fmt.Errorf("convert does not write %s, only pem, der or pkcs12", c.Format)
+	}
+	c.KeyFormat = KeyFormat(strings.ToLower(string(c.KeyFormat)))
+	if c.KeyFormat == "" {
+		c.KeyFormat = KeyFormatPKCS8
+	}
+	if c.KeyFormat != KeyFormatPKCS8 && c.KeyFormat != KeyFormatPKCS1 {
+		return fmt.Errorf("unsupported keyFormat %q (must be pkcs8 or pkcs1)", c.KeyFormat)
+	}
+	if c.KeyFormat == KeyFormatPKCS1 && c.EncryptKey {
+		return fmt.Errorf("keyFormat pkcs1 cannot be combined with encryptKey, encrypted keys are written as PKCS#8")
+	}
+	if c.EncryptKey && c.Format == FormatPKCS12 {
+		return fmt.Errorf("encryptKey does not apply to pkcs12, which is always encrypted")
+	}
+
+	passphrase, err := resolvePassphrase(c.Passphrase, c.PassphraseEnv, c.PassphraseFile)
+	if err != nil {
+		return err
+	}
+	if passphrase == "" && (c.EncryptKey || c.Format == FormatPKCS12) {
+		return fmt.Errorf("encryptKey and pkcs12 format require a passphrase, passphraseEnv or passphraseFile")
+	}
+	c.Passphrase = passphrase
+
+	return nil
+}
+
+// Validate checks and sets default values for ScanConfig
+func (c *ScanConfig) Validate() error {
+	if c.Dir == "" {
+		c.Dir = "certs"
+	}
+	if info, err := os.Stat(c.Dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("directory not found at %s", c.Dir)
+	}
+	if c.Warn == "" {
+		c.Warn = "30d"
+	}
+	if _, err := parseValidity(c.Warn); err != nil {
+		return fmt.Errorf("warn: %w", err)
+	}
+	if c.Output == nil {
+		c.Output = os.Stdout
+	}
+	return nil
+}
+
+// Validate checks and sets default values for ListTrustedConfig
+func (c *ListTrustedConfig) Validate() error {
+	return validateTrustScope(&c.Scope)
+}
+
+// Validate checks and sets default values for VerifyConfig
+func (c *VerifyConfig) Validate() error {
+	// Validate certificate paths
+	if c.CertPath == "" {
+		return fmt.Errorf("certPath is required")
+	}
+	if c.CACertPath == "" {
+		return fmt.Errorf("caCertPath is required")
+	}
+
+	// Check if certificate exists
+	if _, err := os.Stat(c.CertPath); os.IsNotExist(err) {
+		return fmt.Errorf("certificate not found at %s", c.CertPath)
+	}
+
+	// Check if CA certificate exists
+	if _, err := os.Stat(c.CACertPath); os.IsNotExist(err) {
+		return fmt.Errorf("CA certificate not found at %s", c.CACertPath)
+	}
+
+	// Set default usage
+	c.Usage = strings.ToLower(c.Usage)
+	if c.Usage == "" {
+		c.Usage = "any"
+	}
+	if _, ok := extKeyUsages[c.Usage]; !ok {
+		return fmt.Errorf("unsupported usage %q (must be any, server, client, email or code)", c.Usage)
+	}
 
 	return nil
 }
@@ -0,0 +1,36 @@
+package cert
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseValidity(t *testing.T) {
+	tests := []struct {
+		validity string
+		want     time.Duration
+		wantErr  bool
+	}{
+		{"90", 90 * 24 * time.Hour, false},
+		{"90d", 90 * 24 * time.Hour, false},
+		{"2w", 14 * 24 * time.Hour, false},
+		{"1y", 365 * 24 * time.Hour, false},
+		{"8760h", 8760 * time.Hour, false},
+		{"0d", 0, true},
+		{"-1d", 0, true},
+		{"1x", 0, true},
+		{"292y", 292 * 365 * 24 * time.Hour, false},
+		{"600y", 0, true},
+		{"9999999999999d", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseValidity(tt.validity)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseValidity(%q): error %v, want error %t", tt.validity, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseValidity(%q) = %s, want %s", tt.validity, got, tt.want)
+		}
+	}
+}
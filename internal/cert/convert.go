@@ -0,0 +1,243 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/youmark/pkcs8"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// converted holds the certificates and key read from a file being converted
+type converted struct {
+	format OutputFormat
+	cert   *x509.Certificate
+	chain  []*x509.Certificate
+	key    crypto.Signer
+}
+
+// ConvertCertificate reads a PEM, DER or PKCS#12 file, detecting its format
+// from the content, and writes its certificates and key in the configured
+// format. Conversions that would lose the private key are refused.
+func ConvertCertificate(config *ConvertConfig) error {
+	progress := NewGenerationProgress("Conversion", progressOutput(config.ProgressOutput, config.Quiet), !config.NoProgress)
+	defer progress.Complete()
+
+	if err := config.Validate(); err != nil {
+		return withKind(ErrValidation, fmt.Errorf("invalid convert configuration: %w", err))
+	}
+	if err := checkOverwrite([]string{config.Output}, config.Force); err != nil {
+		return err
+	}
+
+	// Load the input and any separate key
+	progress.StartProgress("Loading input")
+	data, err := os.ReadFile(config.Input)
+	if err != nil {
+		return withKind(ErrIO, fmt.Errorf("failed to read input: %w", err))
+	}
+	in, err := decodeConvertInput(data, []byte(config.Passphrase))
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", config.Input, err)
+	}
+	if config.KeyPath != "" {
+		if in.key != nil {
+			return fmt.Errorf("%s already holds a private key", config.Input)
+		}
+		if in.key, err = loadPrivateKey(config.KeyPath, []byte(config.Passphrase)); err != nil {
+			return err
+		}
+	}
+	if in.cert != nil && in.key != nil && !publicKeysEqual(in.key.Public(), in.cert.PublicKey) {
+		return fmt.Errorf("private key does not match the certificate %q", in.cert.Subject.CommonName)
+	}
+
+	progress.StartProgress("Writing " + string(config.Format))
+	if dir := filepath.Dir(config.Output); dir != "." {
+		if err := makeOutputDir(dir, config.DirMode, config.EnforceDirMode); err != nil {
+			return withKind(ErrIO, fmt.Errorf("failed to create output directory: %w", err))
+		}
+	}
+	var keyPassphrase []byte
+	if config.EncryptKey {
+		keyPassphrase = []byte(config.Passphrase)
+	}
+	switch config.Format {
+	case FormatPEM:
+		err = writeConvertedPEM(config.Output, in, keyPassphrase, config.KeyFormat, config.Force)
+	case FormatDER:
+		err = writeConvertedDER(progress, config.Output, in, keyPassphrase, config.KeyFormat, config.Force)
+	case FormatPKCS12:
+		if in.cert == nil || in.key == nil {
+			return fmt.Errorf("pkcs12 output requires a certificate and its private key, add the key with keyPath (--key)")
+		}
+		err = savePKCS12(config.Output, in.key, in.cert, in.chain, config.Passphrase, config.Force)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", config.Output, err)
+	}
+
+	progress.Summary(fmt.Sprintf("  %s (%s) -> %s (%s)", config.Input, in.format, config.Output, config.Format))
+	return nil
+}
+
+// decodeConvertInput parses PEM, a single DER certificate or key, or a
+// PKCS#12 bundle, decrypting keys and bundles with the passphrase
+func decodeConvertInput(data, passphrase []byte) (*converted, error) {
+	if strings.Contains(string(data), "-----BEGIN ") {
+		return decodeConvertPEM(data, passphrase)
+	}
+
+	if cert, err := x509.ParseCertificate(data); err == nil {
+		return &converted{format: FormatDER, cert: cert}, nil
+	}
+	if key, err := parseUnlabeledPrivateKey(data); err == nil {
+		return derKey(key)
+	}
+	if len(passphrase) > 0 {
+		if key, err := pkcs8.ParsePKCS8PrivateKey(data, passphrase); err == nil {
+			return derKey(key)
+		}
+	}
+
+	key, cert, chain, err := pkcs12.DecodeChain(data, string(passphrase))
+	if errors.Is(err, pkcs12.ErrIncorrectPassword) {
+		return nil, fmt.Errorf("PKCS#12 passphrase is incorrect or missing")
+	}
+	if err != nil {
+		// A bundle without a key, as used for Java trust stores
+		certs, storeErr := pkcs12.DecodeTrustStore(data, string(passphrase))
+		if storeErr != nil || len(certs) == 0 {
+			return nil, fmt.Errorf("unrecognized format, expected PEM, DER or PKCS#12")
+		}
+		return &converted{format: FormatPKCS12, cert: certs[0], chain: certs[1:]}, nil
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return &converted{format: FormatPKCS12, cert: cert, chain: chain, key: signer}, nil
+}
+
+// derKey wraps a private key parsed from DER
+func derKey(key any) (*converted, error) {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return &converted{format: FormatDER, key: signer}, nil
+}
+
+// decodeConvertPEM reads the certificates and at most one private key of a
+// PEM file. The first certificate is taken as the leaf of the others.
+func decodeConvertPEM(data, passphrase []byte) (*converted, error) {
+	in := &converted{format: FormatPEM}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		switch {
+		case block.Type == "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("parsing certificate: %w", err)
+			}
+			if in.cert == nil {
+				in.cert = cert
+			} else {
+				in.chain = append(in.chain, cert)
+			}
+		case strings.HasSuffix(block.Type, "PRIVATE KEY"):
+			if in.key != nil {
+				return nil, fmt.Errorf("more than one private key found")
+			}
+			key, err := parsePrivateKey(pem.EncodeToMemory(block), passphrase)
+			if err != nil {
+				return nil, err
+			}
+			in.key = key
+		case block.Type == "EC PARAMETERS":
+			// Written by openssl ecparam ahead of the key
+		default:
+			return nil, fmt.Errorf("%s blocks cannot be converted", block.Type)
+		}
+	}
+	if in.cert == nil && in.key == nil {
+		return nil, fmt.Errorf("no certificate or private key found")
+	}
+	return in, nil
+}
+
+// writeConvertedPEM writes the certificates followed by the key. A file
+// holding a key is created with private key permissions.
+func writeConvertedPEM(path string, in *converted, passphrase []byte, keyFormat KeyFormat, overwrite bool) error {
+	var out []byte
+	for _, cert := range append([]*x509.Certificate{in.cert}, in.chain...) {
+		if cert != nil {
+			out = append(out, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+		}
+	}
+
+	var (
+		file   *os.File
+		keyPEM []byte
+		err    error
+	)
+	if in.key != nil {
+		if keyPEM, err = encodePrivateKey(in.key, passphrase, keyFormat); err != nil {
+			return err
+		}
+		out = append(out, keyPEM...)
+		file, err = createKeyFile(path, overwrite)
+	} else {
+		file, err = createFile(path, certFileMode, overwrite)
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(out)
+	return err
+}
+
+// writeConvertedDER writes the certificate, or the key if there is none. DER
+// holds a single object, so a certificate with its key is refused rather
+// than dropping the key, while chain certificates are left out with a
+// warning.
+func writeConvertedDER(progress *GenerationProgress, path string, in *converted, passphrase []byte, keyFormat KeyFormat, overwrite bool) error {
+	if in.cert == nil {
+		_, keyDER, err := marshalPrivateKey(in.key, passphrase, keyFormat)
+		if err != nil {
+			return err
+		}
+		file, err := createKeyFile(path, overwrite)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = file.Write(keyDER)
+		return err
+	}
+
+	if in.key != nil {
+		return fmt.Errorf("DER holds a single object but the input has a certificate and a private key, convert to pem or pkcs12 instead")
+	}
+	if len(in.chain) > 0 {
+		progress.Warning(fmt.Sprintf("DER holds a single certificate, leaving out %d chain certificates", len(in.chain)))
+	}
+	file, err := createFile(path, certFileMode, overwrite)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(in.cert.Raw)
+	return err
+}
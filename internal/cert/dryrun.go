@@ -0,0 +1,54 @@
+package cert
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// printDryRun writes a summary of the certificate a dry run would issue and
+// the files that would be written
+func printDryRun(out io.Writer, operation string, result *Result, class CertificateClass, serialMode SerialMode, files []string) {
+	cert := result.Certificate
+	fmt.Fprintf(out, "Dry run for %s, nothing was written\n", operation)
+	fmt.Fprintf(out, "  Subject:   %s\n", cert.Subject)
+	if len(result.Chain) > 0 {
+		fmt.Fprintf(out, "  Issuer:    %s\n", result.Chain[0].Subject)
+	} else {
+		fmt.Fprintf(out, "  Issuer:    self-signed\n")
+	}
+	if class > 0 {
+		fmt.Fprintf(out, "  Class:     %d\n", class)
+	}
+	keyType, keySize, curve := keyParams(cert.PublicKey)
+	switch {
+	case keySize > 0:
+		fmt.Fprintf(out, "  Key:       %s %d bits\n", keyType, keySize)
+	case curve != "":
+		fmt.Fprintf(out, "  Key:       %s %s\n", keyType, curve)
+	default:
+		fmt.Fprintf(out, "  Key:       %s\n", keyType)
+	}
+	fmt.Fprintf(out, "  Validity:  %s to %s\n", cert.NotBefore.UTC().Format(time.RFC3339), cert.NotAfter.UTC().Format(time.RFC3339))
+	if serialMode == SerialSequential {
+		fmt.Fprintf(out, "  Serial:    next sequential serial of the CA\n")
+	} else {
+		fmt.Fprintf(out, "  Serial:    %X\n", cert.SerialNumber)
+	}
+	if cert.IsCA {
+		fmt.Fprintf(out, "  CA:        yes, max path length %d\n", cert.MaxPathLen)
+	}
+	if len(cert.DNSNames) > 0 {
+		fmt.Fprintf(out, "  DNS names: %v\n", cert.DNSNames)
+	}
+	if len(cert.IPAddresses) > 0 {
+		fmt.Fprintf(out, "  IPs:       %v\n", cert.IPAddresses)
+	}
+	if len(cert.EmailAddresses) > 0 {
+		fmt.Fprintf(out, "  Emails:    %v\n", cert.EmailAddresses)
+	}
+	fmt.Fprintf(out, "  Files:\n")
+	for _, file := range files {
+		fmt.Fprintf(out, "    %s\n", file)
+	}
+}
@@ -0,0 +1,47 @@
+package cert
+
+import "errors"
+
+// Error kinds returned by the package, wrapped so callers can tell failures
+// apart with errors.Is while the messages stay unchanged
+var (
+	// ErrValidation reports an invalid configuration
+	ErrValidation = errors.New("invalid configuration")
+	// ErrCALoad reports a CA certificate or key that could not be loaded or
+	// cannot issue certificates, such as an expired CA or one that is not a CA
+	ErrCALoad = errors.New("CA unavailable")
+	// ErrCAMismatch reports a CA key that does not belong to the CA
+	// certificate. It is also an ErrCALoad.
+	ErrCAMismatch = errors.New("CA key does not match CA certificate")
+	// ErrExists reports an output file that already exists and may not be
+	// overwritten
+	ErrExists = errors.New("output file exists")
+	// ErrIO reports a file that could not be read, created or written
+	ErrIO = errors.New("i/o error")
+	// ErrVerify reports an issued certificate that does not verify against
+	// its CA
+	ErrVerify = errors.New("certificate verification failed")
+)
+
+// kindError tags an error with one of the error kinds. Its message is that
+// of the wrapped error alone.
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// withKind tags err with kind, returning nil for a nil err
+func withKind(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &kindError{kind: kind, err: err}
+}
@@ -0,0 +1,44 @@
+package cert
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"strings"
+)
+
+// fingerprint formats a digest as colon-separated upper-case hex, as
+// OpenSSL and browsers show certificate fingerprints
+func fingerprint(digest []byte) string {
+	parts := make([]string, len(digest))
+	for i, b := range digest {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// reportFingerprints adds the SHA-1 and SHA-256 fingerprints of cert to the
+// summary if show is set, and writes the SHA-256 fingerprint to path if it
+// is not empty
+func reportFingerprints(progress *GenerationProgress, cert *x509.Certificate, show bool, path string, overwrite bool) error {
+	sha1Sum := sha1.Sum(cert.Raw)
+	sha256Sum := sha256.Sum256(cert.Raw)
+	if show {
+		progress.Summary("SHA1 Fingerprint:   " + fingerprint(sha1Sum[:]))
+		progress.Summary("SHA256 Fingerprint: " + fingerprint(sha256Sum[:]))
+	}
+	if path == "" {
+		return nil
+	}
+
+	file, err := createFile(path, certFileMode, overwrite)
+	if err != nil {
+		return fmt.Errorf("creating fingerprint file: %w", err)
+	}
+	defer file.Close()
+	if _, err := fmt.Fprintln(file, fingerprint(sha256Sum[:])); err != nil {
+		return fmt.Errorf("writing fingerprint file: %w", err)
+	}
+	return nil
+}
@@ -1,66 +1,239 @@
 package cert
 
 import (
+	"bytes"
 	"certgen/internal/system"
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"math/big"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"text/tabwriter"
 	"time"
+
+	"github.com/pavlo-v-chernykh/keystore-go/v4"
+	"github.com/smallstep/pkcs7"
+	"github.com/youmark/pkcs8"
+	"software.sslmate.com/src/go-pkcs12"
 )
 
 const (
 	certFileMode = 0644
 	keyFileMode  = 0600
+	dirMode      = 0755
 )
 
 // Result holds the generated certificate and key data
 type Result struct {
 	Certificate *x509.Certificate
-	PrivateKey  *rsa.PrivateKey
+	PrivateKey  crypto.Signer
+	Chain       []*x509.Certificate // Issuing CAs, starting with the direct issuer
 }
 
 // GenerateCA generates a Certificate Authority certificate and private key
 func GenerateCA(config *CAConfig) (*Result, error) {
-	progress := NewGenerationProgress("CA Certificate", !config.NoProgress)
+	progress := NewGenerationProgress("CA Certificate", progressOutput(config.ProgressOutput, config.Quiet), !config.NoProgress)
 	defer progress.Complete()
 
 	if err := config.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid CA configuration: %w", err)
+		return nil, withKind(ErrValidation, fmt.Errorf("invalid CA configuration: %w", err))
+	}
+
+	// Refuse to replace existing files before any key is generated
+	files := resultFiles(config.OutputDir, config.FileName, config.writesKey(), config.WritePublicKey, config.Format)
+	if config.Format == FormatPKCS7 {
+		files = append(files, filepath.Join(config.OutputDir, config.pkcs7FileName()))
+	}
+	var fingerprintPath string
+	if config.WriteFingerprint {
+		fingerprintPath = filepath.Join(config.OutputDir, config.FileName+".sha256")
+		files = append(files, fingerprintPath)
+	}
+	if err := checkOverwrite(files, config.Force); err != nil {
+		return nil, err
 	}
 
 	// Check output directory permissions
-	if err := ensureWritableDirectory(config.OutputDir); err != nil {
-		return nil, fmt.Errorf("output directory error: %w", err)
+	if !config.DryRun {
+		if err := ensureWritableDirectory(config.OutputDir, config.DirMode, config.EnforceDirMode); err != nil {
+			return nil, fmt.Errorf("output directory error: %w", err)
+		}
 	}
 
-	// Generate private key
-	progress.StartKeyGen()
-	privateKey, err := generatePrivateKey(config.KeySize)
+	result, err := NewGenerator().ca(config, nil, progress)
 	if err != nil {
 		return nil, err
 	}
-	progress.CompleteKeyGen()
+
+	if config.DryRun {
+		printDryRun(os.Stdout, "CA Certificate", result, config.Class, config.SerialMode, files)
+		return result, nil
+	}
+
+	var keyPassphrase []byte
+	if config.EncryptKey {
+		keyPassphrase = []byte(config.Passphrase)
+	}
+	if err := saveResult(result, keyPassphrase, config.KeyFormat, config.writesKey(), config.OutputDir, config.FileName, config.Force, progress); err != nil {
+		return nil, err
+	}
+	if config.WritePublicKey {
+		if err := savePublicKey(filepath.Join(config.OutputDir, config.FileName+".pub"), result.Certificate.PublicKey, config.Force); err != nil {
+			return nil, fmt.Errorf("saving public key: %w", err)
+		}
+	}
+
+	// Write PKCS#12 bundle with the parent CA, if any
+	if config.Format == FormatPKCS12 {
+		if err := savePKCS12(filepath.Join(config.OutputDir, config.FileName+".p12"), result.PrivateKey, result.Certificate, result.Chain, config.Passphrase, config.Force); err != nil {
+			return nil, fmt.Errorf("saving PKCS#12 bundle: %w", err)
+		}
+	}
+
+	// Write raw DER copies of the certificate and key
+	if config.Format == FormatDER {
+		if err := saveDER(result, keyPassphrase, config.KeyFormat, config.writesKey(), config.OutputDir, config.FileName, config.Force); err != nil {
+			return nil, err
+		}
+	}
+
+	// Write the certificate and its issuing chain as PKCS#7
+	if config.Format == FormatPKCS7 {
+		if err := savePKCS7(filepath.Join(config.OutputDir, config.pkcs7FileName()), result.Certificate, result.Chain, config.Force); err != nil {
+			return nil, fmt.Errorf("saving PKCS#7 bundle: %w", err)
+		}
+	}
+
+	progress.Issued(result.Certificate, config.OutputDir)
+	if err := reportFingerprints(progress, result.Certificate, config.Fingerprint, fingerprintPath, config.Force); err != nil {
+		return nil, err
+	}
+	if err := applyFileModes(files, config.CertFileMode, config.KeyFileMode); err != nil {
+		return nil, err
+	}
+	if err := writeReport(config.ReportOutput, result.Certificate, files); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ca generates a CA certificate and key from a validated configuration
+func (g *Generator) ca(config *CAConfig, issuer *Result, progress *GenerationProgress) (*Result, error) {
+	// Load the parent CA for intermediates
+	var (
+		parentCert *x509.Certificate
+		parentKey  crypto.Signer
+		chain      []*x509.Certificate
+		err        error
+	)
+	if issuer != nil {
+		parentCert, chain, err = issuerOf(issuer)
+		if err != nil {
+			return nil, err
+		}
+		parentKey = issuer.PrivateKey
+	} else if config.Type == Intermediate {
+		progress.StartCALoading()
+		parentCert, parentKey, err = loadCA(config.ParentCert, config.ParentKey, []byte(config.ParentPassphrase))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load parent CA: %w", err)
+		}
+		chain = []*x509.Certificate{parentCert}
+		progress.CompleteCALoading()
+	}
+	if parentCert != nil {
+		if err := checkIssuer(parentCert); err != nil {
+			return nil, fmt.Errorf("parent CA: %w", err)
+		}
+		if parentCert.MaxPathLen == 0 && parentCert.MaxPathLenZero {
+			return nil, fmt.Errorf("parent CA %q has path length 0 and does not allow intermediate CAs", parentCert.Subject.CommonName)
+		}
+	}
+
+	// Load the existing private key or generate a new one
+	var privateKey crypto.Signer
+	if config.KeyFile != "" {
+		progress.StartKeyLoading()
+		key, err := loadPrivateKey(config.KeyFile, []byte(config.KeyPassphrase))
+		if err != nil {
+			return nil, err
+		}
+		privateKey = key
+		progress.CompleteKeyLoading()
+	} else if !config.writesKey() {
+		key, err := createKMSKey(config, progress)
+		if err != nil {
+			return nil, err
+		}
+		privateKey = key
+	} else {
+		if config.InsecureFastKeys {
+			progress.Warning(insecureKeyWarning(config.KeyType, config.KeySize, config.Curve))
+		}
+		progress.StartKeyGen()
+		key, err := generatePrivateKey(g.rand, config.KeyType, config.KeySize, config.Curve)
+		if err != nil {
+			progress.stopElapsed()
+			return nil, err
+		}
+		privateKey = key
+		progress.CompleteKeyGen()
+	}
 
 	// Create certificate template
 	progress.StartTemplate()
-	template, err := createCATemplate(config)
+	template, err := createCATemplate(g.rand, config, privateKey.Public())
 	if err != nil {
 		return nil, err
 	}
+	parent, signer := template, crypto.Signer(privateKey)
+	if parentCert != nil {
+		// Signed by the parent, one level further down its path length
+		parent, signer = parentCert, parentKey
+		template.AuthorityKeyId = parentCert.SubjectKeyId
+		inheritIssuerURLs(template, parentCert)
+		if err := constrainPathLen(template, parentCert); err != nil {
+			return nil, err
+		}
+		warnOutlivesIssuer(progress, template, parentCert)
+	}
+	if template.SignatureAlgorithm, err = chooseSignatureAlgorithm(config.SignatureAlgorithm, signer.Public()); err != nil {
+		return nil, err
+	}
 	progress.CompleteTemplate()
 
+	// A dry run stops short of taking a sequential serial and signing
+	if config.DryRun {
+		template.PublicKey = privateKey.Public()
+		return &Result{Certificate: template, PrivateKey: privateKey, Chain: chain}, nil
+	}
+	if parentCert != nil {
+		if err := assignSerialNumber(template, config.SerialMode, config.ParentCert, config.ParentKey); err != nil {
+			return nil, err
+		}
+	}
+
 	// Sign certificate
 	progress.StartSigning()
-	cert, err := generateAndSaveCertificate(template, template, &privateKey.PublicKey, privateKey, config.OutputDir, "ca", progress)
+	cert, err := g.createCertificate(template, parent, privateKey.Public(), signer)
 	if err != nil {
 		return nil, err
 	}
@@ -69,136 +242,787 @@ func GenerateCA(config *CAConfig) (*Result, error) {
 	return &Result{
 		Certificate: cert,
 		PrivateKey:  privateKey,
+		Chain:       chain,
 	}, nil
 }
 
-// GenerateCertificate generates a certificate using the provided configuration
-func GenerateCertificate(config *CertConfig) error {
-	if err := config.Validate(); err != nil {
-		return fmt.Errorf("invalid certificate configuration: %w", err)
+// GenerateCertificate generates a certificate signed by the configured CA and
+// returns the parsed certificate and its private key
+func GenerateCertificate(config *CertConfig) (*Result, error) {
+	return generateCertificate(config, nil)
+}
+
+// GenerateCertificateWithCA generates and writes a certificate like
+// GenerateCertificate, but signed by an already loaded CA instead of the CA
+// files of the configuration
+func GenerateCertificateWithCA(config *CertConfig, ca *CABundle) (*Result, error) {
+	if config.CAKey == "" {
+		config.CAKey = ca.KeyPath
+	}
+	if config.CACert == "" {
+		config.CACert = ca.CertPath
 	}
+	return generateCertificate(config, ca.Issuer())
+}
 
-	// Create certificate template
-	template, err := createCertTemplate(config)
+// generateCertificate generates and writes a certificate signed by issuer,
+// or by the CA files of the configuration if issuer is nil
+func generateCertificate(config *CertConfig, issuer *Result) (*Result, error) {
+	progress := NewGenerationProgress("Certificate", progressOutput(config.ProgressOutput, config.Quiet), !config.NoProgress)
+	defer progress.Complete()
+
+	if err := config.validate(issuer == nil); err != nil {
+		return nil, withKind(ErrValidation, fmt.Errorf("invalid certificate configuration: %w", err))
+	}
+
+	// Refuse to replace existing files before any key is generated
+	files := resultFiles(config.OutputDir, config.FileName, config.KeyFile == "", config.WritePublicKey, config.Format)
+	if *config.WriteFullChain {
+		files = append(files, filepath.Join(config.OutputDir, config.fullChainFileName()))
+	}
+	if config.Format == FormatPKCS7 {
+		files = append(files, filepath.Join(config.OutputDir, config.pkcs7FileName()))
+	}
+	var fingerprintPath string
+	if config.WriteFingerprint {
+		fingerprintPath = filepath.Join(config.OutputDir, config.FileName+".sha256")
+		files = append(files, fingerprintPath)
+	}
+	if config.PEMOutput != nil {
+		files = nil
+	}
+	if err := checkOverwrite(files, config.Force); err != nil {
+		return nil, err
+	}
+
+	// Create output directory if it doesn't exist
+	if !config.DryRun && config.PEMOutput == nil {
+		if err := makeOutputDir(config.OutputDir, config.DirMode, config.EnforceDirMode); err != nil {
+			return nil, withKind(ErrIO, fmt.Errorf("failed to create output directory: %w", err))
+		}
+	}
+
+	result, err := NewGenerator().leaf(config, issuer, progress)
 	if err != nil {
-		return fmt.Errorf("failed to create certificate template: %w", err)
+		return nil, err
+	}
+
+	if config.DryRun {
+		printDryRun(os.Stdout, "Certificate", result, config.Class, config.SerialMode, files)
+		return result, nil
+	}
+
+	// Stream the certificate, its chain and optionally the key instead of
+	// writing files
+	if config.PEMOutput != nil {
+		if err := writePEMOutput(config, result); err != nil {
+			return nil, err
+		}
+		progress.Issued(result.Certificate, "")
+		if err := reportFingerprints(progress, result.Certificate, config.Fingerprint, "", false); err != nil {
+			return nil, err
+		}
+		if err := writeReport(config.ReportOutput, result.Certificate, nil); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	// Write certificate and, unless an existing key file is used, private key
+	var keyPassphrase []byte
+	if config.EncryptKey {
+		keyPassphrase = []byte(config.Passphrase)
+	}
+	if err := saveResult(result, keyPassphrase, config.KeyFormat, config.KeyFile == "", config.OutputDir, config.FileName, config.Force, progress); err != nil {
+		return nil, err
+	}
+	if config.WritePublicKey {
+		if err := savePublicKey(filepath.Join(config.OutputDir, config.FileName+".pub"), result.Certificate.PublicKey, config.Force); err != nil {
+			return nil, fmt.Errorf("saving public key: %w", err)
+		}
+	}
+
+	// Write the leaf followed by its issuing CA
+	if *config.WriteFullChain {
+		chainPath := filepath.Join(config.OutputDir, config.fullChainFileName())
+		if err := writeChain(chainPath, result.Certificate.Raw, fullChain(result.Chain, config.FullChainRoot), config.Force); err != nil {
+			return nil, fmt.Errorf("failed to write full chain: %w", err)
+		}
 	}
 
-	// Generate private key
-	privKey, err := generatePrivateKey(config.KeySize)
+	// Write PKCS#12 bundle with the issuing CA
+	if config.Format == FormatPKCS12 {
+		p12Path := filepath.Join(config.OutputDir, config.FileName+".p12")
+		if err := savePKCS12(p12Path, result.PrivateKey, result.Certificate, result.Chain, config.Passphrase, config.Force); err != nil {
+			return nil, fmt.Errorf("failed to write PKCS#12 bundle: %w", err)
+		}
+	}
+
+	// Write raw DER copies of the certificate and key
+	if config.Format == FormatDER {
+		if err := saveDER(result, keyPassphrase, config.KeyFormat, config.KeyFile == "", config.OutputDir, config.FileName, config.Force); err != nil {
+			return nil, err
+		}
+	}
+
+	// Write the certificate and its issuing chain as PKCS#7
+	if config.Format == FormatPKCS7 {
+		if err := savePKCS7(filepath.Join(config.OutputDir, config.pkcs7FileName()), result.Certificate, result.Chain, config.Force); err != nil {
+			return nil, fmt.Errorf("failed to write PKCS#7 bundle: %w", err)
+		}
+	}
+
+	// Write a Java KeyStore with the key entry under the configured alias
+	if config.Format == FormatJKS {
+		jksPath := filepath.Join(config.OutputDir, config.FileName+".jks")
+		if err := saveJKS(jksPath, config.Alias, result.PrivateKey, result.Certificate, result.Chain, config.KeystorePassword, config.Force); err != nil {
+			return nil, fmt.Errorf("failed to write Java KeyStore: %w", err)
+		}
+	}
+
+	progress.Issued(result.Certificate, config.OutputDir)
+	if err := reportFingerprints(progress, result.Certificate, config.Fingerprint, fingerprintPath, config.Force); err != nil {
+		return nil, err
+	}
+	if err := applyFileModes(files, config.CertFileMode, config.KeyFileMode); err != nil {
+		return nil, err
+	}
+	if err := writeReport(config.ReportOutput, result.Certificate, files); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// writePEMOutput writes a generated certificate, followed by its issuing chain
+// unless writeFullChain is disabled, and the private key if requested to the
+// PEM output of the configuration
+func writePEMOutput(config *CertConfig, result *Result) error {
+	certPEM, keyPEM, err := encodeResult(result, config.PEMOutputKey && config.KeyFile == "", config.EncryptKey, config.Passphrase, config.KeyFormat)
 	if err != nil {
-		return fmt.Errorf("failed to generate private key: %w", err)
+		return err
 	}
+	if *config.WriteFullChain {
+		for _, cert := range fullChain(result.Chain, config.FullChainRoot) {
+			certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+		}
+	}
+	if _, err := config.PEMOutput.Write(append(certPEM, keyPEM...)); err != nil {
+		return fmt.Errorf("writing PEM output: %w", err)
+	}
+	return nil
+}
 
+// leaf generates an end-entity certificate and key from a validated
+// configuration
+func (g *Generator) leaf(config *CertConfig, issuer *Result, progress *GenerationProgress) (*Result, error) {
 	// Load CA certificate and private key
-	caCert, caKey, err := loadCA(config.CACert, config.CAKey)
+	var (
+		caCert *x509.Certificate
+		caKey  crypto.Signer
+		chain  []*x509.Certificate
+		err    error
+	)
+	if issuer != nil {
+		caCert, chain, err = issuerOf(issuer)
+		if err != nil {
+			return nil, err
+		}
+		caKey = issuer.PrivateKey
+	} else {
+		progress.StartCALoading()
+		ca, err := LoadCABundle(config.CACert, config.CAKey, []byte(config.CAPassphrase))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load CA: %w", err)
+		}
+		caCert, caKey = ca.Certificate, ca.PrivateKey
+		if chain, err = loadCAChain(caCert, config.CAChain.values()); err != nil {
+			return nil, err
+		}
+		progress.CompleteCALoading()
+	}
+	if err := checkIssuer(caCert); err != nil {
+		return nil, err
+	}
+
+	// Load the existing private key or generate a new one
+	var privKey crypto.Signer
+	if config.KeyFile != "" {
+		progress.StartKeyLoading()
+		if privKey, err = loadPrivateKey(config.KeyFile, []byte(config.KeyPassphrase)); err != nil {
+			return nil, fmt.Errorf("failed to obtain private key: %w", err)
+		}
+		progress.CompleteKeyLoading()
+	} else {
+		if config.InsecureFastKeys {
+			progress.Warning(insecureKeyWarning(config.KeyType, config.KeySize, config.Curve))
+		}
+		progress.StartKeyGen()
+		if privKey, err = generatePrivateKey(g.rand, config.KeyType, config.KeySize, config.Curve); err != nil {
+			progress.stopElapsed()
+			return nil, fmt.Errorf("failed to obtain private key: %w", err)
+		}
+		progress.CompleteKeyGen()
+	}
+
+	// Create certificate template
+	progress.StartTemplate()
+	template, err := createCertTemplate(g.rand, config, privKey.Public(), caKey.Public())
 	if err != nil {
-		return fmt.Errorf("failed to load CA: %w", err)
+		return nil, fmt.Errorf("failed to create certificate template: %w", err)
+	}
+	inheritIssuerURLs(template, caCert)
+	warnOutlivesIssuer(progress, template, caCert)
+	warnRedundantDNSNames(progress, template.DNSNames)
+	if config.CertPurpose == PurposeClass && config.CommonNameSAN == CommonNameSANWarn && config.commonNameMissingFromSANs() {
+		progress.Warning(fmt.Sprintf("common name %q is not among the DNS names and will not be matched by clients", config.CommonName))
+	}
+	progress.CompleteTemplate()
+
+	// A dry run stops short of taking a sequential serial and signing
+	if config.DryRun {
+		template.PublicKey = privKey.Public()
+		return &Result{Certificate: template, PrivateKey: privKey, Chain: chain}, nil
+	}
+	if err := assignSerialNumber(template, config.SerialMode, config.CACert, config.CAKey); err != nil {
+		return nil, err
 	}
 
 	// Generate certificate
-	certDER, err := x509.CreateCertificate(rand.Reader, template, caCert, &privKey.PublicKey, caKey)
+	progress.StartSigning()
+	cert, err := g.createCertificate(template, caCert, privKey.Public(), caKey)
 	if err != nil {
-		return fmt.Errorf("failed to create certificate: %w", err)
+		return nil, err
 	}
+	if !config.NoVerify {
+		// Code signing and time stamp verifiers check that every CA of the
+		// chain allows the usage
+		usage := x509.ExtKeyUsageAny
+		switch config.Profile {
+		case ProfileCodeSign:
+			usage = x509.ExtKeyUsageCodeSigning
+		case ProfileTimestamping:
+			usage = x509.ExtKeyUsageTimeStamping
+		}
+		if err := verifyIssued(cert, caCert, usage); err != nil {
+			return nil, err
+		}
+	}
+	progress.CompleteSigning()
 
-	// Create output directory if it doesn't exist
-	if err := os.MkdirAll(config.OutputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+	return &Result{
+		Certificate: cert,
+		PrivateKey:  privKey,
+		Chain:       chain,
+	}, nil
+}
+
+// createKMSKey creates the CA key in the key management service named by
+// createKMSKey and opens it as a signer
+func createKMSKey(config *CAConfig, progress *GenerationProgress) (crypto.Signer, error) {
+	create, _ := keyCreator(config.CreateKMSKey) // Checked by Validate
+	progress.StartProgress(fmt.Sprintf("Creating CA key %s", config.CreateKMSKey))
+	ref, err := create(config.CreateKMSKey, config.KeyType, config.KeySize, config.Curve)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CA key: %w", err)
 	}
+	key, err := loadPrivateKey(ref, nil)
+	if err != nil {
+		return nil, err
+	}
+	progress.Summary(fmt.Sprintf("CA key:             %s", ref))
+	return key, nil
+}
 
-	// Write certificate and private key
-	certPath := filepath.Join(config.OutputDir, "cert.crt")
-	keyPath := filepath.Join(config.OutputDir, "cert.key")
+// verifyIssued checks that a newly signed certificate chains to its issuing
+// CA, catching template mistakes such as name constraint violations before
+// the certificate is written. It is verified at the start of its validity,
+// or of the CA's if that is later, and for the given extended key usage,
+// which may be any. Critical custom extensions were added on purpose and are
+// not held against it.
+func verifyIssued(cert, caCert *x509.Certificate, usage x509.ExtKeyUsage) error {
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+	at := cert.NotBefore
+	if caCert.NotBefore.After(at) {
+		at = caCert.NotBefore
+	}
+	leaf := *cert
+	leaf.UnhandledCriticalExtensions = nil
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:       roots,
+		CurrentTime: at,
+		KeyUsages:   []x509.ExtKeyUsage{usage},
+	})
+	if err != nil {
+		return withKind(ErrVerify, fmt.Errorf("issued certificate does not verify against CA %q (skip this check with noVerify): %w", caCert.Subject.CommonName, err))
+	}
+	return nil
+}
 
-	if err := writePEM(certPath, "CERTIFICATE", certDER); err != nil {
-		return fmt.Errorf("failed to write certificate: %w", err)
+// GenerateCSR generates a certificate signing request and, unless an existing
+// key is configured, a new private key
+func GenerateCSR(config *CSRConfig) error {
+	progress := NewGenerationProgress("Certificate Request", progressOutput(config.ProgressOutput, config.Quiet), !config.NoProgress)
+	defer progress.Complete()
+
+	if err := config.Validate(); err != nil {
+		return withKind(ErrValidation, fmt.Errorf("invalid CSR configuration: %w", err))
+	}
+
+	// Refuse to replace existing files before any key is generated
+	csrPath := filepath.Join(config.OutputDir, "request.csr")
+	keyPath := filepath.Join(config.OutputDir, "request.key")
+	files := []string{csrPath}
+	if config.KeyPath == "" {
+		files = append(files, keyPath)
+	}
+	if err := checkOverwrite(files, config.Force); err != nil {
+		return err
+	}
+
+	// Check output directory permissions
+	if err := ensureWritableDirectory(config.OutputDir, config.DirMode, config.EnforceDirMode); err != nil {
+		return fmt.Errorf("output directory error: %w", err)
 	}
 
-	privKeyPEM, err := x509.MarshalPKCS8PrivateKey(privKey)
+	var privKey crypto.Signer
+	if config.KeyPath != "" {
+		// Load the existing key and hold it to the class requirements
+		progress.StartKeyLoading()
+		key, err := loadPrivateKey(config.KeyPath, []byte(config.KeyPassphrase))
+		if err != nil {
+			return err
+		}
+		keyType, keySize, curve := keyParams(key.Public())
+		if err := validateKey(&keyType, &keySize, &curve, config.Class, "certificate", false); err != nil {
+			return fmt.Errorf("existing private key: %w", err)
+		}
+		privKey = key
+		progress.CompleteKeyLoading()
+	} else {
+		progress.StartKeyGen()
+		key, err := generatePrivateKey(rand.Reader, config.KeyType, config.KeySize, config.Curve)
+		if err != nil {
+			progress.stopElapsed()
+			return err
+		}
+		privKey = key
+		progress.CompleteKeyGen()
+	}
+
+	// Create the request template
+	progress.StartTemplate()
+	ipAddresses, err := parseIPAddresses(config.IPAddresses)
 	if err != nil {
-		return fmt.Errorf("failed to marshal private key: %w", err)
+		return err
 	}
+	uris, err := parseURIs(config.URIs)
+	if err != nil {
+		return err
+	}
+	template := &x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName:         config.CommonName,
+			Organization:       config.Organization.values(),
+			OrganizationalUnit: config.OrganizationalUnit.values(),
+			Country:            config.Country.values(),
+			Province:           config.Province.values(),
+			Locality:           config.Locality.values(),
+			StreetAddress:      config.StreetAddress.values(),
+			PostalCode:         config.PostalCode.values(),
+			SerialNumber:       config.SerialNumber,
+		},
+		DNSNames:           config.DNSNames,
+		IPAddresses:        ipAddresses,
+		EmailAddresses:     config.EmailAddresses,
+		URIs:               uris,
+		SignatureAlgorithm: signatureAlgorithmFor(privKey.Public()), // Self-signed by the requester
+	}
+	warnRedundantDNSNames(progress, template.DNSNames)
+	progress.CompleteTemplate()
 
-	if err := writePEM(keyPath, "PRIVATE KEY", privKeyPEM); err != nil {
-		return fmt.Errorf("failed to write private key: %w", err)
+	// Sign the request with the private key
+	progress.StartSigning()
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, template, privKey)
+	if err != nil {
+		return fmt.Errorf("failed to create certificate request: %w", err)
 	}
+	progress.CompleteSigning()
+
+	// Write the request and any newly generated key
+	progress.StartSaving()
+	if err := writePEM(csrPath, "CERTIFICATE REQUEST", csrDER, config.Force); err != nil {
+		return fmt.Errorf("failed to write certificate request: %w", err)
+	}
+	if config.KeyPath == "" {
+		var keyPassphrase []byte
+		if config.EncryptKey {
+			keyPassphrase = []byte(config.Passphrase)
+		}
+		if err := savePrivateKey(keyPath, privKey, keyPassphrase, config.KeyFormat, config.Force); err != nil {
+			return fmt.Errorf("failed to write private key: %w", err)
+		}
+	}
+	if err := applyFileModes(files, config.CertFileMode, config.KeyFileMode); err != nil {
+		return err
+	}
+	progress.CompleteSaving()
 
 	return nil
 }
 
-// loadCA loads a CA certificate and private key from files
-func loadCA(certPath, keyPath string) (*x509.Certificate, crypto.PrivateKey, error) {
-	// Read CA certificate
-	certPEM, err := os.ReadFile(certPath)
+// loadPrivateKey loads a PEM encoded private key from a file, or opens the
+// key of a registered signer. The passphrase is only used when the key is
+// encrypted.
+func loadPrivateKey(path string, passphrase []byte) (crypto.Signer, error) {
+	if open, ok := signerOpener(path); ok {
+		key, err := open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open signer %s: %w", path, err)
+		}
+		return key, nil
+	}
+	keyPEM, err := os.ReadFile(path)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		return nil, withKind(ErrIO, fmt.Errorf("failed to read private key: %w", err))
 	}
+	return parsePrivateKey(keyPEM, passphrase)
+}
 
-	block, _ := pem.Decode(certPEM)
-	if block == nil {
-		return nil, nil, fmt.Errorf("failed to decode CA certificate")
+// keyParams describes a public key in terms of the configured key type,
+// RSA size and ECDSA curve
+func keyParams(pub crypto.PublicKey) (KeyType, int, Curve) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return KeyTypeRSA, k.N.BitLen(), ""
+	case *ecdsa.PublicKey:
+		return KeyTypeECDSA, 0, Curve(strings.ReplaceAll(k.Curve.Params().Name, "-", ""))
+	case ed25519.PublicKey:
+		return KeyTypeEd25519, 0, ""
+	default:
+		return KeyType(fmt.Sprintf("%T", pub)), 0, "" // Rejected by validateKey
 	}
+}
 
-	caCert, err := x509.ParseCertificate(block.Bytes)
+// loadCA loads a CA certificate and private key from files. The passphrase is
+// only used when the key is encrypted. The certificate file may be a bundle,
+// in which case its first certificate is the CA.
+func loadCA(certPath, keyPath string, passphrase []byte) (*x509.Certificate, crypto.Signer, error) {
+	// Read CA certificate, ignoring any chain and non-certificate blocks
+	certs, err := readCertificates(certPath)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to parse CA certificate: %w", err)
+		return nil, nil, withKind(ErrCALoad, fmt.Errorf("failed to load CA certificate: %w", err))
 	}
+	caCert := certs[0]
 
-	// Read CA private key
-	keyPEM, err := os.ReadFile(keyPath)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to read CA private key: %w", err)
+	// Open a CA key held by a registered signer, or read it from its file
+	var caKey crypto.Signer
+	if open, ok := signerOpener(keyPath); ok {
+		if caKey, err = open(keyPath); err != nil {
+			return nil, nil, withKind(ErrCALoad, fmt.Errorf("failed to open CA signer %s: %w", keyPath, err))
+		}
+	} else {
+		keyPEM, err := os.ReadFile(keyPath)
+		if err != nil {
+			return nil, nil, withKind(ErrCALoad, withKind(ErrIO, fmt.Errorf("failed to read CA private key: %w", err)))
+		}
+		if caKey, err = parsePrivateKey(keyPEM, passphrase); err != nil {
+			return nil, nil, withKind(ErrCALoad, fmt.Errorf("failed to load CA private key: %w", err))
+		}
+	}
+
+	// Catch mismatched files before they sign certificates nothing verifies
+	if !publicKeysEqual(caKey.Public(), caCert.PublicKey) {
+		return nil, nil, withKind(ErrCALoad, withKind(ErrCAMismatch, fmt.Errorf("CA key %s does not match CA certificate %s", keyPath, certPath)))
+	}
+
+	return caCert, caKey, nil
+}
+
+// checkIssuer verifies that a CA certificate may issue certificates now: it
+// must be a CA with the keyCertSign key usage inside its validity period
+func checkIssuer(caCert *x509.Certificate) error {
+	name := caCert.Subject.CommonName
+	if !caCert.BasicConstraintsValid || !caCert.IsCA {
+		return withKind(ErrCALoad, fmt.Errorf("certificate %q is not a CA (basic constraints CA:FALSE or missing)", name))
+	}
+	if caCert.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return withKind(ErrCALoad, fmt.Errorf("CA certificate %q is not allowed to sign certificates (missing keyCertSign key usage)", name))
+	}
+	now := time.Now()
+	if now.After(caCert.NotAfter) {
+		return withKind(ErrCALoad, fmt.Errorf("CA certificate %q expired on %s", name, caCert.NotAfter.UTC().Format(time.DateOnly)))
+	}
+	if now.Before(caCert.NotBefore) {
+		return withKind(ErrCALoad, fmt.Errorf("CA certificate %q is not valid until %s", name, caCert.NotBefore.UTC().Format(time.DateOnly)))
 	}
+	return nil
+}
 
-	block, _ = pem.Decode(keyPEM)
-	if block == nil {
-		return nil, nil, fmt.Errorf("failed to decode CA private key")
+// constrainPathLen keeps a CA certificate template within the path length of
+// its issuer, one level further down. Issuers with path length 0 may only
+// issue end-entity certificates.
+func constrainPathLen(template, caCert *x509.Certificate) error {
+	if !template.IsCA {
+		return nil
+	}
+	if caCert.MaxPathLen == 0 && caCert.MaxPathLenZero {
+		return fmt.Errorf("CA %q has path length 0 and may only issue end-entity certificates", caCert.Subject.CommonName)
+	}
+	if caCert.MaxPathLen > 0 {
+		// A negative or unset zero length means unlimited
+		limit := caCert.MaxPathLen - 1
+		if template.MaxPathLen < 0 || (template.MaxPathLen == 0 && !template.MaxPathLenZero) || template.MaxPathLen > limit {
+			template.MaxPathLen = limit
+		}
+		template.MaxPathLenZero = template.MaxPathLen == 0
+	}
+	return nil
+}
+
+// warnOutlivesIssuer warns if a certificate would stay valid after its CA
+// expires, as it cannot be verified past that point. Overlaps of less than a
+// day, such as a leaf issued right after a CA with the same validity period,
+// are ignored.
+func warnOutlivesIssuer(progress *GenerationProgress, template, caCert *x509.Certificate) {
+	if template.NotAfter.After(caCert.NotAfter.Add(24 * time.Hour)) {
+		progress.Warning(fmt.Sprintf("certificate expires on %s, after its CA %q on %s",
+			template.NotAfter.UTC().Format(time.DateOnly), caCert.Subject.CommonName, caCert.NotAfter.UTC().Format(time.DateOnly)))
+	}
+}
+
+// warnRedundantDNSNames warns about DNS names that a wildcard among the
+// names already covers
+func warnRedundantDNSNames(progress *GenerationProgress, names []string) {
+	for _, name := range names {
+		if strings.HasPrefix(name, "*.") {
+			continue
+		}
+		for _, pattern := range names {
+			if strings.HasPrefix(pattern, "*.") && matchesWildcard(pattern, name) {
+				progress.Warning(fmt.Sprintf("DNS name %q is redundant, %q already covers it", name, pattern))
+				break
+			}
+		}
+	}
+}
+
+// parsePrivateKey decodes a PEM encoded PKCS#8, PKCS#1 (RSA) or SEC 1 (EC)
+// private key, decrypting it with the passphrase if it is an ENCRYPTED
+// PRIVATE KEY block. Blocks that hold no key, such as the EC PARAMETERS
+// written by openssl ecparam, are skipped.
+func parsePrivateKey(keyPEM, passphrase []byte) (crypto.Signer, error) {
+	var block *pem.Block
+	for rest := keyPEM; ; {
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, fmt.Errorf("failed to decode private key")
+		}
+		if strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			break
+		}
+	}
+	if block.Headers["Proc-Type"] == "4,ENCRYPTED" {
+		return nil, fmt.Errorf("legacy encrypted %s is not supported, convert it with openssl pkcs8 -topk8", block.Type)
 	}
 
-	caKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	var (
+		key any
+		err error
+	)
+	switch block.Type {
+	case "ENCRYPTED PRIVATE KEY":
+		if len(passphrase) == 0 {
+			return nil, fmt.Errorf("private key is encrypted but no passphrase was provided")
+		}
+		key, err = pkcs8.ParsePKCS8PrivateKey(block.Bytes, passphrase)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = parseUnlabeledPrivateKey(block.Bytes)
+	}
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to parse CA private key: %w", err)
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
 	}
 
-	return caCert, caKey, nil
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+// parseUnlabeledPrivateKey parses a key whose PEM block type does not name
+// its encoding, trying PKCS#8, then PKCS#1, then SEC 1. The PKCS#8 error is
+// returned if none matches, as that is what a PRIVATE KEY block should hold.
+func parseUnlabeledPrivateKey(der []byte) (any, error) {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err == nil {
+		return key, nil
+	}
+	if rsaKey, rsaErr := x509.ParsePKCS1PrivateKey(der); rsaErr == nil {
+		return rsaKey, nil
+	}
+	if ecKey, ecErr := x509.ParseECPrivateKey(der); ecErr == nil {
+		return ecKey, nil
+	}
+	return nil, err
 }
 
 // Helper functions
 
-func generatePrivateKey(keySize int) (*rsa.PrivateKey, error) {
-	key, err := rsa.GenerateKey(rand.Reader, keySize)
+// insecureKeyWarning describes the risk of a key generated with insecure fast
+// keys allowed
+func insecureKeyWarning(keyType KeyType, keySize int, curve Curve) string {
+	key := fmt.Sprintf("%d-bit RSA", keySize)
+	switch keyType {
+	case KeyTypeECDSA:
+		key = fmt.Sprintf("ECDSA %s", curve)
+	case KeyTypeEd25519:
+		key = "Ed25519"
+	}
+	return fmt.Sprintf("insecure fast keys allowed: generating a %s key that ignores the class requirements, for tests only", key)
+}
+
+func generatePrivateKey(random io.Reader, keyType KeyType, keySize int, curve Curve) (crypto.Signer, error) {
+	var (
+		key crypto.Signer
+		err error
+	)
+	switch keyType {
+	case KeyTypeRSA, "":
+		key, err = rsa.GenerateKey(random, keySize)
+	case KeyTypeECDSA:
+		var c elliptic.Curve
+		switch curve {
+		case CurveP256:
+			c = elliptic.P256()
+		case CurveP384:
+			c = elliptic.P384()
+		case CurveP521:
+			c = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %s", curve)
+		}
+		key, err = ecdsa.GenerateKey(c, random)
+	case KeyTypeEd25519:
+		_, key, err = ed25519.GenerateKey(random)
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", keyType)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("generating private key: %w", err)
 	}
 	return key, nil
 }
 
-func createCATemplate(config *CAConfig) (*x509.Certificate, error) {
-	serialNumber, err := generateSerialNumber()
+// signatureAlgorithmFor picks the signature algorithm matching the signing key
+func signatureAlgorithmFor(pub crypto.PublicKey) x509.SignatureAlgorithm {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return x509.SHA256WithRSA
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P384():
+			return x509.ECDSAWithSHA384
+		case elliptic.P521():
+			return x509.ECDSAWithSHA512
+		default:
+			return x509.ECDSAWithSHA256
+		}
+	case ed25519.PublicKey:
+		return x509.PureEd25519
+	default:
+		return x509.UnknownSignatureAlgorithm // Let x509 choose
+	}
+}
+
+// chooseSignatureAlgorithm returns the configured signature algorithm after
+// checking that the signing key can produce it, or the default for the key
+func chooseSignatureAlgorithm(name string, signer crypto.PublicKey) (x509.SignatureAlgorithm, error) {
+	choice, err := parseSignatureAlgorithm(name)
+	if err != nil {
+		return 0, err
+	}
+	if choice.algorithm == x509.UnknownSignatureAlgorithm {
+		return signatureAlgorithmFor(signer), nil
+	}
+	if keyType, _, _ := keyParams(signer); keyType != choice.keyType {
+		return 0, fmt.Errorf("signature algorithm %s requires a signing key of type %s, not %s", name, choice.keyType, keyType)
+	}
+	return choice.algorithm, nil
+}
+
+func createCATemplate(random io.Reader, config *CAConfig, pub crypto.PublicKey) (*x509.Certificate, error) {
+	serialNumber, err := templateSerialNumber(random, config.Serial)
+	if err != nil {
+		return nil, err
+	}
+
+	subjectKeyID, err := generateSubjectKeyID(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	validity, err := validityDuration(config.NotBefore, config.NotAfter, config.Validity, config.ValidityDays)
+	if err != nil {
+		return nil, err
+	}
+	notBefore, notAfter, err := validityPeriod(config.NotBefore, config.NotAfter, config.Backdate, validity)
 	if err != nil {
 		return nil, err
 	}
 
-	now := time.Now()
 	template := &x509.Certificate{
 		SerialNumber: serialNumber,
 		Subject: pkix.Name{
 			CommonName:         config.CommonName,
-			Organization:       []string{config.Organization},
-			OrganizationalUnit: []string{config.OrganizationalUnit},
-			Country:            []string{config.Country},
-			Province:           []string{config.Province},
-			Locality:           []string{config.Locality},
+			Organization:       config.Organization.values(),
+			OrganizationalUnit: config.OrganizationalUnit.values(),
+			Country:            config.Country.values(),
+			Province:           config.Province.values(),
+			Locality:           config.Locality.values(),
+			StreetAddress:      config.StreetAddress.values(),
+			PostalCode:         config.PostalCode.values(),
+			SerialNumber:       config.SerialNumber,
 		},
-		NotBefore:             now,
-		NotAfter:              now.AddDate(0, 0, config.ValidityDays),
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		SignatureAlgorithm:    signatureAlgorithmFor(pub), // Self-signed
 		IsCA:                  true,
-		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		CRLDistributionPoints: config.CRLDistributionPoints,
+		OCSPServer:            config.OCSPServers,
+		IssuingCertificateURL: config.IssuingCertificateURL.values(),
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
 		BasicConstraintsValid: true,
-		SubjectKeyId:          generateSubjectKeyID(),
-		AuthorityKeyId:        generateSubjectKeyID(), // For root CA, AuthorityKeyId = SubjectKeyId
+		SubjectKeyId:          subjectKeyID,
+		AuthorityKeyId:        subjectKeyID, // Self-signed, AuthorityKeyId = SubjectKeyId
+	}
+
+	// Identify the CA itself by SANs, which some tooling keys off
+	template.DNSNames = config.DNSNames
+	template.EmailAddresses = config.EmailAddresses
+	if template.URIs, err = parseURIs(config.URIs); err != nil {
+		return nil, err
+	}
+
+	// Restrict the names the CA may issue for
+	if config.hasNameConstraints() {
+		template.PermittedDNSDomains = config.PermittedDNSDomains
+		template.ExcludedDNSDomains = config.ExcludedDNSDomains
+		if template.PermittedIPRanges, err = parseIPRanges(config.PermittedIPRanges); err != nil {
+			return nil, err
+		}
+		if template.ExcludedIPRanges, err = parseIPRanges(config.ExcludedIPRanges); err != nil {
+			return nil, err
+		}
+		template.PermittedEmailAddresses = config.PermittedEmailAddresses
+		template.ExcludedEmailAddresses = config.ExcludedEmailAddresses
+		template.PermittedDNSDomainsCritical = true // Marks the whole extension critical, per RFC 5280
 	}
 
 	// Configure class-specific settings
@@ -222,8 +1046,6 @@ func createCATemplate(config *CAConfig) (*x509.Certificate, error) {
 	if config.Type == Root {
 		// Root certificates are self-signed
 		template.AuthorityKeyId = template.SubjectKeyId
-		// Root certificates should have longer validity
-		template.NotAfter = now.AddDate(0, 0, config.ValidityDays)
 		// Root certificates should have specific key usage
 		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
 		// Root certificates should have specific extensions
@@ -241,37 +1063,132 @@ func createCATemplate(config *CAConfig) (*x509.Certificate, error) {
 		}
 	}
 
+	if err := applyKeyUsages(template, config.KeyUsages, config.KeyType); err != nil {
+		return nil, err
+	}
+	if err := addPolicies(template, config.PolicyOIDs); err != nil {
+		return nil, err
+	}
+	if template.ExtraExtensions, err = parseExtensions(config.ExtraExtensions); err != nil {
+		return nil, fmt.Errorf("extraExtensions: %w", err)
+	}
+
 	return template, nil
 }
 
-func generateSubjectKeyID() []byte {
-	id := make([]byte, 20)
-	rand.Read(id)
-	return id
+// applyKeyUsages replaces the default key usages of template with the
+// configured ones, if any. Key encipherment only applies to RSA key
+// transport and is dropped for other key types.
+func applyKeyUsages(template *x509.Certificate, names []string, keyType KeyType) error {
+	if len(names) > 0 {
+		keyUsage, err := parseKeyUsages(names)
+		if err != nil {
+			return fmt.Errorf("keyUsages: %w", err)
+		}
+		template.KeyUsage = keyUsage
+	}
+	if keyType != KeyTypeRSA {
+		template.KeyUsage &^= x509.KeyUsageKeyEncipherment
+	}
+	return nil
 }
 
-func createCertTemplate(config *CertConfig) (*x509.Certificate, error) {
-	serialNumber, err := generateSerialNumber()
+// addPolicies adds the configured certificate policy OIDs to the class
+// defaults of template, skipping any it already has
+func addPolicies(template *x509.Certificate, oids []string) error {
+	policies, err := parseOIDs(oids)
+	if err != nil {
+		return fmt.Errorf("policyOIDs: %w", err)
+	}
+	for _, policy := range policies {
+		if !slices.ContainsFunc(template.PolicyIdentifiers, policy.Equal) {
+			template.PolicyIdentifiers = append(template.PolicyIdentifiers, policy)
+		}
+	}
+	return nil
+}
+
+// generateSubjectKeyID computes the key identifier as the SHA-1 hash of the
+// subjectPublicKey bit string (RFC 5280, section 4.2.1.2, method 1), so a
+// child's AuthorityKeyId matches its issuer's SubjectKeyId
+func generateSubjectKeyID(pub crypto.PublicKey) ([]byte, error) {
+	spkiDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling public key: %w", err)
+	}
+
+	var spki struct {
+		Algorithm        pkix.AlgorithmIdentifier
+		SubjectPublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(spkiDER, &spki); err != nil {
+		return nil, fmt.Errorf("parsing public key: %w", err)
+	}
+
+	id := sha1.Sum(spki.SubjectPublicKey.Bytes)
+	return id[:], nil
+}
+
+func createCertTemplate(random io.Reader, config *CertConfig, pub, caPub crypto.PublicKey) (*x509.Certificate, error) {
+	serialNumber, err := templateSerialNumber(random, config.Serial)
+	if err != nil {
+		return nil, err
+	}
+
+	subjectKeyID, err := generateSubjectKeyID(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	ipAddresses, err := parseIPAddresses(config.IPAddresses)
+	if err != nil {
+		return nil, err
+	}
+	uris, err := parseURIs(config.URIs)
+	if err != nil {
+		return nil, err
+	}
+
+	validity, err := validityDuration(config.NotBefore, config.NotAfter, config.Validity, config.ValidityDays)
+	if err != nil {
+		return nil, err
+	}
+	notBefore, notAfter, err := validityPeriod(config.NotBefore, config.NotAfter, config.Backdate, validity)
+	if err != nil {
+		return nil, err
+	}
+
+	signatureAlgorithm, err := chooseSignatureAlgorithm(config.SignatureAlgorithm, caPub)
 	if err != nil {
 		return nil, err
 	}
 
-	now := time.Now()
 	template := &x509.Certificate{
 		SerialNumber: serialNumber,
 		Subject: pkix.Name{
 			CommonName:         config.CommonName,
-			Organization:       []string{config.Organization},
-			OrganizationalUnit: []string{config.OrganizationalUnit},
-			Country:            []string{config.Country},
-			Province:           []string{config.Province},
-			Locality:           []string{config.Locality},
+			Organization:       config.Organization.values(),
+			OrganizationalUnit: config.OrganizationalUnit.values(),
+			Country:            config.Country.values(),
+			Province:           config.Province.values(),
+			Locality:           config.Locality.values(),
+			StreetAddress:      config.StreetAddress.values(),
+			PostalCode:         config.PostalCode.values(),
+			SerialNumber:       config.SerialNumber,
 		},
-		NotBefore:             now,
-		NotAfter:              now.AddDate(0, 0, config.ValidityDays),
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		SignatureAlgorithm:    signatureAlgorithm, // Signed by the CA key
+		SubjectKeyId:          subjectKeyID,
 		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
 		BasicConstraintsValid: true,
 		DNSNames:              config.DNSNames,
+		IPAddresses:           ipAddresses,
+		EmailAddresses:        config.EmailAddresses,
+		URIs:                  uris,
+		CRLDistributionPoints: config.CRLDistributionPoints,
+		OCSPServer:            config.OCSPServers,
+		IssuingCertificateURL: config.IssuingCertificateURL.values(),
 	}
 
 	// Configure class-specific settings
@@ -285,11 +1202,94 @@ func createCertTemplate(config *CertConfig) (*x509.Certificate, error) {
 		template.PolicyIdentifiers = []asn1.ObjectIdentifier{{2, 5, 29, 32, 0}} // Any Policy
 	}
 
+	// X.509-SVIDs authenticate workloads to each other over TLS
+	if config.Profile == ProfileSPIFFE {
+		template.KeyUsage = x509.KeyUsageDigitalSignature
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	}
+
+	// Mutual TLS client certificates only authenticate a client
+	if config.Profile == ProfileClient {
+		template.KeyUsage = x509.KeyUsageDigitalSignature
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	}
+
+	// Code signing certificates only sign code
+	if config.Profile == ProfileCodeSign {
+		template.KeyUsage = x509.KeyUsageDigitalSignature
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning}
+	}
+
+	// A time stamping authority has the single extended key usage RFC 3161
+	// requires, made critical below
+	if config.Profile == ProfileTimestamping {
+		template.KeyUsage = x509.KeyUsageDigitalSignature
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping}
+	}
+
+	// OCSP signers only sign responses
+	if config.CertPurpose == PurposeOCSPSigning {
+		template.KeyUsage = x509.KeyUsageDigitalSignature
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning}
+	}
+
+	// Configured extended key usages replace the class defaults
+	if len(config.ExtKeyUsages) > 0 {
+		if template.ExtKeyUsage, err = parseExtKeyUsages(config.ExtKeyUsages); err != nil {
+			return nil, fmt.Errorf("extKeyUsages: %w", err)
+		}
+	}
+	if err := applyKeyUsages(template, config.KeyUsages, config.KeyType); err != nil {
+		return nil, err
+	}
+
+	if err := addPolicies(template, config.PolicyOIDs); err != nil {
+		return nil, err
+	}
+	if template.ExtraExtensions, err = parseExtensions(config.ExtraExtensions); err != nil {
+		return nil, fmt.Errorf("extraExtensions: %w", err)
+	}
+
+	// Clients must not check the revocation status of an OCSP signer itself
+	// (RFC 6960 section 4.2.2.2.1)
+	if config.CertPurpose == PurposeOCSPSigning && !slices.ContainsFunc(template.ExtraExtensions, func(ext pkix.Extension) bool { return ext.Id.Equal(oidOCSPNoCheck) }) {
+		template.ExtraExtensions = append(template.ExtraExtensions, pkix.Extension{
+			Id:    oidOCSPNoCheck,
+			Value: asn1.NullBytes,
+		})
+	}
+
+	// crypto/x509 never marks the extended key usage extension critical, but
+	// one given as an extra extension replaces its own
+	if config.Profile == ProfileTimestamping {
+		value, err := asn1.Marshal([]asn1.ObjectIdentifier{oidExtKeyUsageTimeStamping})
+		if err != nil {
+			return nil, fmt.Errorf("encoding extended key usage: %w", err)
+		}
+		template.ExtraExtensions = append(template.ExtraExtensions, pkix.Extension{
+			Id:       oidExtensionExtKeyUsage,
+			Critical: true,
+			Value:    value,
+		})
+	}
+
 	return template, nil
 }
 
-func generateAndSaveCertificate(template, parent *x509.Certificate, pub *rsa.PublicKey, priv *rsa.PrivateKey, outDir, prefix string, progress *GenerationProgress) (*x509.Certificate, error) {
-	derBytes, err := x509.CreateCertificate(rand.Reader, template, parent, pub, priv)
+// oidOCSPNoCheck is the id-pkix-ocsp-nocheck extension
+var oidOCSPNoCheck = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 5}
+
+// Object identifiers of the extended key usage extension and of the time
+// stamping usage (RFC 5280 section 4.2.1.12)
+var (
+	oidExtensionExtKeyUsage    = asn1.ObjectIdentifier{2, 5, 29, 37}
+	oidExtKeyUsageTimeStamping = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 8}
+)
+
+// createCertificate signs the certificate for pub with signer and parses
+// the result
+func (g *Generator) createCertificate(template, parent *x509.Certificate, pub crypto.PublicKey, signer crypto.Signer) (*x509.Certificate, error) {
+	derBytes, err := x509.CreateCertificate(g.rand, template, parent, pub, signer)
 	if err != nil {
 		return nil, fmt.Errorf("creating certificate: %w", err)
 	}
@@ -298,7 +1298,12 @@ func generateAndSaveCertificate(template, parent *x509.Certificate, pub *rsa.Pub
 	if err != nil {
 		return nil, fmt.Errorf("parsing certificate: %w", err)
 	}
+	return cert, nil
+}
 
+// saveResult saves the certificate and, if saveKey is set, the private key
+// of result as prefix.crt and prefix.key in outDir
+func saveResult(result *Result, passphrase []byte, keyFormat KeyFormat, saveKey bool, outDir, prefix string, overwrite bool, progress *GenerationProgress) error {
 	// Use a WaitGroup to ensure both files are written
 	var wg sync.WaitGroup
 	var certErr, keyErr error
@@ -307,86 +1312,411 @@ func generateAndSaveCertificate(template, parent *x509.Certificate, pub *rsa.Pub
 	wg.Add(2)
 	go func() {
 		defer wg.Done()
-		certErr = saveCertificate(filepath.Join(outDir, prefix+".crt"), derBytes)
+		certErr = saveCertificate(filepath.Join(outDir, prefix+".crt"), result.Certificate.Raw, overwrite)
 	}()
 	go func() {
 		defer wg.Done()
-		keyErr = savePrivateKey(filepath.Join(outDir, prefix+".key"), priv)
+		if saveKey {
+			keyErr = savePrivateKey(filepath.Join(outDir, prefix+".key"), result.PrivateKey, passphrase, keyFormat, overwrite)
+		}
 	}()
 	wg.Wait()
 	progress.CompleteSaving()
 
 	if certErr != nil {
-		return nil, fmt.Errorf("saving certificate: %w", certErr)
+		return fmt.Errorf("saving certificate: %w", certErr)
+	}
+	if keyErr != nil {
+		return fmt.Errorf("saving private key: %w", keyErr)
+	}
+	return nil
+}
+
+func generateSerialNumber(random io.Reader) (*big.Int, error) {
+	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
+	serialNumber, err := rand.Int(random, serialNumberLimit)
+	if err != nil {
+		return nil, fmt.Errorf("generating serial number: %w", err)
+	}
+	return serialNumber, nil
+}
+
+func saveCertificate(path string, derBytes []byte, overwrite bool) error {
+	certOut, err := createFile(path, certFileMode, overwrite)
+	if err != nil {
+		return fmt.Errorf("creating certificate file: %w", err)
+	}
+	defer certOut.Close()
+
+	if err := pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: derBytes}); err != nil {
+		return fmt.Errorf("encoding certificate: %w", err)
+	}
+	return nil
+}
+
+// savePrivateKey writes the key as PKCS#8 or PKCS#1 PEM. A non-empty
+// passphrase encrypts it with PBES2 (PBKDF2-SHA256, AES-256-CBC).
+func savePrivateKey(path string, privateKey crypto.Signer, passphrase []byte, format KeyFormat, overwrite bool) error {
+	keyPEM, err := encodePrivateKey(privateKey, passphrase, format)
+	if err != nil {
+		return err
+	}
+
+	keyOut, err := createKeyFile(path, overwrite)
+	if err != nil {
+		return fmt.Errorf("creating private key file: %w", err)
+	}
+	defer keyOut.Close()
+
+	if _, err := keyOut.Write(keyPEM); err != nil {
+		return fmt.Errorf("writing private key: %w", err)
+	}
+	return nil
+}
+
+// encodePrivateKey encodes a private key as PEM in the given format,
+// encrypted as PKCS#8 if a passphrase is given
+func encodePrivateKey(privateKey crypto.Signer, passphrase []byte, format KeyFormat) ([]byte, error) {
+	blockType, der, err := marshalPrivateKey(privateKey, passphrase, format)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), nil
+}
+
+// marshalPrivateKey returns the DER encoding of a private key and its PEM
+// block type. PKCS#1 is only used for unencrypted RSA keys.
+func marshalPrivateKey(privateKey crypto.Signer, passphrase []byte, format KeyFormat) (string, []byte, error) {
+	if format == KeyFormatPKCS1 && len(passphrase) == 0 {
+		rsaKey, ok := privateKey.(*rsa.PrivateKey)
+		if !ok {
+			return "", nil, fmt.Errorf("pkcs1 key format requires an rsa key, got %T", privateKey)
+		}
+		return "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), nil
+	}
+
+	blockType := "PRIVATE KEY"
+	if len(passphrase) > 0 {
+		blockType = "ENCRYPTED PRIVATE KEY"
+	}
+	der, err := pkcs8.MarshalPrivateKey(privateKey, passphrase, nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("marshaling private key: %w", err)
+	}
+	return blockType, der, nil
+}
+
+// loadCAChain returns caCert followed by the CAs above it, in issuing order,
+// taken from the certificates in the chain files. Files may hold the
+// certificates in any order and may repeat caCert; certificates that are not
+// part of its chain are rejected so a wrong bundle is not silently ignored.
+func loadCAChain(caCert *x509.Certificate, paths []string) ([]*x509.Certificate, error) {
+	var candidates []*x509.Certificate
+	for _, path := range paths {
+		certs, err := readCertificates(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load CA chain: %w", err)
+		}
+		candidates = append(candidates, certs...)
+	}
+
+	chain := []*x509.Certificate{caCert}
+	for current := caCert; !isSelfSigned(current); {
+		i := slices.IndexFunc(candidates, func(c *x509.Certificate) bool {
+			return !slices.ContainsFunc(chain, c.Equal) && current.CheckSignatureFrom(c) == nil
+		})
+		if i < 0 {
+			break
+		}
+		current = candidates[i]
+		chain = append(chain, current)
+	}
+	for _, c := range candidates {
+		if !slices.ContainsFunc(chain, c.Equal) {
+			return nil, fmt.Errorf("CA chain certificate %q is not an issuer of CA %q", c.Subject.CommonName, caCert.Subject.CommonName)
+		}
+	}
+	return chain, nil
+}
+
+// isSelfSigned reports whether a certificate is a root, signed by its own key
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawSubject, cert.RawIssuer) && cert.CheckSignatureFrom(cert) == nil
+}
+
+// fullChain returns the CAs of a chain that go into the full chain output,
+// leaving out a self-signed root unless includeRoot is set, as clients must
+// already trust it
+func fullChain(chain []*x509.Certificate, includeRoot bool) []*x509.Certificate {
+	if includeRoot {
+		return chain
+	}
+	return slices.DeleteFunc(slices.Clone(chain), isSelfSigned)
+}
+
+// writeChain writes the leaf certificate followed by the CA certificates in
+// leaf-to-root order. Certificates already in the chain are skipped so a
+// self-signed CA is only written once.
+func writeChain(path string, leafDER []byte, chain []*x509.Certificate, overwrite bool) error {
+	file, err := createFile(path, certFileMode, overwrite)
+	if err != nil {
+		return fmt.Errorf("creating chain file: %w", err)
+	}
+	defer file.Close()
+
+	seen := [][]byte{leafDER}
+	if err := pem.Encode(file, &pem.Block{Type: "CERTIFICATE", Bytes: leafDER}); err != nil {
+		return fmt.Errorf("encoding certificate: %w", err)
+	}
+	for _, cert := range chain {
+		if slices.ContainsFunc(seen, func(der []byte) bool { return bytes.Equal(der, cert.Raw) }) {
+			continue
+		}
+		seen = append(seen, cert.Raw)
+		if err := pem.Encode(file, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}); err != nil {
+			return fmt.Errorf("encoding CA certificate: %w", err)
+		}
+	}
+	return nil
+}
+
+// savePKCS12 writes the key, certificate and CA chain as a PKCS#12 bundle
+// encrypted with AES-256 and the given passphrase
+func savePKCS12(path string, privateKey crypto.Signer, cert *x509.Certificate, chain []*x509.Certificate, passphrase string, overwrite bool) error {
+	pfxData, err := pkcs12.Modern.Encode(privateKey, cert, chain, passphrase)
+	if err != nil {
+		return fmt.Errorf("encoding PKCS#12: %w", err)
+	}
+	file, err := createKeyFile(path, overwrite)
+	if err != nil {
+		return fmt.Errorf("creating PKCS#12 file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(pfxData); err != nil {
+		return fmt.Errorf("writing PKCS#12 file: %w", err)
+	}
+	return nil
+}
+
+// saveJKS writes the key, certificate and CA chain as a single private key
+// entry of a Java KeyStore, protecting the store and the key with password
+func saveJKS(path, alias string, privateKey crypto.Signer, cert *x509.Certificate, chain []*x509.Certificate, password string, overwrite bool) error {
+	keyDER, err := pkcs8.MarshalPrivateKey(privateKey, nil, nil)
+	if err != nil {
+		return fmt.Errorf("marshaling private key: %w", err)
+	}
+	entry := keystore.PrivateKeyEntry{
+		CreationTime:     time.Now(),
+		PrivateKey:       keyDER,
+		CertificateChain: []keystore.Certificate{{Type: "X509", Content: cert.Raw}},
+	}
+	for _, c := range chain {
+		entry.CertificateChain = append(entry.CertificateChain, keystore.Certificate{Type: "X509", Content: c.Raw})
+	}
+
+	ks := keystore.New(keystore.WithMinPasswordLen(6))
+	if err := ks.SetPrivateKeyEntry(alias, entry, []byte(password)); err != nil {
+		return fmt.Errorf("adding key entry: %w", err)
+	}
+	file, err := createKeyFile(path, overwrite)
+	if err != nil {
+		return fmt.Errorf("creating keystore file: %w", err)
+	}
+	defer file.Close()
+	if err := ks.Store(file, []byte(password)); err != nil {
+		return fmt.Errorf("writing keystore file: %w", err)
+	}
+	return nil
+}
+
+// resultFiles lists the files GenerateCA and GenerateCertificate write for
+// a result named prefix in outDir, apart from the full and PKCS#7 chains
+func resultFiles(outDir, prefix string, saveKey, publicKey bool, format OutputFormat) []string {
+	files := []string{filepath.Join(outDir, prefix+".crt")}
+	if saveKey {
+		files = append(files, filepath.Join(outDir, prefix+".key"))
+	}
+	if publicKey {
+		files = append(files, filepath.Join(outDir, prefix+".pub"))
+	}
+	switch format {
+	case FormatPKCS12:
+		files = append(files, filepath.Join(outDir, prefix+".p12"))
+	case FormatJKS:
+		files = append(files, filepath.Join(outDir, prefix+".jks"))
+	case FormatDER:
+		files = append(files, filepath.Join(outDir, prefix+".der"))
+		if saveKey {
+			files = append(files, filepath.Join(outDir, prefix+".key.der"))
+		}
+	}
+	return files
+}
+
+// savePublicKey writes the public key as a PEM encoded SubjectPublicKeyInfo
+func savePublicKey(path string, pub crypto.PublicKey, overwrite bool) error {
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return fmt.Errorf("marshaling public key: %w", err)
+	}
+	return writePEM(path, "PUBLIC KEY", pubDER, overwrite)
+}
+
+// saveDER saves the certificate and, if saveKey is set, the private key of
+// result as raw DER in prefix.der and prefix.key.der in outDir. The key is
+// encoded and encrypted as savePrivateKey does.
+func saveDER(result *Result, passphrase []byte, keyFormat KeyFormat, saveKey bool, outDir, prefix string, overwrite bool) error {
+	certOut, err := createFile(filepath.Join(outDir, prefix+".der"), certFileMode, overwrite)
+	if err != nil {
+		return fmt.Errorf("creating DER certificate file: %w", err)
+	}
+	defer certOut.Close()
+	if _, err := certOut.Write(result.Certificate.Raw); err != nil {
+		return fmt.Errorf("writing DER certificate file: %w", err)
 	}
-	if keyErr != nil {
-		return nil, fmt.Errorf("saving private key: %w", keyErr)
+	if !saveKey {
+		return nil
 	}
 
-	return cert, nil
-}
-
-func generateSerialNumber() (*big.Int, error) {
-	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
-	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
+	_, keyDER, err := marshalPrivateKey(result.PrivateKey, passphrase, keyFormat)
 	if err != nil {
-		return nil, fmt.Errorf("generating serial number: %w", err)
+		return err
 	}
-	return serialNumber, nil
+	keyOut, err := createKeyFile(filepath.Join(outDir, prefix+".key.der"), overwrite)
+	if err != nil {
+		return fmt.Errorf("creating DER private key file: %w", err)
+	}
+	defer keyOut.Close()
+	if _, err := keyOut.Write(keyDER); err != nil {
+		return fmt.Errorf("writing DER private key file: %w", err)
+	}
+	return nil
 }
 
-func saveCertificate(path string, derBytes []byte) error {
-	certOut, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, certFileMode)
+// savePKCS7 writes the certificate followed by its issuing chain as a
+// degenerate PKCS#7 SignedData structure, which holds certificates only
+func savePKCS7(path string, cert *x509.Certificate, chain []*x509.Certificate, overwrite bool) error {
+	var certs []byte
+	seen := [][]byte{cert.Raw}
+	certs = append(certs, cert.Raw...)
+	for _, c := range chain {
+		if slices.ContainsFunc(seen, func(der []byte) bool { return bytes.Equal(der, c.Raw) }) {
+			continue
+		}
+		seen = append(seen, c.Raw)
+		certs = append(certs, c.Raw...)
+	}
+	p7b, err := pkcs7.DegenerateCertificate(certs)
 	if err != nil {
-		return fmt.Errorf("creating certificate file: %w", err)
+		return fmt.Errorf("encoding PKCS#7: %w", err)
 	}
-	defer certOut.Close()
 
-	if err := pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: derBytes}); err != nil {
-		return fmt.Errorf("encoding certificate: %w", err)
+	file, err := createFile(path, certFileMode, overwrite)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := file.Write(p7b); err != nil {
+		return fmt.Errorf("writing PKCS#7 file: %w", err)
 	}
 	return nil
 }
 
-func savePrivateKey(path string, privateKey *rsa.PrivateKey) error {
-	keyOut, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, keyFileMode)
-	if err != nil {
-		return fmt.Errorf("creating private key file: %w", err)
+// createFile opens path for writing. An existing file is only truncated if
+// overwrite is set; otherwise it is left untouched and an error returned.
+func createFile(path string, perm os.FileMode, overwrite bool) (*os.File, error) {
+	flag := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if overwrite {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
 	}
-	defer keyOut.Close()
+	file, err := os.OpenFile(path, flag, perm)
+	if errors.Is(err, fs.ErrExist) {
+		return nil, withKind(ErrExists, fmt.Errorf("%s already exists (use --force to overwrite)", path))
+	}
+	return file, withKind(ErrIO, err)
+}
 
-	privKeyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+// createKeyFile opens path for writing a private key. The mode is set to
+// owner-only after opening, since a replaced file keeps its old permissions.
+func createKeyFile(path string, overwrite bool) (*os.File, error) {
+	file, err := createFile(path, keyFileMode, overwrite)
 	if err != nil {
-		return fmt.Errorf("marshaling private key: %w", err)
+		return nil, err
+	}
+	if err := file.Chmod(keyFileMode); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("restricting permissions: %w", err)
+	}
+	return file, nil
+}
+
+// applyFileModes sets the configured permissions of written files: keyMode
+// for private keys and keystores, certMode for any other file. Files that
+// were not written are skipped.
+func applyFileModes(files []string, certMode, keyMode string) error {
+	for _, path := range files {
+		mode := certMode
+		if holdsPrivateKey(path) {
+			mode = keyMode
+		}
+		perm, err := parseFileMode(mode)
+		if err != nil {
+			return err
+		}
+		if err := os.Chmod(path, perm); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return withKind(ErrIO, fmt.Errorf("setting permissions of %s: %w", path, err))
+		}
+	}
+	return nil
+}
+
+// holdsPrivateKey reports whether an output file holds a private key
+func holdsPrivateKey(path string) bool {
+	for _, suffix := range []string{".key", ".key.der", ".p12", ".jks"} {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
 	}
+	return false
+}
 
-	if err := pem.Encode(keyOut, &pem.Block{
-		Type:  "PRIVATE KEY",
-		Bytes: privKeyBytes,
-	}); err != nil {
-		return fmt.Errorf("encoding private key: %w", err)
+// checkOverwrite fails if any of the output files exists and overwrite is
+// not set, so nothing is generated that could not be written
+func checkOverwrite(files []string, overwrite bool) error {
+	if overwrite {
+		return nil
+	}
+	for _, file := range files {
+		if _, err := os.Stat(file); err == nil {
+			return withKind(ErrExists, fmt.Errorf("%s already exists (use --force to overwrite)", file))
+		}
 	}
 	return nil
 }
 
-func ensureWritableDirectory(dir string) error {
+func ensureWritableDirectory(dir, mode string, enforce bool) error {
 	// Check if directory exists
 	info, err := os.Stat(dir)
 	if err != nil {
 		if os.IsNotExist(err) {
-			// Create directory with appropriate permissions
-			if err := os.MkdirAll(dir, 0755); err != nil {
-				return fmt.Errorf("creating directory: %w", err)
+			// Create directory with the configured permissions
+			if err := makeOutputDir(dir, mode, enforce); err != nil {
+				return withKind(ErrIO, fmt.Errorf("creating directory: %w", err))
 			}
 			return nil
 		}
-		return fmt.Errorf("checking directory: %w", err)
+		return withKind(ErrIO, fmt.Errorf("checking directory: %w", err))
 	}
 
 	// Check if it's a directory
 	if !info.IsDir() {
 		return fmt.Errorf("path exists but is not a directory")
 	}
+	if enforce {
+		if err := makeOutputDir(dir, mode, enforce); err != nil {
+			return withKind(ErrIO, fmt.Errorf("setting directory permissions: %w", err))
+		}
+	}
 
 	// Check if we can write to it
 	testFile := filepath.Join(dir, ".test")
@@ -400,8 +1730,30 @@ func ensureWritableDirectory(dir string) error {
 	return nil
 }
 
-func writePEM(path, blockType string, data []byte) error {
-	file, err := os.Create(path)
+// makeOutputDir creates dir and any missing parents. A directory it creates
+// gets exactly the octal permissions mode, which the umask would otherwise
+// narrow, as does an existing one if enforce is set. An empty mode is 0755.
+func makeOutputDir(dir, mode string, enforce bool) error {
+	perm := os.FileMode(dirMode)
+	if mode != "" {
+		var err error
+		if perm, err = parseFileMode(mode); err != nil {
+			return err
+		}
+	}
+	_, err := os.Stat(dir)
+	existed := err == nil
+	if err := os.MkdirAll(dir, perm); err != nil {
+		return err
+	}
+	if existed && !enforce {
+		return nil
+	}
+	return os.Chmod(dir, perm)
+}
+
+func writePEM(path, blockType string, data []byte, overwrite bool) error {
+	file, err := createFile(path, certFileMode, overwrite)
 	if err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
 	}
@@ -413,118 +1765,605 @@ func writePEM(path, blockType string, data []byte) error {
 	return nil
 }
 
-// SignCertificate signs an existing certificate with a CA
+// SignCertificate issues a new certificate from the CA for the subject and
+// public key of an existing certificate or a certificate request
 func SignCertificate(config *SignConfig) error {
-	progress := NewGenerationProgress("Certificate Signing", !config.NoProgress)
+	return signCertificate(config, nil)
+}
+
+// SignCertificateWithCA signs and writes a certificate like SignCertificate,
+// but with an already loaded CA instead of the CA files of the configuration
+func SignCertificateWithCA(config *SignConfig, ca *CABundle) error {
+	if config.CAKeyPath == "" {
+		config.CAKeyPath = ca.KeyPath
+	}
+	if config.CACertPath == "" {
+		config.CACertPath = ca.CertPath
+	}
+	return signCertificate(config, ca.Issuer())
+}
+
+// signCertificate signs and writes a certificate with issuer, or with the CA
+// files of the configuration if issuer is nil
+func signCertificate(config *SignConfig, issuer *Result) error {
+	progress := NewGenerationProgress("Certificate Signing", progressOutput(config.ProgressOutput, config.Quiet), !config.NoProgress)
 	defer progress.Complete()
 
-	if err := config.Validate(); err != nil {
-		return fmt.Errorf("invalid signing configuration: %w", err)
+	if err := config.validate(issuer == nil); err != nil {
+		return withKind(ErrValidation, fmt.Errorf("invalid signing configuration: %w", err))
+	}
+
+	// Refuse to replace an existing certificate before signing
+	signedCertPath := filepath.Join(config.OutputDir, config.FileName+".crt")
+	files := []string{signedCertPath}
+	var fingerprintPath string
+	if config.WriteFingerprint {
+		fingerprintPath = filepath.Join(config.OutputDir, config.FileName+".sha256")
+		files = append(files, fingerprintPath)
+	}
+	if err := checkOverwrite(files, config.Force); err != nil {
+		return err
+	}
+
+	// Create output directory if it doesn't exist
+	if !config.DryRun {
+		if err := makeOutputDir(config.OutputDir, config.DirMode, config.EnforceDirMode); err != nil {
+			return withKind(ErrIO, fmt.Errorf("failed to create output directory: %w", err))
+		}
+	}
+
+	result, err := NewGenerator().sign(config, issuer, progress)
+	if err != nil {
+		return err
+	}
+
+	if config.DryRun {
+		printDryRun(os.Stdout, "Certificate Signing", result, 0, config.SerialMode, files)
+		return nil
+	}
+
+	// Write the signed certificate
+	progress.StartSaving()
+	if err := writePEM(signedCertPath, "CERTIFICATE", result.Certificate.Raw, config.Force); err != nil {
+		return fmt.Errorf("failed to write signed certificate: %w", err)
+	}
+	progress.CompleteSaving()
+
+	progress.Issued(result.Certificate, config.OutputDir)
+	if err := reportFingerprints(progress, result.Certificate, config.Fingerprint, fingerprintPath, config.Force); err != nil {
+		return err
 	}
+	if err := writeReport(config.ReportOutput, result.Certificate, files); err != nil {
+		return err
+	}
+
+	return nil
+}
 
-	// Load the certificate to be signed
+// sign re-issues a certificate or certificate request from a validated
+// configuration
+func (g *Generator) sign(config *SignConfig, issuer *Result, progress *GenerationProgress) (*Result, error) {
+	// Load the certificate or request to be signed
 	progress.StartLoading()
-	certPEM, err := os.ReadFile(config.CertPath)
+	var (
+		template *x509.Certificate
+		pub      crypto.PublicKey
+		err      error
+	)
+	if config.CSRPath != "" {
+		template, pub, err = templateFromCSR(config.CSRPath)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		certs, err := readCertificates(config.CertPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load certificate: %w", err)
+		}
+		template, pub = templateFromCertificate(certs[0]), certs[0].PublicKey
+	}
+	progress.CompleteLoading()
+
+	// Check the certificate's private key matches, if provided
+	var privKey crypto.Signer
+	if config.KeyPath != "" {
+		progress.StartKeyLoading()
+		privKey, err = loadPrivateKey(config.KeyPath, []byte(config.KeyPassphrase))
+		if err != nil {
+			return nil, err
+		}
+		if !publicKeysEqual(privKey.Public(), pub) {
+			return nil, fmt.Errorf("private key does not match the certificate public key")
+		}
+		progress.CompleteKeyLoading()
+	}
+
+	// Load CA certificate and private key
+	var (
+		caCert *x509.Certificate
+		caKey  crypto.Signer
+		chain  []*x509.Certificate
+	)
+	if issuer != nil {
+		caCert, chain, err = issuerOf(issuer)
+		if err != nil {
+			return nil, err
+		}
+		caKey = issuer.PrivateKey
+	} else {
+		progress.StartCALoading()
+		ca, err := LoadCABundle(config.CACertPath, config.CAKeyPath, []byte(config.CAPassphrase))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load CA: %w", err)
+		}
+		caCert, caKey, chain = ca.Certificate, ca.PrivateKey, []*x509.Certificate{ca.Certificate}
+		progress.CompleteCALoading()
+	}
+	if err := checkIssuer(caCert); err != nil {
+		return nil, err
+	}
+
+	// Re-issue under the CA with a fresh serial and validity period
+	if err := reissueTemplate(g.rand, template, pub, caCert, caKey, config.ValidityDays); err != nil {
+		return nil, err
+	}
+	if template.SignatureAlgorithm, err = chooseSignatureAlgorithm(config.SignatureAlgorithm, caKey.Public()); err != nil {
+		return nil, err
+	}
+	template.BasicConstraintsValid = true
+	template.IsCA = config.IsCA
+	if config.IsCA {
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+		if err := constrainPathLen(template, caCert); err != nil {
+			return nil, err
+		}
+	} else {
+		template.MaxPathLen = 0
+		template.MaxPathLenZero = false
+		template.KeyUsage &^= x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	}
+	warnOutlivesIssuer(progress, template, caCert)
+
+	// A dry run stops short of taking a sequential serial and signing
+	if config.DryRun {
+		template.PublicKey = pub
+		return &Result{Certificate: template, PrivateKey: privKey, Chain: chain}, nil
+	}
+	if err := assignSerialNumber(template, config.SerialMode, config.CACertPath, config.CAKeyPath); err != nil {
+		return nil, err
+	}
+
+	// Sign the certificate
+	progress.StartSigning()
+	cert, err := g.createCertificate(template, caCert, pub, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign certificate: %w", err)
+	}
+	progress.CompleteSigning()
+
+	return &Result{
+		Certificate: cert,
+		PrivateKey:  privKey,
+		Chain:       chain,
+	}, nil
+}
+
+// reissueTemplate prepares a template copied from an earlier certificate or
+// request for issuance by the CA: a fresh serial, a validity window starting
+// now and the CA as authority. A zero validityDays keeps the template's
+// original validity period, or 365 days if it has none. An existing
+// SubjectKeyId is preserved, otherwise it is derived from pub.
+func reissueTemplate(random io.Reader, template *x509.Certificate, pub crypto.PublicKey, caCert *x509.Certificate, caKey crypto.Signer, validityDays int) error {
+	serialNumber, err := generateSerialNumber(random)
+	if err != nil {
+		return err
+	}
+	if len(template.SubjectKeyId) == 0 {
+		if template.SubjectKeyId, err = generateSubjectKeyID(pub); err != nil {
+			return err
+		}
+	}
+	validity := template.NotAfter.Sub(template.NotBefore)
+	if validityDays > 0 || validity <= 0 {
+		if validityDays == 0 {
+			validityDays = 365
+		}
+		validity = time.Duration(validityDays) * 24 * time.Hour
+	}
+	now := time.Now()
+	template.SerialNumber = serialNumber
+	template.NotBefore = now
+	template.NotAfter = now.Add(validity)
+	template.AuthorityKeyId = caCert.SubjectKeyId
+	template.SignatureAlgorithm = signatureAlgorithmFor(caKey.Public())
+	template.CRLDistributionPoints = nil
+	template.OCSPServer = nil
+	template.IssuingCertificateURL = nil
+	inheritIssuerURLs(template, caCert)
+	return nil
+}
+
+// inheritIssuerURLs copies the issuing CA's CRL distribution points, OCSP
+// responders and CA Issuers URLs to a template that does not configure its
+// own
+func inheritIssuerURLs(template, caCert *x509.Certificate) {
+	if len(template.CRLDistributionPoints) == 0 {
+		template.CRLDistributionPoints = caCert.CRLDistributionPoints
+	}
+	if len(template.OCSPServer) == 0 {
+		template.OCSPServer = caCert.OCSPServer
+	}
+	if len(template.IssuingCertificateURL) == 0 {
+		template.IssuingCertificateURL = caCert.IssuingCertificateURL
+	}
+}
+
+// templateFromCertificate builds a signing template from the subject, SANs
+// and key usages of an existing certificate
+func templateFromCertificate(cert *x509.Certificate) *x509.Certificate {
+	return &x509.Certificate{
+		Subject:            cert.Subject,
+		NotBefore:          cert.NotBefore,
+		NotAfter:           cert.NotAfter,
+		KeyUsage:           cert.KeyUsage,
+		ExtKeyUsage:        cert.ExtKeyUsage,
+		UnknownExtKeyUsage: cert.UnknownExtKeyUsage,
+		SubjectKeyId:       cert.SubjectKeyId,
+		DNSNames:           cert.DNSNames,
+		EmailAddresses:     cert.EmailAddresses,
+		IPAddresses:        cert.IPAddresses,
+		URIs:               cert.URIs,
+		PolicyIdentifiers:  cert.PolicyIdentifiers,
+	}
+}
+
+// templateFromCSR builds a signing template from a certificate request after
+// checking its self-signature
+func templateFromCSR(path string) (*x509.Certificate, crypto.PublicKey, error) {
+	csrPEM, err := os.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("failed to read certificate: %w", err)
+		return nil, nil, withKind(ErrIO, fmt.Errorf("failed to read certificate request: %w", err))
 	}
 
-	block, _ := pem.Decode(certPEM)
-	if block == nil {
-		return fmt.Errorf("failed to decode certificate")
+	block, _ := pem.Decode(csrPEM)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, nil, fmt.Errorf("failed to decode certificate request")
 	}
 
-	cert, err := x509.ParseCertificate(block.Bytes)
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
 	if err != nil {
-		return fmt.Errorf("failed to parse certificate: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse certificate request: %w", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, nil, fmt.Errorf("invalid certificate request signature: %w", err)
 	}
-	progress.CompleteLoading()
 
-	// Load the certificate's private key
-	progress.StartKeyLoading()
-	keyPEM, err := os.ReadFile(config.KeyPath)
+	keyUsage := x509.KeyUsageDigitalSignature
+	if _, ok := csr.PublicKey.(*rsa.PublicKey); ok {
+		keyUsage |= x509.KeyUsageKeyEncipherment
+	}
+
+	template := &x509.Certificate{
+		Subject:        csr.Subject,
+		KeyUsage:       keyUsage,
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:       csr.DNSNames,
+		EmailAddresses: csr.EmailAddresses,
+		IPAddresses:    csr.IPAddresses,
+		URIs:           csr.URIs,
+	}
+	return template, csr.PublicKey, nil
+}
+
+// publicKeysEqual reports whether two public keys are the same key
+func publicKeysEqual(a, b crypto.PublicKey) bool {
+	k, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && k.Equal(b)
+}
+
+// RenewCertificate re-issues a certificate with its existing private key so
+// the public key, and any pins on it, stay the same
+func RenewCertificate(config *RenewConfig) error {
+	progress := NewGenerationProgress("Certificate Renewal", progressOutput(config.ProgressOutput, config.Quiet), !config.NoProgress)
+	defer progress.Complete()
+
+	if err := config.Validate(); err != nil {
+		return withKind(ErrValidation, fmt.Errorf("invalid renewal configuration: %w", err))
+	}
+
+	// Refuse to replace an existing certificate before signing
+	renewedCertPath := filepath.Join(config.OutputDir, "renewed.crt")
+	if err := checkOverwrite([]string{renewedCertPath}, config.Force); err != nil {
+		return err
+	}
+
+	// Create output directory if it doesn't exist
+	if err := makeOutputDir(config.OutputDir, config.DirMode, config.EnforceDirMode); err != nil {
+		return withKind(ErrIO, fmt.Errorf("failed to create output directory: %w", err))
+	}
+
+	result, err := NewGenerator().renew(config, progress)
 	if err != nil {
-		return fmt.Errorf("failed to read private key: %w", err)
+		return err
+	}
+
+	// Write the renewed certificate
+	progress.StartSaving()
+	if err := writePEM(renewedCertPath, "CERTIFICATE", result.Certificate.Raw, config.Force); err != nil {
+		return fmt.Errorf("failed to write renewed certificate: %w", err)
 	}
+	progress.CompleteSaving()
+	progress.Issued(result.Certificate, config.OutputDir)
+
+	return nil
+}
 
-	block, _ = pem.Decode(keyPEM)
-	if block == nil {
-		return fmt.Errorf("failed to decode private key")
+// renew re-issues a certificate with its existing key from a validated
+// configuration, signed and verified like the certificates of sign and cert
+func (g *Generator) renew(config *RenewConfig, progress *GenerationProgress) (*Result, error) {
+	// Load the certificate to renew
+	progress.StartLoading()
+	certs, err := readCertificates(config.CertPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
 	}
+	oldCert := certs[0]
+	template, pub := templateFromCertificate(oldCert), oldCert.PublicKey
+	progress.CompleteLoading()
 
-	privKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	// Load the existing private key
+	progress.StartKeyLoading()
+	signer, err := loadPrivateKey(config.KeyPath, []byte(config.KeyPassphrase))
 	if err != nil {
-		return fmt.Errorf("failed to parse private key: %w", err)
+		return nil, err
+	}
+	if !publicKeysEqual(signer.Public(), pub) {
+		return nil, fmt.Errorf("private key does not match the certificate public key")
 	}
 	progress.CompleteKeyLoading()
 
 	// Load CA certificate and private key
 	progress.StartCALoading()
-	caCert, caKey, err := loadCA(config.CACertPath, config.CAKeyPath)
+	ca, err := LoadCABundle(config.CACertPath, config.CAKeyPath, []byte(config.CAPassphrase))
 	if err != nil {
-		return fmt.Errorf("failed to load CA: %w", err)
+		return nil, fmt.Errorf("failed to load CA: %w", err)
+	}
+	caCert, caKey := ca.Certificate, ca.PrivateKey
+	if err := checkIssuer(caCert); err != nil {
+		return nil, err
 	}
 	progress.CompleteCALoading()
 
-	// Create output directory if it doesn't exist
-	if err := os.MkdirAll(config.OutputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+	// Keep the basic constraints, re-issue with a fresh serial and validity
+	template.BasicConstraintsValid = oldCert.BasicConstraintsValid
+	template.IsCA = oldCert.IsCA
+	template.MaxPathLen = oldCert.MaxPathLen
+	template.MaxPathLenZero = oldCert.MaxPathLenZero
+	if err := constrainPathLen(template, caCert); err != nil {
+		return nil, err
+	}
+	if err := reissueTemplate(g.rand, template, pub, caCert, caKey, config.ValidityDays); err != nil {
+		return nil, err
+	}
+	if template.SignatureAlgorithm, err = chooseSignatureAlgorithm(config.SignatureAlgorithm, caKey.Public()); err != nil {
+		return nil, err
+	}
+	warnOutlivesIssuer(progress, template, caCert)
+	if err := assignSerialNumber(template, config.SerialMode, config.CACertPath, config.CAKeyPath); err != nil {
+		return nil, err
 	}
 
-	// Sign the certificate
+	// Sign the renewed certificate
 	progress.StartSigning()
-	certDER, err := x509.CreateCertificate(rand.Reader, cert, caCert, &privKey.(*rsa.PrivateKey).PublicKey, caKey)
+	cert, err := g.createCertificate(template, caCert, pub, caKey)
 	if err != nil {
-		return fmt.Errorf("failed to sign certificate: %w", err)
+		return nil, fmt.Errorf("failed to sign certificate: %w", err)
 	}
-	progress.CompleteSigning()
-
-	// Write the signed certificate
-	progress.StartSaving()
-	signedCertPath := filepath.Join(config.OutputDir, "signed.crt")
-	if err := writePEM(signedCertPath, "CERTIFICATE", certDER); err != nil {
-		return fmt.Errorf("failed to write signed certificate: %w", err)
+	if !config.NoVerify {
+		if err := verifyIssued(cert, caCert, x509.ExtKeyUsageAny); err != nil {
+			return nil, err
+		}
 	}
-	progress.CompleteSaving()
+	progress.CompleteSigning()
 
-	return nil
+	return &Result{Certificate: cert, PrivateKey: signer, Chain: []*x509.Certificate{caCert}}, nil
 }
 
 // TrustCertificate trusts a certificate in the system
 func TrustCertificate(config *TrustConfig) error {
-	progress := NewGenerationProgress("Certificate Trust", !config.NoProgress)
+	progress := NewGenerationProgress("Certificate Trust", progressOutput(config.ProgressOutput, config.Quiet), !config.NoProgress)
 	defer progress.Complete()
 
 	if err := config.Validate(); err != nil {
-		return fmt.Errorf("invalid trust configuration: %w", err)
+		return withKind(ErrValidation, fmt.Errorf("invalid trust configuration: %w", err))
 	}
 
 	// Create output directory if it doesn't exist
-	if err := os.MkdirAll(config.OutputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+	if err := makeOutputDir(config.OutputDir, config.DirMode, config.EnforceDirMode); err != nil {
+		return withKind(ErrIO, fmt.Errorf("failed to create output directory: %w", err))
 	}
 
 	// Read the certificate
 	progress.StartLoading()
 	certPEM, err := os.ReadFile(config.CertPath)
 	if err != nil {
-		return fmt.Errorf("failed to read certificate: %w", err)
+		return withKind(ErrIO, fmt.Errorf("failed to read certificate: %w", err))
 	}
 	progress.CompleteLoading()
 
-	// Copy the certificate to the output directory
+	// Copy the certificate to the output directory, leaving an identical copy
+	// from an earlier run in place
 	progress.StartSaving()
-	trustedCertPath := filepath.Join(config.OutputDir, "trusted.crt")
-	if err := os.WriteFile(trustedCertPath, certPEM, 0644); err != nil {
-		return fmt.Errorf("failed to write trusted certificate: %w", err)
+	trustedCertPath := filepath.Join(config.OutputDir, config.FileName+".crt")
+	if existing, err := os.ReadFile(trustedCertPath); err != nil || !bytes.Equal(existing, certPEM) {
+		if err := checkOverwrite([]string{trustedCertPath}, config.Force); err != nil {
+			return err
+		}
+		if err := os.WriteFile(trustedCertPath, certPEM, 0644); err != nil {
+			return withKind(ErrIO, fmt.Errorf("failed to write trusted certificate: %w", err))
+		}
 	}
 	progress.CompleteSaving()
 
-	// Install and trust the certificate
-	trustManager := system.NewCertificateTrustManager(progress)
-	if err := trustManager.InstallAndTrustCA(trustedCertPath); err != nil {
+	// Install and trust the certificate unless it already is, so that
+	// repeated runs do not stack duplicate trust anchors
+	trustManager := system.NewCertificateTrustManager(progress, config.Escalation)
+	trusted := false
+	if !config.Force {
+		if trusted, err = trustManager.IsTrustedCA(trustedCertPath, system.Scope(config.Scope)); err != nil {
+			return fmt.Errorf("failed to check the trust store (use --force to install anyway): %w", err)
+		}
+	}
+	if trusted {
+		progress.Summary(fmt.Sprintf("%s is already trusted, skipping installation (use --force to install it again)", config.CertPath))
+	} else if err := trustManager.InstallAndTrustCA(trustedCertPath, system.Scope(config.Scope)); err != nil {
 		return fmt.Errorf("failed to install and trust certificate: %w", err)
 	}
+	if config.NSS {
+		if err := trustManager.TrustCANSS(trustedCertPath); err != nil {
+			return fmt.Errorf("failed to trust certificate in NSS: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// UntrustCertificate removes a certificate trusted with TrustCertificate from
+// the system trust store
+func UntrustCertificate(config *UntrustConfig) error {
+	progress := NewGenerationProgress("Certificate Untrust", progressOutput(config.ProgressOutput, config.Quiet), !config.NoProgress)
+	defer progress.Complete()
+
+	if err := config.Validate(); err != nil {
+		return withKind(ErrValidation, fmt.Errorf("invalid untrust configuration: %w", err))
+	}
+
+	trustManager := system.NewCertificateTrustManager(progress, config.Escalation)
+	if err := trustManager.UntrustCA(config.CertPath, system.Scope(config.Scope)); err != nil {
+		return fmt.Errorf("failed to untrust certificate: %w", err)
+	}
+	if config.NSS {
+		if err := trustManager.UntrustCANSS(config.CertPath); err != nil {
+			return fmt.Errorf("failed to untrust certificate in NSS: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ListTrustedCertificates prints the subject, SHA-256 fingerprint and expiry
+// of the CA certificates in the system trust store
+func ListTrustedCertificates(config *ListTrustedConfig) error {
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid list-trusted configuration: %w", err)
+	}
+
+	cas, err := system.NewCertificateTrustManager(nil, "").ListTrustedCAs(system.Scope(config.Scope), config.All)
+	if err != nil {
+		return fmt.Errorf("failed to list trusted certificates: %w", err)
+	}
+	out := resultOutput(config.ProgressOutput, config.Quiet)
+	if len(cas) == 0 {
+		fmt.Fprintln(out, "No trusted CA certificates found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SUBJECT\tSHA256 FINGERPRINT\tEXPIRES\tSOURCE")
+	for _, ca := range cas {
+		subject := ca.Certificate.Subject.CommonName
+		if subject == "" {
+			subject = ca.Certificate.Subject.String()
+		}
+		sum := sha256.Sum256(ca.Certificate.Raw)
+		expires := ca.Certificate.NotAfter.UTC().Format(time.DateOnly)
+		if time.Now().After(ca.Certificate.NotAfter) {
+			expires += " (expired)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", subject, fingerprint(sum[:]), expires, ca.Source)
+	}
+	return w.Flush()
+}
+
+// VerifyCertificate verifies that a certificate chains to the given CA, is
+// within its validity period, carries the required key usage and, when a DNS
+// name is configured, is valid for that name
+func VerifyCertificate(config *VerifyConfig) error {
+	if err := config.Validate(); err != nil {
+		return withKind(ErrValidation, fmt.Errorf("invalid verify configuration: %w", err))
+	}
+
+	// Load the certificate and any intermediates bundled after it
+	certs, err := readCertificates(config.CertPath)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate: %w", err)
+	}
+	leaf := certs[0]
+
+	// Load the CA certificates; self-signed ones are roots
+	caCerts, err := readCertificates(config.CACertPath)
+	if err != nil {
+		return fmt.Errorf("failed to load CA certificate: %w", err)
+	}
+
+	roots := x509.NewCertPool()
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+	for _, c := range caCerts {
+		if bytes.Equal(c.RawIssuer, c.RawSubject) && c.CheckSignatureFrom(c) == nil {
+			roots.AddCert(c)
+		} else {
+			intermediates.AddCert(c)
+		}
+	}
+
+	chains, err := leaf.Verify(x509.VerifyOptions{
+		DNSName:       config.DNSName,
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{extKeyUsages[config.Usage]},
+	})
+	if err != nil {
+		return fmt.Errorf("verification failed: %w", err)
+	}
+
+	out := resultOutput(config.ProgressOutput, config.Quiet)
+	fmt.Fprintf(out, "✓ %s: OK\n", config.CertPath)
+	for i, c := range chains[0] {
+		fmt.Fprintf(out, "  %d: %s\n", i, c.Subject)
+	}
+	fmt.Fprintf(out, "  Valid until %s\n", leaf.NotAfter.Format(time.RFC3339))
 
 	return nil
 }
+
+// readCertificates reads every CERTIFICATE block from a PEM file
+func readCertificates(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, withKind(ErrIO, fmt.Errorf("reading %s: %w", path, err))
+	}
+
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing certificate in %s: %w", path, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return certs, nil
+}
@@ -0,0 +1,409 @@
+package cert
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplateSubjectHasNoEmptyRDNs(t *testing.T) {
+	key, err := generatePrivateKey(rand.Reader, KeyTypeECDSA, 0, CurveP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the common name is set; blank list entries must not become RDNs
+	caTemplate, err := createCATemplate(rand.Reader, &CAConfig{
+		Type:               Root,
+		CommonName:         "Root CA",
+		OrganizationalUnit: StringList{""},
+		ValidityDays:       30,
+	}, key.Public())
+	if err != nil {
+		t.Fatalf("createCATemplate: %v", err)
+	}
+	leafTemplate, err := createCertTemplate(rand.Reader, &CertConfig{
+		CommonName:   "www.example.com",
+		Locality:     StringList{""},
+		ValidityDays: 30,
+	}, key.Public(), key.Public())
+	if err != nil {
+		t.Fatalf("createCertTemplate: %v", err)
+	}
+
+	for _, template := range []*x509.Certificate{caTemplate, leafTemplate} {
+		der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+		if err != nil {
+			t.Fatalf("%s: CreateCertificate: %v", template.Subject.CommonName, err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkNoEmptyRDNs(t, cert)
+		if len(cert.Subject.Organization) != 0 || len(cert.Subject.Country) != 0 {
+			t.Errorf("%s: subject %s has organization or country attributes", cert.Subject.CommonName, cert.Subject)
+		}
+		if len(cert.Subject.Names) != 1 {
+			t.Errorf("%s: subject %s has %d attributes, want only the common name", cert.Subject.CommonName, cert.Subject, len(cert.Subject.Names))
+		}
+	}
+}
+
+// checkNoEmptyRDNs reports subject attributes without a value
+func checkNoEmptyRDNs(t *testing.T, cert *x509.Certificate) {
+	t.Helper()
+	for _, name := range cert.Subject.Names {
+		if value, ok := name.Value.(string); !ok || value == "" {
+			t.Errorf("%s: subject attribute %v has an empty value", cert.Subject.CommonName, name.Type)
+		}
+	}
+}
+
+func TestEd25519CertificateVerifiesAgainstIssuer(t *testing.T) {
+	caDir, certDir := t.TempDir(), t.TempDir()
+	_, err := GenerateCA(&CAConfig{
+		Type:         Root,
+		Class:        Class2,
+		CommonName:   "Ed25519 Root CA",
+		Organization: StringList{"Test"},
+		Country:      StringList{"US"},
+		ValidityDays: 3650,
+		KeyType:      KeyTypeEd25519,
+		OutputDir:    caDir,
+		Quiet:        true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateCA: %v", err)
+	}
+	caCert, caKey := filepath.Join(caDir, "ca.crt"), filepath.Join(caDir, "ca.key")
+	_, err = GenerateCertificate(&CertConfig{
+		Class:        Class2,
+		CommonName:   "www.example.com",
+		Organization: StringList{"Test"},
+		Country:      StringList{"US"},
+		DNSNames:     []string{"www.example.com"},
+		KeyType:      KeyTypeEd25519,
+		CACert:       caCert,
+		CAKey:        caKey,
+		OutputDir:    certDir,
+		Quiet:        true,
+	})
+	if err != nil {
+		t.Fatalf("GenerateCertificate: %v", err)
+	}
+
+	ca := readTestCertificate(t, caCert)
+	leaf := readTestCertificate(t, filepath.Join(certDir, "cert.crt"))
+	if _, ok := leaf.PublicKey.(ed25519.PublicKey); !ok {
+		t.Errorf("leaf key is %T, want ed25519.PublicKey", leaf.PublicKey)
+	}
+	for _, cert := range []*x509.Certificate{ca, leaf} {
+		if cert.SignatureAlgorithm != x509.PureEd25519 {
+			t.Errorf("%s: signature algorithm %v, want %v", cert.Subject.CommonName, cert.SignatureAlgorithm, x509.PureEd25519)
+		}
+	}
+	verifyChain(t, leaf, ca, x509.ExtKeyUsageServerAuth)
+
+	// Signing with an Ed25519 CA key must not assume an RSA key
+	signDir := t.TempDir()
+	err = SignCertificate(&SignConfig{
+		CertPath:   filepath.Join(certDir, "cert.crt"),
+		KeyPath:    filepath.Join(certDir, "cert.key"),
+		CACertPath: caCert,
+		CAKeyPath:  caKey,
+		OutputDir:  signDir,
+		Quiet:      true,
+	})
+	if err != nil {
+		t.Fatalf("SignCertificate: %v", err)
+	}
+	signed := readTestCertificate(t, filepath.Join(signDir, "signed.crt"))
+	if signed.SignatureAlgorithm != x509.PureEd25519 {
+		t.Errorf("signed: signature algorithm %v, want %v", signed.SignatureAlgorithm, x509.PureEd25519)
+	}
+	verifyChain(t, signed, ca, x509.ExtKeyUsageServerAuth)
+}
+
+func TestSubjectKeyIDVector(t *testing.T) {
+	// Key of RFC 8032 test 1; the expected identifier is the SHA-1 of the
+	// subjectPublicKey BIT STRING (RFC 5280 section 4.2.1.2, method 1), as
+	// written by openssl's subjectKeyIdentifier=hash
+	caKey := ed25519.NewKeyFromSeed(mustDecodeHex(t, "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60"))
+	want := mustDecodeHex(t, "5b27aa5589179770e47575b162a1ded97b8bfc6d")
+
+	id, err := generateSubjectKeyID(caKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(id, want) {
+		t.Fatalf("subject key id %x, want %x", id, want)
+	}
+
+	caTemplate, err := createCATemplate(rand.Reader, &CAConfig{
+		Type:         Root,
+		CommonName:   "Root CA",
+		ValidityDays: 30,
+	}, caKey.Public())
+	if err != nil {
+		t.Fatalf("createCATemplate: %v", err)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ca.SubjectKeyId, want) {
+		t.Errorf("CA subject key id %x, want %x", ca.SubjectKeyId, want)
+	}
+
+	key, err := generatePrivateKey(rand.Reader, KeyTypeECDSA, 0, CurveP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafTemplate, err := createCertTemplate(rand.Reader, &CertConfig{
+		CommonName:   "www.example.com",
+		ValidityDays: 30,
+	}, key.Public(), caKey.Public())
+	if err != nil {
+		t.Fatalf("createCertTemplate: %v", err)
+	}
+	der, err = x509.CreateCertificate(rand.Reader, leafTemplate, ca, key.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(leaf.AuthorityKeyId, want) {
+		t.Errorf("leaf authority key id %x, want the CA subject key id %x", leaf.AuthorityKeyId, want)
+	}
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestCodeSignLeafVerifiesForCodeSigningOnly(t *testing.T) {
+	g := NewGenerator()
+	ca, err := g.CA(&CAConfig{
+		Type:         Root,
+		Class:        Class3,
+		CommonName:   "Code Signing Root CA",
+		Organization: StringList{"Test"},
+		Country:      StringList{"US"},
+		ValidityDays: 3650,
+		KeyType:      KeyTypeECDSA,
+		Curve:        CurveP384,
+	}, nil)
+	if err != nil {
+		t.Fatalf("CA: %v", err)
+	}
+	leaf, err := g.Leaf(&CertConfig{
+		Class:        Class3,
+		Profile:      ProfileCodeSign,
+		CommonName:   "Test Code Signing",
+		Organization: StringList{"Test"},
+		Country:      StringList{"US"},
+		KeyType:      KeyTypeECDSA,
+		Curve:        CurveP384,
+	}, ca)
+	if err != nil {
+		t.Fatalf("Leaf: %v", err)
+	}
+
+	verifyChain(t, leaf.Certificate, ca.Certificate, x509.ExtKeyUsageCodeSigning)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Certificate)
+	_, err = leaf.Certificate.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}})
+	var invalid x509.CertificateInvalidError
+	if !errors.As(err, &invalid) || invalid.Reason != x509.IncompatibleUsage {
+		t.Errorf("verifying for serverAuth: got %v, want an incompatible usage error", err)
+	}
+}
+
+func TestBackdateCountsTowardsMaxValidity(t *testing.T) {
+	g := NewGenerator()
+	ca, err := g.CA(&CAConfig{
+		Type:         Root,
+		Class:        Class3,
+		CommonName:   "Backdate Root CA",
+		Organization: StringList{"Test"},
+		Country:      StringList{"US"},
+		ValidityDays: 3650,
+		KeyType:      KeyTypeECDSA,
+		Curve:        CurveP384,
+	}, nil)
+	if err != nil {
+		t.Fatalf("CA: %v", err)
+	}
+	leafConfig := func(validity string) *CertConfig {
+		return &CertConfig{
+			Class:        Class2,
+			CommonName:   "backdate.example.com",
+			Organization: StringList{"Test"},
+			Country:      StringList{"US"},
+			DNSNames:     []string{"backdate.example.com"},
+			Validity:     validity,
+			Backdate:     "48h",
+			KeyType:      KeyTypeECDSA,
+			Curve:        CurveP256,
+		}
+	}
+
+	_, maxValidityDays := getClassRequirements(Class2)
+	if _, err := g.Leaf(leafConfig(fmt.Sprintf("%dd", maxValidityDays)), ca); err == nil || !strings.Contains(err.Error(), "including the backdate") {
+		t.Errorf("maximum validity plus backdate: got %v, want an error", err)
+	}
+
+	// Without a validity the default leaves room for the backdate
+	leaf, err := g.Leaf(leafConfig(""), ca)
+	if err != nil {
+		t.Fatalf("Leaf: %v", err)
+	}
+	if got, want := leaf.Certificate.NotAfter.Sub(leaf.Certificate.NotBefore), time.Duration(maxValidityDays)*24*time.Hour; got != want {
+		t.Errorf("validity %s, want %s", got, want)
+	}
+}
+
+func TestClass1EmailRequiredForEmailProtection(t *testing.T) {
+	g := NewGenerator()
+	ca, err := g.CA(&CAConfig{
+		Type:         Root,
+		Class:        Class2,
+		CommonName:   "Class 1 Issuing Root CA",
+		Organization: StringList{"Test"},
+		Country:      StringList{"US"},
+		ValidityDays: 3650,
+		KeyType:      KeyTypeECDSA,
+		Curve:        CurveP384,
+	}, nil)
+	if err != nil {
+		t.Fatalf("CA: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		profile      Profile
+		extKeyUsages []string
+		wantErr      bool
+	}{
+		{"class defaults", "", nil, true},
+		{"emailProtection", "", []string{"clientAuth", "emailProtection"}, true},
+		{"serverAuth", "", []string{"serverAuth"}, false},
+		{"client profile", ProfileClient, nil, false},
+		{"codesign profile", ProfileCodeSign, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := g.Leaf(&CertConfig{
+				Class:        Class1,
+				Profile:      tt.profile,
+				CommonName:   "host.example.com",
+				Organization: StringList{"Test"},
+				Country:      StringList{"US"},
+				ExtKeyUsages: tt.extKeyUsages,
+				KeyType:      KeyTypeECDSA,
+				Curve:        CurveP256,
+			}, ca)
+			if gotErr := err != nil && strings.Contains(err.Error(), "emailAddresses"); gotErr != tt.wantErr {
+				t.Errorf("got %v, want an emailAddresses error: %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGeneratorRejectsSequentialSerials(t *testing.T) {
+	_, err := NewGenerator().CA(&CAConfig{
+		Type:         Root,
+		Class:        Class2,
+		CommonName:   "Sequential Root CA",
+		Organization: StringList{"Test"},
+		Country:      StringList{"US"},
+		ValidityDays: 3650,
+		KeyType:      KeyTypeECDSA,
+		Curve:        CurveP384,
+		SerialMode:   SerialSequential,
+	}, nil)
+	if !errors.Is(err, ErrValidation) || !strings.Contains(err.Error(), "serialMode sequential") {
+		t.Errorf("got %v, want a validation error for serialMode sequential", err)
+	}
+}
+
+func TestGenerateCSRWithEncryptedKey(t *testing.T) {
+	dir := t.TempDir()
+	key, err := generatePrivateKey(rand.Reader, KeyTypeECDSA, 0, CurveP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(dir, "existing.key")
+	if err := savePrivateKey(keyPath, key, []byte("key secret"), "", false); err != nil {
+		t.Fatal(err)
+	}
+	config := func() *CSRConfig {
+		return &CSRConfig{
+			CommonName:    "api.example.com",
+			Organization:  StringList{"Test"},
+			Country:       StringList{"US"},
+			DNSNames:      []string{"api.example.com"},
+			KeyPath:       keyPath,
+			KeyPassphrase: "key secret",
+			OutputDir:     filepath.Join(dir, "out"),
+			Quiet:         true,
+		}
+	}
+
+	if err := GenerateCSR(config()); err != nil {
+		t.Fatalf("GenerateCSR: %v", err)
+	}
+
+	encrypted := config()
+	encrypted.EncryptKey, encrypted.Passphrase, encrypted.Force = true, "new secret", true
+	if err := GenerateCSR(encrypted); !errors.Is(err, ErrValidation) {
+		t.Errorf("encryptKey with keyPath: got %v, want a validation error", err)
+	}
+}
+
+func BenchmarkGeneratePrivateKey(b *testing.B) {
+	benchmarks := []struct {
+		name    string
+		keyType KeyType
+		keySize int
+		curve   Curve
+	}{
+		{"RSA2048", KeyTypeRSA, 2048, ""},
+		{"RSA3072", KeyTypeRSA, 3072, ""},
+		{"RSA4096", KeyTypeRSA, 4096, ""},
+		{"ECDSAP256", KeyTypeECDSA, 0, CurveP256},
+		{"ECDSAP384", KeyTypeECDSA, 0, CurveP384},
+		{"ECDSAP521", KeyTypeECDSA, 0, CurveP521},
+		{"Ed25519", KeyTypeEd25519, 0, ""},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := generatePrivateKey(rand.Reader, bm.keyType, bm.keySize, bm.curve); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !windows
+
+package cert
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestKeyFilePermissions(t *testing.T) {
+	// A permissive umask must not widen the mode keys are created with
+	defer syscall.Umask(syscall.Umask(0))
+
+	dir := t.TempDir()
+	caCert, caKey := writeTestCA(t, filepath.Join(dir, "ca"))
+	writeTestLeaf(t, caCert, caKey, filepath.Join(dir, "leaf"))
+
+	for _, path := range []string{caKey, filepath.Join(dir, "leaf", "cert.key")} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("%s: mode %#o, want 0600", path, perm)
+		}
+	}
+}
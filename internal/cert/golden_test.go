@@ -0,0 +1,266 @@
+package cert
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// goldenKeyTypes are the key types each class is issued with
+var goldenKeyTypes = []KeyType{KeyTypeRSA, KeyTypeECDSA, KeyTypeEd25519}
+
+// TestTemplateGolden issues a root, an intermediate and a leaf for every
+// class and key type from a deterministic randomness source and compares
+// their parsed fields with testdata/golden. Roots must be Class 2 or higher,
+// so the Class 1 chain hangs off a Class 2 root that is not pinned again.
+// Run with -update to rewrite the files after an intended change.
+func TestTemplateGolden(t *testing.T) {
+	// The validity starts at midnight so that it is the same within a day
+	start := time.Now().UTC().Truncate(24 * time.Hour)
+
+	for _, class := range []CertificateClass{Class1, Class2, Class3} {
+		for _, keyType := range goldenKeyTypes {
+			name := fmt.Sprintf("class%d-%s", class, keyType)
+			t.Run(name, func(t *testing.T) {
+				g := NewGenerator(WithRand(newTestRand(name)))
+				root, err := g.CA(goldenCAConfig(Root, max(class, Class2), keyType, start), nil)
+				if err != nil {
+					t.Fatalf("root: %v", err)
+				}
+				intermediate, err := g.CA(goldenCAConfig(Intermediate, class, keyType, start), root)
+				if err != nil {
+					t.Fatalf("intermediate: %v", err)
+				}
+				leaf, err := g.Leaf(goldenCertConfig(class, keyType, start), intermediate)
+				if err != nil {
+					t.Fatalf("leaf: %v", err)
+				}
+
+				certs := map[string]*x509.Certificate{
+					"intermediate": intermediate.Certificate,
+					"leaf":         leaf.Certificate,
+				}
+				if class >= Class2 {
+					certs["root"] = root.Certificate
+				}
+				for kind, cert := range certs {
+					if !cert.NotBefore.Equal(start) {
+						t.Errorf("%s: notBefore %s, want %s", kind, cert.NotBefore, start)
+					}
+					checkNoEmptyRDNs(t, cert)
+					id, err := generateSubjectKeyID(cert.PublicKey)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !bytes.Equal(cert.SubjectKeyId, id) {
+						t.Errorf("%s: subject key id %x is not derived from the public key (%x)", kind, cert.SubjectKeyId, id)
+					}
+					compareGolden(t, filepath.Join("testdata", "golden", fmt.Sprintf("%s-%s.txt", name, kind)), describeTemplate(cert, keyType))
+				}
+			})
+		}
+	}
+}
+
+func goldenCAConfig(certType CertificateType, class CertificateClass, keyType KeyType, start time.Time) *CAConfig {
+	config := &CAConfig{
+		Type:         certType,
+		Class:        class,
+		CommonName:   fmt.Sprintf("Golden Class %d Intermediate CA", class),
+		Organization: StringList{"Golden"},
+		Country:      StringList{"US"},
+		NotBefore:    start.Format(time.RFC3339),
+		KeyType:      keyType,
+	}
+	if certType == Root {
+		config.CommonName = fmt.Sprintf("Golden Class %d Root CA", class)
+		config.ValidityDays = 3650
+		if keyType == KeyTypeRSA {
+			config.KeySize = 4096
+		}
+	} else {
+		_, config.ValidityDays = getClassRequirements(class)
+	}
+	if keyType == KeyTypeECDSA {
+		config.Curve = CurveP384
+	}
+	return config
+}
+
+func goldenCertConfig(class CertificateClass, keyType KeyType, start time.Time) *CertConfig {
+	config := &CertConfig{
+		Class:        class,
+		CommonName:   "golden.example.com",
+		Organization: StringList{"Golden"},
+		Country:      StringList{"US"},
+		DNSNames:     []string{"golden.example.com"},
+		NotBefore:    start.Format(time.RFC3339),
+		ValidityDays: 365,
+		KeyType:      keyType,
+	}
+	if class == Class1 {
+		config.EmailAddresses = []string{"golden@example.com"}
+	}
+	if keyType == KeyTypeECDSA {
+		config.Curve = CurveP384
+	}
+	return config
+}
+
+// describeTemplate renders the fields of cert that its template decides, one
+// per line. Go generates RSA and ECDSA keys with extra randomness of its own,
+// so serial numbers and key identifiers are only reproducible, and pinned,
+// for Ed25519.
+func describeTemplate(cert *x509.Certificate, keyType KeyType) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "subject: %s\n", cert.Subject)
+	fmt.Fprintf(&b, "issuer: %s\n", cert.Issuer)
+	fmt.Fprintf(&b, "publicKey: %s\n", describePublicKey(cert.PublicKey))
+	fmt.Fprintf(&b, "signatureAlgorithm: %s\n", cert.SignatureAlgorithm)
+	fmt.Fprintf(&b, "validity: %s\n", describeValidity(cert.NotAfter.Sub(cert.NotBefore)))
+	fmt.Fprintf(&b, "isCA: %t\n", cert.IsCA)
+	if cert.IsCA {
+		fmt.Fprintf(&b, "maxPathLen: %s\n", describePathLen(cert))
+	}
+	fmt.Fprintf(&b, "keyUsage: %s\n", describeKeyUsage(cert.KeyUsage))
+	fmt.Fprintf(&b, "extKeyUsage: %s\n", describeExtKeyUsage(cert.ExtKeyUsage))
+	fmt.Fprintf(&b, "policies: %s\n", describePolicies(cert))
+	fmt.Fprintf(&b, "dnsNames: %s\n", describeList(cert.DNSNames))
+	fmt.Fprintf(&b, "emailAddresses: %s\n", describeList(cert.EmailAddresses))
+	if keyType == KeyTypeEd25519 {
+		fmt.Fprintf(&b, "serial: %x\n", cert.SerialNumber)
+		fmt.Fprintf(&b, "subjectKeyId: %x\n", cert.SubjectKeyId)
+		fmt.Fprintf(&b, "authorityKeyId: %x\n", cert.AuthorityKeyId)
+	}
+	return b.String()
+}
+
+func describePublicKey(pub any) string {
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", pub.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA " + pub.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return fmt.Sprintf("%T", pub)
+	}
+}
+
+func describeValidity(validity time.Duration) string {
+	if validity%(24*time.Hour) != 0 {
+		return validity.String()
+	}
+	return fmt.Sprintf("%d days", validity/(24*time.Hour))
+}
+
+func describePathLen(cert *x509.Certificate) string {
+	if cert.MaxPathLen < 0 || (cert.MaxPathLen == 0 && !cert.MaxPathLenZero) {
+		return "unlimited"
+	}
+	return fmt.Sprint(cert.MaxPathLen)
+}
+
+// keyUsageOrder lists the key usage bits in the order of RFC 5280
+var keyUsageOrder = []struct {
+	usage x509.KeyUsage
+	name  string
+}{
+	{x509.KeyUsageDigitalSignature, "digitalSignature"},
+	{x509.KeyUsageContentCommitment, "contentCommitment"},
+	{x509.KeyUsageKeyEncipherment, "keyEncipherment"},
+	{x509.KeyUsageDataEncipherment, "dataEncipherment"},
+	{x509.KeyUsageKeyAgreement, "keyAgreement"},
+	{x509.KeyUsageCertSign, "keyCertSign"},
+	{x509.KeyUsageCRLSign, "cRLSign"},
+	{x509.KeyUsageEncipherOnly, "encipherOnly"},
+	{x509.KeyUsageDecipherOnly, "decipherOnly"},
+}
+
+func describeKeyUsage(keyUsage x509.KeyUsage) string {
+	var names []string
+	for _, usage := range keyUsageOrder {
+		if keyUsage&usage.usage != 0 {
+			names = append(names, usage.name)
+		}
+	}
+	return describeList(names)
+}
+
+// extKeyUsageOrder names the extended key usages the templates set
+var extKeyUsageOrder = []struct {
+	usage x509.ExtKeyUsage
+	name  string
+}{
+	{x509.ExtKeyUsageAny, "any"},
+	{x509.ExtKeyUsageServerAuth, "serverAuth"},
+	{x509.ExtKeyUsageClientAuth, "clientAuth"},
+	{x509.ExtKeyUsageCodeSigning, "codeSigning"},
+	{x509.ExtKeyUsageEmailProtection, "emailProtection"},
+	{x509.ExtKeyUsageTimeStamping, "timeStamping"},
+	{x509.ExtKeyUsageOCSPSigning, "ocspSigning"},
+}
+
+func describeExtKeyUsage(usages []x509.ExtKeyUsage) string {
+	names := make([]string, 0, len(usages))
+	for _, usage := range usages {
+		name := fmt.Sprintf("unknown(%d)", usage)
+		for _, known := range extKeyUsageOrder {
+			if known.usage == usage {
+				name = known.name
+			}
+		}
+		names = append(names, name)
+	}
+	return describeList(names)
+}
+
+func describePolicies(cert *x509.Certificate) string {
+	oids := make([]string, 0, len(cert.PolicyIdentifiers))
+	for _, oid := range cert.PolicyIdentifiers {
+		oids = append(oids, oid.String())
+	}
+	return describeList(oids)
+}
+
+// describeList joins values, or reports that there are none
+func describeList(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	return strings.Join(values, ", ")
+}
+
+// compareGolden compares got with the golden file at path, or rewrites the
+// file with -update
+func compareGolden(t *testing.T, path, got string) {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file (run with -update to create it): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs from the golden file:\n--- got\n%s--- want\n%s", path, got, want)
+	}
+}
@@ -0,0 +1,158 @@
+package cert
+
+import (
+	"crypto/x509"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// caNode tracks a generated CA of a hierarchy
+type caNode struct {
+	cert       string // Path to the certificate
+	key        string // Path to the private key
+	passphrase string // Resolved key passphrase, empty if the key is not encrypted
+	chain      []*x509.Certificate
+}
+
+// GenerateHierarchy generates a root CA, its intermediates and the leaf
+// certificates of a HierarchyConfig in dependency order. Each certificate is
+// written to its own subdirectory, and each leaf's fullchain.crt holds the
+// complete chain up to the root. If generation fails partway the error lists
+// the directories that were already written.
+func GenerateHierarchy(config *HierarchyConfig) error {
+	if err := config.Validate(); err != nil {
+		return withKind(ErrValidation, fmt.Errorf("invalid hierarchy configuration: %w", err))
+	}
+
+	var created []string
+	fail := func(err error) error {
+		if len(created) == 0 {
+			return err
+		}
+		return fmt.Errorf("%w (already created: %s)", err, strings.Join(created, ", "))
+	}
+
+	// Generate the root CA
+	cas := map[string]*caNode{}
+	root := config.Root
+	root.Type = Root
+	root.NoProgress = config.NoProgress
+	root.ProgressOutput = config.ProgressOutput
+	root.Quiet = config.Quiet
+	root.Force = config.Force
+	if root.OutputDir == "" {
+		root.OutputDir = filepath.Join(config.OutputDir, "root")
+	}
+	result, err := GenerateCA(&root)
+	if err != nil {
+		return fail(fmt.Errorf("root: %w", err))
+	}
+	created = append(created, root.OutputDir)
+	cas["root"] = &caNode{
+		cert:       caCertPath(&root),
+		key:        caKeyPath(&root),
+		passphrase: caKeyPassphrase(&root),
+		chain:      []*x509.Certificate{result.Certificate},
+	}
+
+	// Generate intermediates once their parent exists
+	pending := config.Intermediates
+	for len(pending) > 0 {
+		var next []IntermediateSpec
+		for _, spec := range pending {
+			parent, ok := cas[spec.Parent]
+			if !ok {
+				next = append(next, spec)
+				continue
+			}
+
+			ca := spec.CAConfig
+			ca.Type = Intermediate
+			ca.NoProgress = config.NoProgress
+			ca.ProgressOutput = config.ProgressOutput
+			ca.Quiet = config.Quiet
+			ca.Force = config.Force
+			if ca.OutputDir == "" {
+				ca.OutputDir = filepath.Join(config.OutputDir, spec.Name)
+			}
+			ca.ParentCert = parent.cert
+			ca.ParentKey = parent.key
+			ca.ParentPassphrase = parent.passphrase
+			result, err := GenerateCA(&ca)
+			if err != nil {
+				return fail(fmt.Errorf("intermediate %q: %w", spec.Name, err))
+			}
+			created = append(created, ca.OutputDir)
+			cas[spec.Name] = &caNode{
+				cert:       caCertPath(&ca),
+				key:        caKeyPath(&ca),
+				passphrase: caKeyPassphrase(&ca),
+				chain:      append([]*x509.Certificate{result.Certificate}, parent.chain...),
+			}
+		}
+		if len(next) == len(pending) {
+			return fail(fmt.Errorf("intermediate %q: parent %q is never generated (cycle)", next[0].Name, next[0].Parent))
+		}
+		pending = next
+	}
+
+	// Generate the leaves
+	for _, spec := range config.Leaves {
+		issuer := cas[spec.Issuer]
+
+		leaf := spec.CertConfig
+		leaf.NoProgress = config.NoProgress
+		leaf.ProgressOutput = config.ProgressOutput
+		leaf.Quiet = config.Quiet
+		leaf.Force = config.Force
+		if leaf.OutputDir == "" {
+			leaf.OutputDir = filepath.Join(config.OutputDir, spec.Name)
+		}
+		leaf.CACert = issuer.cert
+		leaf.CAKey = issuer.key
+		leaf.CAPassphrase = issuer.passphrase
+		result, err := GenerateCertificate(&leaf)
+		if err != nil {
+			return fail(fmt.Errorf("leaf %q: %w", spec.Name, err))
+		}
+		created = append(created, leaf.OutputDir)
+
+		// Extend the full chain from the direct issuer up to the root, which
+		// is only included with fullChainRoot
+		if *leaf.WriteFullChain {
+			chainPath := filepath.Join(leaf.OutputDir, leaf.fullChainFileName())
+			if err := writeChain(chainPath, result.Certificate.Raw, fullChain(issuer.chain, leaf.FullChainRoot), true); err != nil {
+				return fail(fmt.Errorf("leaf %q: writing full chain: %w", spec.Name, err))
+			}
+		}
+	}
+
+	return nil
+}
+
+// caCertPath returns the path of a generated CA's certificate
+func caCertPath(ca *CAConfig) string {
+	return filepath.Join(ca.OutputDir, ca.FileName+".crt")
+}
+
+// caKeyPath returns the path of a generated CA's private key, which is the
+// configured keyFile if the CA reused an existing key
+func caKeyPath(ca *CAConfig) string {
+	if ca.KeyFile != "" {
+		return ca.KeyFile
+	}
+	return filepath.Join(ca.OutputDir, ca.FileName+".key")
+}
+
+// caKeyPassphrase returns the passphrase protecting a generated CA's private
+// key, or an empty string if the key is not encrypted
+func caKeyPassphrase(ca *CAConfig) string {
+	if ca.KeyFile != "" {
+		return ca.KeyPassphrase
+	}
+	if !ca.EncryptKey {
+		return ""
+	}
+	return ca.Passphrase
+}
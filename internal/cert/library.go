@@ -0,0 +1,254 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+
+	"github.com/youmark/pkcs8"
+)
+
+// Generator issues certificates in memory. Unlike GenerateCA,
+// GenerateCertificate and SignCertificate it never writes files and only
+// reports progress to the configured writer, so it rejects sequential
+// serials, whose counter is kept in a file.
+type Generator struct {
+	rand     io.Reader
+	progress io.Writer
+}
+
+// Option configures a Generator
+type Option func(*Generator)
+
+// WithRand sets the randomness source used for keys, serial numbers and
+// signatures, crypto/rand by default. A deterministic source only makes the
+// output of Ed25519 keys reproducible, including their serial numbers and
+// subject key identifiers. Go mixes randomness of its own into RSA and ECDSA
+// key generation, which also shifts the serial numbers drawn after it.
+func WithRand(r io.Reader) Option {
+	return func(g *Generator) {
+		g.rand = r
+	}
+}
+
+// WithProgress sets the writer progress messages and warnings are written
+// to. Progress is not reported by default.
+func WithProgress(w io.Writer) Option {
+	return func(g *Generator) {
+		g.progress = w
+	}
+}
+
+// NewGenerator creates a Generator with the given options
+func NewGenerator(opts ...Option) *Generator {
+	g := &Generator{rand: rand.Reader, progress: io.Discard}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// CA generates a CA certificate and key. A nil issuer creates a root CA, or
+// an intermediate signed by the parent CA files of the configuration; a
+// non-nil issuer creates an intermediate signed by it.
+func (g *Generator) CA(config *CAConfig, issuer *Result) (*Result, error) {
+	if issuer != nil {
+		config.Type = Intermediate
+	}
+	if err := checkInMemorySerial(config.SerialMode); err != nil {
+		return nil, withKind(ErrValidation, fmt.Errorf("invalid CA configuration: %w", err))
+	}
+	if err := config.validate(issuer == nil); err != nil {
+		return nil, withKind(ErrValidation, fmt.Errorf("invalid CA configuration: %w", err))
+	}
+
+	progress := NewGenerationProgress("CA Certificate", progressOutput(g.progress, config.Quiet), !config.NoProgress)
+	defer progress.Complete()
+	return g.ca(config, issuer, progress)
+}
+
+// Leaf generates an end-entity certificate and key signed by issuer, or by
+// the CA files of the configuration if issuer is nil
+func (g *Generator) Leaf(config *CertConfig, issuer *Result) (*Result, error) {
+	if err := checkInMemorySerial(config.SerialMode); err != nil {
+		return nil, withKind(ErrValidation, fmt.Errorf("invalid certificate configuration: %w", err))
+	}
+	if err := config.validate(issuer == nil); err != nil {
+		return nil, withKind(ErrValidation, fmt.Errorf("invalid certificate configuration: %w", err))
+	}
+
+	progress := NewGenerationProgress("Certificate", progressOutput(g.progress, config.Quiet), !config.NoProgress)
+	defer progress.Complete()
+	return g.leaf(config, issuer, progress)
+}
+
+// Sign re-issues an existing certificate or certificate request under
+// issuer, or under the CA files of the configuration if issuer is nil. The
+// result only holds a private key if the configuration names one.
+func (g *Generator) Sign(config *SignConfig, issuer *Result) (*Result, error) {
+	if err := checkInMemorySerial(config.SerialMode); err != nil {
+		return nil, withKind(ErrValidation, fmt.Errorf("invalid signing configuration: %w", err))
+	}
+	if err := config.validate(issuer == nil); err != nil {
+		return nil, withKind(ErrValidation, fmt.Errorf("invalid signing configuration: %w", err))
+	}
+
+	progress := NewGenerationProgress("Certificate Signing", progressOutput(g.progress, config.Quiet), !config.NoProgress)
+	defer progress.Complete()
+	return g.sign(config, issuer, progress)
+}
+
+// checkInMemorySerial rejects serial modes the Generator cannot honour
+// without writing files
+func checkInMemorySerial(mode SerialMode) error {
+	if mode == SerialSequential {
+		return fmt.Errorf("serialMode sequential keeps its counter in a file next to the CA and is not supported by the Generator; use random serials or the file-based functions")
+	}
+	return nil
+}
+
+// CABundle is an issuing CA whose certificate and key were parsed once. It
+// can be shared by any number of issuances, including concurrent ones, since
+// signing never modifies it.
+type CABundle struct {
+	Certificate *x509.Certificate
+	PrivateKey  crypto.Signer
+	Chain       []*x509.Certificate // CAs above it, starting with its issuer
+	KeyPath     string              // Path of the CA key, next to which sequential serials are kept
+	CertPath    string              // Path of the CA certificate, next to which the serials of a signer key are kept
+}
+
+// LoadCABundle loads a CA certificate and private key from files. The
+// passphrase is only used when the key is encrypted.
+func LoadCABundle(certPath, keyPath string, passphrase []byte) (*CABundle, error) {
+	caCert, caKey, err := loadCA(certPath, keyPath, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return &CABundle{Certificate: caCert, PrivateKey: caKey, KeyPath: keyPath, CertPath: certPath}, nil
+}
+
+// NewCABundle creates a CA from a certificate and a signer holding its key,
+// such as a key in an HSM that cannot be exported. Sequential serials need
+// CertPath to be set, as they are kept next to the CA certificate when the
+// key is not a file.
+func NewCABundle(caCert *x509.Certificate, signer crypto.Signer) (*CABundle, error) {
+	if caCert == nil || signer == nil {
+		return nil, fmt.Errorf("CA bundle requires a certificate and a signer")
+	}
+	if !publicKeysEqual(signer.Public(), caCert.PublicKey) {
+		return nil, withKind(ErrCALoad, withKind(ErrCAMismatch, fmt.Errorf("signer does not match CA certificate %q", caCert.Subject.CommonName)))
+	}
+	return &CABundle{Certificate: caCert, PrivateKey: signer}, nil
+}
+
+// Issuer returns the CA as an issuer for the Generator methods
+func (b *CABundle) Issuer() *Result {
+	return &Result{Certificate: b.Certificate, PrivateKey: b.PrivateKey, Chain: b.Chain}
+}
+
+// GenerateCAPEM generates a CA certificate and key like GenerateCA, but
+// returns them PEM encoded instead of writing any files. The key is
+// encrypted if the configuration sets encryptKey, and not returned if it
+// uses an existing keyFile.
+func GenerateCAPEM(config *CAConfig) (certPEM, keyPEM []byte, err error) {
+	result, err := pemGenerator(config.ProgressOutput).CA(config, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encodeResult(result, config.KeyFile == "", config.EncryptKey, config.Passphrase, config.KeyFormat)
+}
+
+// GenerateCertificatePEM generates a certificate and key like
+// GenerateCertificate, but returns them PEM encoded instead of writing any
+// files. The key is encrypted if the configuration sets encryptKey, and not
+// returned if it uses an existing keyFile.
+func GenerateCertificatePEM(config *CertConfig) (certPEM, keyPEM []byte, err error) {
+	result, err := pemGenerator(config.ProgressOutput).Leaf(config, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encodeResult(result, config.KeyFile == "", config.EncryptKey, config.Passphrase, config.KeyFormat)
+}
+
+// SignCertificatePEM signs a certificate like SignCertificate, but returns it
+// PEM encoded instead of writing it
+func SignCertificatePEM(config *SignConfig) ([]byte, error) {
+	result, err := pemGenerator(config.ProgressOutput).Sign(config, nil)
+	if err != nil {
+		return nil, err
+	}
+	if result.Certificate.Raw == nil {
+		return nil, fmt.Errorf("a dry run does not issue a certificate")
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: result.Certificate.Raw}), nil
+}
+
+// pemGenerator returns a Generator for the PEM functions, reporting progress
+// only if the configuration sets an output
+func pemGenerator(out io.Writer) *Generator {
+	if out == nil {
+		return NewGenerator()
+	}
+	return NewGenerator(WithProgress(out))
+}
+
+// encodeResult PEM encodes a generated certificate and its private key, the
+// same way they are written to the .crt and .key files
+func encodeResult(result *Result, saveKey, encryptKey bool, passphrase string, keyFormat KeyFormat) ([]byte, []byte, error) {
+	if result.Certificate.Raw == nil {
+		return nil, nil, fmt.Errorf("a dry run does not issue a certificate")
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: result.Certificate.Raw})
+	if !saveKey {
+		return certPEM, nil, nil
+	}
+	var keyPassphrase []byte
+	if encryptKey {
+		keyPassphrase = []byte(passphrase)
+	}
+	keyPEM, err := encodePrivateKey(result.PrivateKey, keyPassphrase, keyFormat)
+	if err != nil {
+		return nil, nil, err
+	}
+	return certPEM, keyPEM, nil
+}
+
+// issuerOf returns the certificate and key of an in-memory issuer together
+// with the chain of certificates issued by it
+func issuerOf(issuer *Result) (*x509.Certificate, []*x509.Certificate, error) {
+	if issuer.Certificate == nil || issuer.PrivateKey == nil {
+		return nil, nil, fmt.Errorf("issuer has no certificate or private key")
+	}
+	if !publicKeysEqual(issuer.PrivateKey.Public(), issuer.Certificate.PublicKey) {
+		return nil, nil, fmt.Errorf("issuer key does not match issuer certificate")
+	}
+	return issuer.Certificate, append([]*x509.Certificate{issuer.Certificate}, issuer.Chain...), nil
+}
+
+// WritePEM writes the certificate followed by its issuing chain to certOut
+// and, if keyOut is not nil, the private key as unencrypted PKCS#8 to keyOut
+func (r *Result) WritePEM(certOut, keyOut io.Writer) error {
+	for _, cert := range append([]*x509.Certificate{r.Certificate}, r.Chain...) {
+		if err := pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}); err != nil {
+			return fmt.Errorf("encoding certificate: %w", err)
+		}
+	}
+	if keyOut == nil {
+		return nil
+	}
+	if r.PrivateKey == nil {
+		return fmt.Errorf("result has no private key")
+	}
+	keyDER, err := pkcs8.MarshalPrivateKey(r.PrivateKey, nil, nil)
+	if err != nil {
+		return fmt.Errorf("marshaling private key: %w", err)
+	}
+	if err := pem.Encode(keyOut, &pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}); err != nil {
+		return fmt.Errorf("encoding private key: %w", err)
+	}
+	return nil
+}
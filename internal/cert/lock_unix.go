@@ -0,0 +1,18 @@
+//go:build !windows
+
+package cert
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive lock on file, waiting for other holders
+func lockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+}
+
+// unlockFile releases a lock taken by lockFile
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
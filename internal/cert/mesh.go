@@ -0,0 +1,98 @@
+package cert
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+)
+
+// GenerateMesh issues the certificates of a MeshConfig, each merged over the
+// shared settings, and ends with a status table of all of them. Like
+// GenerateBatch, every certificate is attempted even if others fail.
+func GenerateMesh(config *MeshConfig) ([]BatchResult, error) {
+	progress := NewGenerationProgress("Service Mesh Certificates", progressOutput(config.ProgressOutput, config.Quiet), !config.NoProgress)
+	defer progress.Complete()
+
+	batch := config.batch()
+	if err := batch.Validate(); err != nil {
+		return nil, withKind(ErrValidation, fmt.Errorf("invalid mesh configuration: %w", err))
+	}
+	results, output, err := issueBatch(batch, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	var table bytes.Buffer
+	w := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTATUS\tSERIAL\tEXPIRES\tOUTPUT")
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			fmt.Fprintf(w, "%s\tfailed\t-\t-\t%s\n", result.Name, result.OutputDir)
+			continue
+		}
+		issued := result.Result.Certificate
+		fmt.Fprintf(w, "%s\tissued\t%X\t%s\t%s\n", result.Name, issued.SerialNumber, issued.NotAfter.Format("2006-01-02"), result.OutputDir)
+	}
+	w.Flush()
+	for _, line := range strings.Split(strings.TrimSuffix(table.String(), "\n"), "\n") {
+		progress.Summary(line)
+	}
+
+	// Errors, warnings and summaries follow the table, in configuration order
+	for i, result := range results {
+		if result.Err != nil {
+			progress.Summary(fmt.Sprintf("✗ %s: %v", result.Name, result.Err))
+		} else if output[i].Len() > 0 {
+			progress.Summary(result.Name + ":")
+		}
+		summarizeOutput(progress, &output[i])
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("%d of %d certificates failed", failed, len(results))
+	}
+	return results, nil
+}
+
+// batch converts a MeshConfig to the BatchConfig that issues its
+// certificates, with the shared settings merged into every entry
+func (c *MeshConfig) batch() *BatchConfig {
+	batch := &BatchConfig{
+		CACert:           c.CA.CACert,
+		CAKey:            c.CA.CAKey,
+		CAPassphrase:     c.CA.CAPassphrase,
+		CAPassphraseEnv:  c.CA.CAPassphraseEnv,
+		CAPassphraseFile: c.CA.CAPassphraseFile,
+		OutputDir:        c.OutputDir,
+		Workers:          c.Workers,
+		NoProgress:       c.NoProgress,
+		Quiet:            c.Quiet,
+		Force:            c.Force,
+		ProgressOutput:   c.ProgressOutput,
+		Certificates:     make([]BatchSpec, len(c.Certificates)),
+	}
+	// A shared output directory would put every certificate in the same place
+	shared := c.CA
+	shared.OutputDir = ""
+	for i, spec := range c.Certificates {
+		batch.Certificates[i] = BatchSpec{Name: spec.Name, CertConfig: mergeCertConfig(shared, spec.CertConfig)}
+	}
+	return batch
+}
+
+// mergeCertConfig returns shared with every setting of entry that is not
+// empty, zero or false applied on top. Lists replace the shared list.
+func mergeCertConfig(shared, entry CertConfig) CertConfig {
+	merged := shared
+	dst := reflect.ValueOf(&merged).Elem()
+	src := reflect.ValueOf(entry)
+	for i := range src.NumField() {
+		if field := src.Field(i); !field.IsZero() {
+			dst.Field(i).Set(field)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,270 @@
+package cert
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// maxOCSPRequestSize limits the size of POSTed OCSP requests
+const maxOCSPRequestSize = 10 << 10
+
+// OCSPResponder answers OCSP requests for a CA over HTTP, looking up the
+// status of certificates in the revocation store maintained by
+// RevokeCertificate. The store is read on every request, so revocations
+// take effect without restarting the responder.
+type OCSPResponder struct {
+	caCert     *x509.Certificate
+	signerCert *x509.Certificate // Delegated signer, or the CA certificate
+	signer     crypto.Signer
+	storePath  string
+	validity   time.Duration
+	log        io.Writer
+}
+
+// NewOCSPResponder loads the CA and signing key of the configuration and
+// returns a responder that implements http.Handler
+func NewOCSPResponder(config *OCSPConfig) (*OCSPResponder, error) {
+	if err := config.Validate(); err != nil {
+		return nil, withKind(ErrValidation, fmt.Errorf("invalid OCSP configuration: %w", err))
+	}
+
+	r := &OCSPResponder{
+		storePath: config.StorePath,
+		validity:  time.Duration(config.ResponseValidityHours) * time.Hour,
+		log:       io.Discard,
+	}
+	if config.SignerCertPath == "" {
+		caCert, caKey, err := loadCA(config.CACertPath, config.CAKeyPath, []byte(config.CAPassphrase))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load CA: %w", err)
+		}
+		r.caCert, r.signerCert, r.signer = caCert, caCert, caKey
+	} else {
+		certs, err := readCertificates(config.CACertPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load CA certificate: %w", err)
+		}
+		r.caCert = certs[0]
+		if r.signerCert, r.signer, err = loadOCSPSigner(config, r.caCert); err != nil {
+			return nil, err
+		}
+	}
+	if len(r.caCert.SubjectKeyId) == 0 {
+		return nil, fmt.Errorf("CA certificate has no SubjectKeyId")
+	}
+	switch r.signer.Public().(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+	default:
+		keyType, _, _ := keyParams(r.signer.Public())
+		return nil, fmt.Errorf("OCSP responses can only be signed with RSA or ECDSA keys, not %s", keyType)
+	}
+	return r, nil
+}
+
+// loadOCSPSigner loads a delegated OCSP signing certificate and key and
+// checks that the CA issued it for OCSP signing
+func loadOCSPSigner(config *OCSPConfig, caCert *x509.Certificate) (*x509.Certificate, crypto.Signer, error) {
+	certs, err := readCertificates(config.SignerCertPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load signer certificate: %w", err)
+	}
+	signerCert := certs[0]
+	if err := signerCert.CheckSignatureFrom(caCert); err != nil {
+		return nil, nil, fmt.Errorf("signer certificate was not issued by this CA: %w", err)
+	}
+	if !slices.Contains(signerCert.ExtKeyUsage, x509.ExtKeyUsageOCSPSigning) {
+		return nil, nil, fmt.Errorf("signer certificate lacks the OCSP signing extended key usage")
+	}
+	signerKey, err := loadPrivateKey(config.SignerKeyPath, []byte(config.KeyPassphrase))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load signer private key: %w", err)
+	}
+	if !publicKeysEqual(signerKey.Public(), signerCert.PublicKey) {
+		return nil, nil, fmt.Errorf("signer key %s does not match signer certificate %s", config.SignerKeyPath, config.SignerCertPath)
+	}
+	return signerCert, signerKey, nil
+}
+
+// ServeOCSP answers OCSP requests on the configured address until the
+// server fails
+func ServeOCSP(config *OCSPConfig) error {
+	progress := NewGenerationProgress("OCSP Responder", progressOutput(config.ProgressOutput, config.Quiet), !config.NoProgress)
+
+	progress.StartCALoading()
+	responder, err := NewOCSPResponder(config)
+	if err != nil {
+		return err
+	}
+	progress.CompleteCALoading()
+	if !config.NoProgress {
+		responder.log = progress.out
+	}
+
+	progress.StartProgress(fmt.Sprintf("Serving OCSP for %s on %s", responder.caCert.Subject, config.Listen))
+	server := &http.Server{
+		Addr:              config.Listen,
+		Handler:           responder,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+	if err := server.ListenAndServe(); err != nil {
+		return fmt.Errorf("OCSP responder failed: %w", err)
+	}
+	return nil
+}
+
+// ServeHTTP answers a single OCSP request sent by GET, base64 encoded in the
+// path, or by POST as described in RFC 6960 appendix A
+func (r *OCSPResponder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var der []byte
+	switch req.Method {
+	case http.MethodGet:
+		encoded := strings.TrimPrefix(req.URL.Path, "/")
+		// Some clients drop the base64 padding or use the URL-safe alphabet
+		encoded = strings.NewReplacer("-", "+", "_", "/").Replace(strings.TrimRight(encoded, "="))
+		var err error
+		if der, err = base64.RawStdEncoding.DecodeString(encoded); err != nil {
+			r.writeResponse(w, req, ocsp.MalformedRequestErrorResponse, nil)
+			return
+		}
+	case http.MethodPost:
+		if ct := req.Header.Get("Content-Type"); ct != "application/ocsp-request" {
+			http.Error(w, "unsupported content type "+ct, http.StatusUnsupportedMediaType)
+			return
+		}
+		var err error
+		if der, err = io.ReadAll(io.LimitReader(req.Body, maxOCSPRequestSize+1)); err != nil || len(der) > maxOCSPRequestSize {
+			r.writeResponse(w, req, ocsp.MalformedRequestErrorResponse, nil)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ocspReq, err := ocsp.ParseRequest(der)
+	if err != nil {
+		fmt.Fprintf(r.log, "Malformed request from %s: %v\n", req.RemoteAddr, err)
+		r.writeResponse(w, req, ocsp.MalformedRequestErrorResponse, nil)
+		return
+	}
+	if !r.issuedByCA(ocspReq) {
+		fmt.Fprintf(r.log, "Serial %X: not issued by this CA\n", ocspReq.SerialNumber)
+		r.writeResponse(w, req, ocsp.UnauthorizedErrorResponse, nil)
+		return
+	}
+
+	template, err := r.status(ocspReq.SerialNumber)
+	if err != nil {
+		fmt.Fprintf(r.log, "Serial %X: %v\n", ocspReq.SerialNumber, err)
+		r.writeResponse(w, req, ocsp.InternalErrorErrorResponse, nil)
+		return
+	}
+	template.IssuerHash = ocspReq.HashAlgorithm
+	if r.signerCert != r.caCert {
+		template.Certificate = r.signerCert
+	}
+	resp, err := ocsp.CreateResponse(r.caCert, r.signerCert, template, r.signer)
+	if err != nil {
+		fmt.Fprintf(r.log, "Serial %X: failed to sign response: %v\n", ocspReq.SerialNumber, err)
+		r.writeResponse(w, req, ocsp.InternalErrorErrorResponse, nil)
+		return
+	}
+	fmt.Fprintf(r.log, "Serial %X: %s\n", ocspReq.SerialNumber, ocspStatusNames[template.Status])
+	r.writeResponse(w, req, resp, &template)
+}
+
+// ocspStatusNames names OCSP certificate statuses for the request log
+var ocspStatusNames = map[int]string{
+	ocsp.Good:    "good",
+	ocsp.Revoked: "revoked",
+	ocsp.Unknown: "unknown",
+}
+
+// issuedByCA reports whether an OCSP request names this responder's CA by
+// the hashes of its subject and public key
+func (r *OCSPResponder) issuedByCA(req *ocsp.Request) bool {
+	if !req.HashAlgorithm.Available() {
+		return false
+	}
+	var publicKeyInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(r.caCert.RawSubjectPublicKeyInfo, &publicKeyInfo); err != nil {
+		return false
+	}
+	h := req.HashAlgorithm.New()
+	h.Write(publicKeyInfo.PublicKey.RightAlign())
+	if !bytes.Equal(h.Sum(nil), req.IssuerKeyHash) {
+		return false
+	}
+	h.Reset()
+	h.Write(r.caCert.RawSubject)
+	return bytes.Equal(h.Sum(nil), req.IssuerNameHash)
+}
+
+// status looks up a serial number in the revocation store. Serials that were
+// not revoked are reported as good.
+func (r *OCSPResponder) status(serial *big.Int) (ocsp.Response, error) {
+	now := time.Now()
+	template := ocsp.Response{
+		Status:       ocsp.Good,
+		SerialNumber: serial,
+		ThisUpdate:   now,
+		NextUpdate:   now.Add(r.validity),
+	}
+
+	store, err := loadRevocationStore(r.storePath)
+	if err != nil {
+		return template, err
+	}
+	revoked := store[hex.EncodeToString(r.caCert.SubjectKeyId)]
+	if revoked == nil {
+		return template, nil
+	}
+	for _, entry := range revoked.Revoked {
+		number, ok := new(big.Int).SetString(entry.Serial, 16)
+		if !ok {
+			return template, fmt.Errorf("invalid serial %q in %s", entry.Serial, r.storePath)
+		}
+		if number.Cmp(serial) == 0 {
+			template.Status = ocsp.Revoked
+			template.RevokedAt = entry.RevokedAt
+			template.RevocationReason = revocationReasons[entry.Reason]
+			break
+		}
+	}
+	return template, nil
+}
+
+// writeResponse sends an OCSP response. Successful responses to GET requests
+// may be cached by HTTP proxies until their next update.
+func (r *OCSPResponder) writeResponse(w http.ResponseWriter, req *http.Request, resp []byte, template *ocsp.Response) {
+	w.Header().Set("Content-Type", "application/ocsp-response")
+	if template != nil && req.Method == http.MethodGet {
+		maxAge := int(time.Until(template.NextUpdate).Seconds())
+		w.Header().Set("Last-Modified", template.ThisUpdate.UTC().Format(http.TimeFormat))
+		w.Header().Set("Expires", template.NextUpdate.UTC().Format(http.TimeFormat))
+		w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d, public, no-transform, must-revalidate", maxAge))
+	}
+	w.Write(resp)
+}
@@ -1,100 +1,142 @@
 package cert
 
 import (
+	"crypto/x509"
 	"fmt"
+	"io"
+	"os"
 	"sync"
 	"time"
-
-	"github.com/schollz/progressbar/v3"
 )
 
-// ProgressTracker handles progress reporting during certificate generation
-type ProgressTracker struct {
-	bar     *progressbar.ProgressBar
-	current int
-	total   int
-}
-
-// NewProgressTracker creates a new progress tracker
-func NewProgressTracker(description string) *ProgressTracker {
-	total := 100
-	bar := progressbar.NewOptions(total,
-		progressbar.OptionEnableColorCodes(true),
-		progressbar.OptionShowBytes(false),
-		progressbar.OptionSetWidth(40),
-		progressbar.OptionSetDescription(fmt.Sprintf("[cyan]%-30s[reset]", description)),
-		progressbar.OptionSetTheme(progressbar.Theme{
-			Saucer:        "[green]=[reset]",
-			SaucerHead:    "[green]>[reset]",
-			SaucerPadding: " ",
-			BarStart:      "[",
-			BarEnd:        "]",
-		}),
-		progressbar.OptionShowCount(),
-	)
-	return &ProgressTracker{
-		bar:     bar,
-		current: 0,
-		total:   total,
-	}
-}
-
-// Step advances the progress bar by the specified percentage
-func (p *ProgressTracker) Step(percent int) {
-	p.current += percent
-	if p.current > p.total {
-		p.current = p.total
-	}
-	p.bar.Set(p.current)
-}
-
-// Complete marks the progress as complete
-func (p *ProgressTracker) Complete() {
-	p.bar.Finish()
-	fmt.Println() // Add newline after progress bar
-}
-
 // GenerationProgress tracks the progress of certificate generation
 type GenerationProgress struct {
 	operation string
 	enabled   bool
+	out       io.Writer
 	startTime time.Time
+	summary   []string
 	mu        sync.Mutex
+
+	keyGenStart time.Time     // When the current key generation started
+	keyGenStop  chan struct{} // Closed to stop the elapsed time display
+	keyGenDone  chan struct{} // Closed once the elapsed time display stopped
 }
 
-// NewGenerationProgress creates a new progress tracker
-func NewGenerationProgress(operation string, enabled bool) *GenerationProgress {
+// NewGenerationProgress creates a new progress tracker writing to out, or to
+// stdout if out is nil. Use io.Discard to drop progress and warnings.
+func NewGenerationProgress(operation string, out io.Writer, enabled bool) *GenerationProgress {
+	if out == nil {
+		out = os.Stdout
+	}
 	return &GenerationProgress{
 		operation: operation,
 		enabled:   enabled,
+		out:       out,
 		startTime: time.Now(),
 	}
 }
 
-// StartKeyGen indicates the start of key generation
+// progressOutput returns the writer progress is reported to: out, or
+// nothing at all in quiet mode
+func progressOutput(out io.Writer, quiet bool) io.Writer {
+	if quiet {
+		return io.Discard
+	}
+	return out
+}
+
+// resultOutput returns the writer of commands that print their result
+// rather than progress: out, stdout if out is nil, or nothing in quiet mode
+func resultOutput(out io.Writer, quiet bool) io.Writer {
+	if out == nil {
+		out = os.Stdout
+	}
+	return progressOutput(out, quiet)
+}
+
+// StartKeyGen indicates the start of key generation. On a terminal the time
+// spent so far is shown until CompleteKeyGen, as large RSA keys can take a
+// while.
 func (p *GenerationProgress) StartKeyGen() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	if p.enabled {
-		fmt.Printf("Generating private key for %s...\n", p.operation)
+	p.keyGenStart = time.Now()
+	if !p.enabled {
+		return
+	}
+	fmt.Fprintf(p.out, "Generating private key for %s...\n", p.operation)
+	if isTerminal(p.out) {
+		p.keyGenStop = make(chan struct{})
+		p.keyGenDone = make(chan struct{})
+		go p.showElapsed(p.keyGenStart, p.keyGenStop, p.keyGenDone)
+	}
+}
+
+// showElapsed rewrites a line with the time since start every second until
+// stop is closed, then clears it
+func (p *GenerationProgress) showElapsed(start time.Time, stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	shown := false
+	for {
+		select {
+		case <-stop:
+			if shown {
+				p.mu.Lock()
+				fmt.Fprint(p.out, "\r\033[K")
+				p.mu.Unlock()
+			}
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			fmt.Fprintf(p.out, "\r  %ds elapsed", int(time.Since(start).Seconds()))
+			p.mu.Unlock()
+			shown = true
+		}
+	}
+}
+
+// stopElapsed stops the elapsed time display of StartKeyGen, if any. Call it
+// when key generation fails.
+func (p *GenerationProgress) stopElapsed() {
+	p.mu.Lock()
+	stop, done := p.keyGenStop, p.keyGenDone
+	p.keyGenStop, p.keyGenDone = nil, nil
+	p.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
 	}
 }
 
 // CompleteKeyGen indicates the completion of key generation
 func (p *GenerationProgress) CompleteKeyGen() {
+	p.stopElapsed()
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	if p.enabled {
-		fmt.Printf("✓ Private key generated\n")
+		fmt.Fprintf(p.out, "✓ Private key generated in %s\n", time.Since(p.keyGenStart).Round(10*time.Millisecond))
 	}
 }
 
+// isTerminal reports whether out is a terminal rather than a file or pipe
+func isTerminal(out io.Writer) bool {
+	f, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
 // StartTemplate indicates the start of template creation
 func (p *GenerationProgress) StartTemplate() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	if p.enabled {
-		fmt.Printf("Creating certificate template for %s...\n", p.operation)
+		fmt.Fprintf(p.out, "Creating certificate template for %s...\n", p.operation)
 	}
 }
 
@@ -103,7 +145,7 @@ func (p *GenerationProgress) CompleteTemplate() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	if p.enabled {
-		fmt.Printf("✓ Certificate template created\n")
+		fmt.Fprintf(p.out, "✓ Certificate template created\n")
 	}
 }
 
@@ -112,7 +154,7 @@ func (p *GenerationProgress) StartSigning() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	if p.enabled {
-		fmt.Printf("Signing certificate for %s...\n", p.operation)
+		fmt.Fprintf(p.out, "Signing certificate for %s...\n", p.operation)
 	}
 }
 
@@ -121,7 +163,7 @@ func (p *GenerationProgress) CompleteSigning() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	if p.enabled {
-		fmt.Printf("✓ Certificate signed\n")
+		fmt.Fprintf(p.out, "✓ Certificate signed\n")
 	}
 }
 
@@ -130,7 +172,7 @@ func (p *GenerationProgress) StartSaving() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	if p.enabled {
-		fmt.Printf("Saving certificate and key for %s...\n", p.operation)
+		fmt.Fprintf(p.out, "Saving certificate and key for %s...\n", p.operation)
 	}
 }
 
@@ -139,7 +181,7 @@ func (p *GenerationProgress) CompleteSaving() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	if p.enabled {
-		fmt.Printf("✓ Certificate and key saved\n")
+		fmt.Fprintf(p.out, "✓ Certificate and key saved\n")
 	}
 }
 
@@ -148,7 +190,7 @@ func (p *GenerationProgress) StartLoading() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	if p.enabled {
-		fmt.Printf("Loading certificate for %s...\n", p.operation)
+		fmt.Fprintf(p.out, "Loading certificate for %s...\n", p.operation)
 	}
 }
 
@@ -157,7 +199,7 @@ func (p *GenerationProgress) CompleteLoading() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	if p.enabled {
-		fmt.Printf("✓ Certificate loaded\n")
+		fmt.Fprintf(p.out, "✓ Certificate loaded\n")
 	}
 }
 
@@ -166,7 +208,7 @@ func (p *GenerationProgress) StartKeyLoading() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	if p.enabled {
-		fmt.Printf("Loading private key for %s...\n", p.operation)
+		fmt.Fprintf(p.out, "Loading private key for %s...\n", p.operation)
 	}
 }
 
@@ -175,7 +217,7 @@ func (p *GenerationProgress) CompleteKeyLoading() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	if p.enabled {
-		fmt.Printf("✓ Private key loaded\n")
+		fmt.Fprintf(p.out, "✓ Private key loaded\n")
 	}
 }
 
@@ -184,7 +226,7 @@ func (p *GenerationProgress) StartCALoading() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	if p.enabled {
-		fmt.Printf("Loading CA certificate and key for %s...\n", p.operation)
+		fmt.Fprintf(p.out, "Loading CA certificate and key for %s...\n", p.operation)
 	}
 }
 
@@ -193,7 +235,38 @@ func (p *GenerationProgress) CompleteCALoading() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	if p.enabled {
-		fmt.Printf("✓ CA certificate and key loaded\n")
+		fmt.Fprintf(p.out, "✓ CA certificate and key loaded\n")
+	}
+}
+
+// Warning reports a problem that does not stop the operation. Warnings are
+// shown even when progress display is disabled.
+func (p *GenerationProgress) Warning(message string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.out, "⚠ Warning: %s\n", message)
+}
+
+// Summary adds a line to the summary printed by Complete. Like warnings,
+// summary lines are shown even when progress display is disabled.
+func (p *GenerationProgress) Summary(line string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.summary = append(p.summary, line)
+}
+
+// Issued adds a record of an issued certificate to the summary: the time
+// it was issued, its serial number, its subject and, if not empty, the
+// directory it was written to
+func (p *GenerationProgress) Issued(cert *x509.Certificate, outputDir string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.summary = append(p.summary,
+		"Issued:             "+time.Now().UTC().Format(time.RFC3339),
+		fmt.Sprintf("Serial Number:      %X", cert.SerialNumber),
+		"Subject:            "+cert.Subject.CommonName)
+	if outputDir != "" {
+		p.summary = append(p.summary, "Output Directory:   "+outputDir)
 	}
 }
 
@@ -203,7 +276,10 @@ func (p *GenerationProgress) Complete() {
 	defer p.mu.Unlock()
 	if p.enabled {
 		duration := time.Since(p.startTime)
-		fmt.Printf("\n%s completed in %s\n", p.operation, duration.Round(time.Millisecond))
+		fmt.Fprintf(p.out, "\n%s completed in %s\n", p.operation, duration.Round(time.Millisecond))
+	}
+	for _, line := range p.summary {
+		fmt.Fprintln(p.out, line)
 	}
 }
 
@@ -217,6 +293,6 @@ func (p *GenerationProgress) StartProgress(message string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	if p.enabled {
-		fmt.Printf("%s...\n", message)
+		fmt.Fprintf(p.out, "%s...\n", message)
 	}
 }
@@ -0,0 +1,52 @@
+package cert
+
+import (
+	"crypto/x509"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenewCertificate(t *testing.T) {
+	dir := t.TempDir()
+	caCert, caKey := writeTestCA(t, filepath.Join(dir, "ca"))
+	leafDir := filepath.Join(dir, "leaf")
+	oldCert := readTestCertificate(t, writeTestLeaf(t, caCert, caKey, leafDir))
+
+	err := RenewCertificate(&RenewConfig{
+		CertPath:           filepath.Join(leafDir, "cert.crt"),
+		KeyPath:            filepath.Join(leafDir, "cert.key"),
+		CACertPath:         caCert,
+		CAKeyPath:          caKey,
+		SignatureAlgorithm: "ECDSAWithSHA512",
+		OutputDir:          filepath.Join(dir, "renewed"),
+		Quiet:              true,
+	})
+	if err != nil {
+		t.Fatalf("RenewCertificate: %v", err)
+	}
+
+	renewed := readTestCertificate(t, filepath.Join(dir, "renewed", "renewed.crt"))
+	if renewed.SignatureAlgorithm != x509.ECDSAWithSHA512 {
+		t.Errorf("signature algorithm = %v, want %v", renewed.SignatureAlgorithm, x509.ECDSAWithSHA512)
+	}
+	if renewed.SerialNumber.Cmp(oldCert.SerialNumber) == 0 {
+		t.Errorf("renewed certificate kept serial %x", oldCert.SerialNumber)
+	}
+	if err := verifyIssued(renewed, readTestCertificate(t, caCert), x509.ExtKeyUsageServerAuth); err != nil {
+		t.Errorf("renewed certificate: %v", err)
+	}
+
+	// A signature algorithm the CA key cannot produce is refused
+	err = RenewCertificate(&RenewConfig{
+		CertPath:           filepath.Join(leafDir, "cert.crt"),
+		KeyPath:            filepath.Join(leafDir, "cert.key"),
+		CACertPath:         caCert,
+		CAKeyPath:          caKey,
+		SignatureAlgorithm: "SHA256WithRSA",
+		OutputDir:          filepath.Join(dir, "refused"),
+		Quiet:              true,
+	})
+	if err == nil {
+		t.Errorf("renewing with SHA256WithRSA under an ECDSA CA succeeded")
+	}
+}
@@ -0,0 +1,60 @@
+package cert
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Report describes an issued certificate and the files written for it, for
+// automation that needs to locate and register the output
+type Report struct {
+	Subject           string    `json:"subject"`
+	Issuer            string    `json:"issuer"`
+	Serial            string    `json:"serial"` // Upper-case hex
+	NotBefore         time.Time `json:"notBefore"`
+	NotAfter          time.Time `json:"notAfter"`
+	DNSNames          []string  `json:"dnsNames,omitempty"`
+	IssuingCertURL    []string  `json:"issuingCertificateURL,omitempty"` // Authority Information Access CA Issuers URLs
+	SHA1Fingerprint   string    `json:"sha1Fingerprint"`
+	SHA256Fingerprint string    `json:"sha256Fingerprint"`
+	Files             []string  `json:"files"`
+}
+
+// NewReport describes cert and the files written for it
+func NewReport(cert *x509.Certificate, files []string) *Report {
+	sha1Sum := sha1.Sum(cert.Raw)
+	sha256Sum := sha256.Sum256(cert.Raw)
+	if files == nil {
+		files = []string{}
+	}
+	return &Report{
+		Subject:           cert.Subject.String(),
+		Issuer:            cert.Issuer.String(),
+		Serial:            fmt.Sprintf("%X", cert.SerialNumber),
+		NotBefore:         cert.NotBefore.UTC(),
+		NotAfter:          cert.NotAfter.UTC(),
+		DNSNames:          cert.DNSNames,
+		IssuingCertURL:    cert.IssuingCertificateURL,
+		SHA1Fingerprint:   fingerprint(sha1Sum[:]),
+		SHA256Fingerprint: fingerprint(sha256Sum[:]),
+		Files:             files,
+	}
+}
+
+// writeReport writes a JSON report of cert and its files to out, if set
+func writeReport(out io.Writer, cert *x509.Certificate, files []string) error {
+	if out == nil {
+		return nil
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(NewReport(cert, files)); err != nil {
+		return fmt.Errorf("writing report: %w", err)
+	}
+	return nil
+}
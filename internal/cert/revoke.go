@@ -0,0 +1,171 @@
+package cert
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// revocationStore is the persisted list of revoked certificates, keyed by
+// the hex encoded SubjectKeyId of the issuing CA
+type revocationStore map[string]*caRevocations
+
+// caRevocations holds the revoked certificates of a single CA
+type caRevocations struct {
+	CRLNumber int64        `json:"crlNumber"`
+	Revoked   []revocation `json:"revoked"`
+}
+
+// revocation is a single revoked certificate
+type revocation struct {
+	Serial    string    `json:"serial"` // Hex serial number
+	RevokedAt time.Time `json:"revokedAt"`
+	Reason    string    `json:"reason"`
+}
+
+// RevokeCertificate records a certificate issued by the CA as revoked and
+// regenerates the CA's CRL as crl.pem. Without a certificate or serial it
+// only regenerates the CRL.
+func RevokeCertificate(config *RevokeConfig) error {
+	progress := NewGenerationProgress("Certificate Revocation", progressOutput(config.ProgressOutput, config.Quiet), !config.NoProgress)
+	defer progress.Complete()
+
+	if err := config.Validate(); err != nil {
+		return withKind(ErrValidation, fmt.Errorf("invalid revocation configuration: %w", err))
+	}
+
+	// Load CA certificate and private key
+	progress.StartCALoading()
+	caCert, caKey, err := loadCA(config.CACertPath, config.CAKeyPath, []byte(config.CAPassphrase))
+	if err != nil {
+		return fmt.Errorf("failed to load CA: %w", err)
+	}
+	if caCert.KeyUsage&x509.KeyUsageCRLSign == 0 {
+		return fmt.Errorf("CA certificate is not allowed to sign CRLs (missing cRLSign key usage)")
+	}
+	if len(caCert.SubjectKeyId) == 0 {
+		return fmt.Errorf("CA certificate has no SubjectKeyId")
+	}
+	progress.CompleteCALoading()
+
+	// Determine the serial to revoke
+	var serial *big.Int
+	if config.Serial != "" {
+		serial, _ = new(big.Int).SetString(config.Serial, 16) // Checked by Validate
+	}
+	if config.CertPath != "" {
+		progress.StartLoading()
+		certs, err := readCertificates(config.CertPath)
+		if err != nil {
+			return fmt.Errorf("failed to load certificate: %w", err)
+		}
+		if err := certs[0].CheckSignatureFrom(caCert); err != nil {
+			return fmt.Errorf("certificate was not issued by this CA: %w", err)
+		}
+		serial = certs[0].SerialNumber
+		progress.CompleteLoading()
+	}
+
+	// Record the revocation
+	store, err := loadRevocationStore(config.StorePath)
+	if err != nil {
+		return err
+	}
+	caID := hex.EncodeToString(caCert.SubjectKeyId)
+	revoked := store[caID]
+	if revoked == nil {
+		revoked = &caRevocations{}
+		store[caID] = revoked
+	}
+	if serial != nil {
+		// Compare numbers, as older stores may hold zero-padded serials
+		for _, r := range revoked.Revoked {
+			if number, ok := new(big.Int).SetString(r.Serial, 16); ok && number.Cmp(serial) == 0 {
+				return fmt.Errorf("serial %s is already revoked", serial.Text(16))
+			}
+		}
+		revoked.Revoked = append(revoked.Revoked, revocation{
+			Serial:    serial.Text(16),
+			RevokedAt: time.Now().UTC(),
+			Reason:    config.Reason,
+		})
+	}
+	revoked.CRLNumber++
+
+	// Sign the CRL
+	progress.StartProgress("Signing CRL")
+	now := time.Now()
+	template := &x509.RevocationList{
+		Number:     big.NewInt(revoked.CRLNumber),
+		ThisUpdate: now,
+		NextUpdate: now.AddDate(0, 0, config.CRLValidityDays),
+	}
+	for _, r := range revoked.Revoked {
+		number, ok := new(big.Int).SetString(r.Serial, 16)
+		if !ok {
+			return fmt.Errorf("invalid serial %q in %s", r.Serial, config.StorePath)
+		}
+		template.RevokedCertificateEntries = append(template.RevokedCertificateEntries, x509.RevocationListEntry{
+			SerialNumber:   number,
+			RevocationTime: r.RevokedAt,
+			ReasonCode:     revocationReasons[r.Reason],
+		})
+	}
+	crlDER, err := x509.CreateRevocationList(rand.Reader, template, caCert, caKey)
+	if err != nil {
+		return fmt.Errorf("failed to sign CRL: %w", err)
+	}
+
+	// Write the CRL and store
+	progress.StartProgress("Saving CRL and revocation store")
+	if err := makeOutputDir(config.OutputDir, config.DirMode, config.EnforceDirMode); err != nil {
+		return withKind(ErrIO, fmt.Errorf("failed to create output directory: %w", err))
+	}
+	if err := writePEM(filepath.Join(config.OutputDir, "crl.pem"), "X509 CRL", crlDER, true); err != nil {
+		return fmt.Errorf("failed to write CRL: %w", err)
+	}
+	if err := saveRevocationStore(config.StorePath, store, config.DirMode); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// loadRevocationStore reads the revocation store, returning an empty store
+// if the file does not exist yet
+func loadRevocationStore(path string) (revocationStore, error) {
+	store := revocationStore{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, withKind(ErrIO, fmt.Errorf("failed to read revocation store: %w", err))
+	}
+	if err := json.Unmarshal(data, &store); err != nil {
+		return nil, fmt.Errorf("failed to parse revocation store %s: %w", path, err)
+	}
+	return store, nil
+}
+
+// saveRevocationStore writes the revocation store, creating its directory
+// with the octal permissions mode
+func saveRevocationStore(path string, store revocationStore, mode string) error {
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode revocation store: %w", err)
+	}
+	if err := makeOutputDir(filepath.Dir(path), mode, false); err != nil {
+		return withKind(ErrIO, fmt.Errorf("failed to create revocation store directory: %w", err))
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return withKind(ErrIO, fmt.Errorf("failed to write revocation store: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package cert
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRevokeCertificate(t *testing.T) {
+	dir := t.TempDir()
+	caCert, caKey := writeTestCA(t, filepath.Join(dir, "ca"))
+	leaf := readTestCertificate(t, writeTestLeaf(t, caCert, caKey, filepath.Join(dir, "leaf")))
+
+	crlDir := filepath.Join(dir, "crl")
+	err := RevokeCertificate(&RevokeConfig{
+		CACertPath: caCert,
+		CAKeyPath:  caKey,
+		CertPath:   filepath.Join(dir, "leaf", "cert.crt"),
+		Reason:     "keyCompromise",
+		OutputDir:  crlDir,
+		Quiet:      true,
+	})
+	if err != nil {
+		t.Fatalf("RevokeCertificate: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(crlDir, "crl.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "X509 CRL" {
+		t.Fatalf("crl.pem does not hold an X509 CRL block")
+	}
+	crl, err := x509.ParseRevocationList(block.Bytes)
+	if err != nil {
+		t.Fatalf("ParseRevocationList: %v", err)
+	}
+	ca := readTestCertificate(t, caCert)
+	if err := crl.CheckSignatureFrom(ca); err != nil {
+		t.Errorf("CRL signature: %v", err)
+	}
+	if len(crl.RevokedCertificateEntries) != 1 {
+		t.Fatalf("CRL has %d entries, want 1", len(crl.RevokedCertificateEntries))
+	}
+	entry := crl.RevokedCertificateEntries[0]
+	if entry.SerialNumber.Cmp(leaf.SerialNumber) != 0 {
+		t.Errorf("revoked serial = %x, want %x", entry.SerialNumber, leaf.SerialNumber)
+	}
+	if entry.ReasonCode != revocationReasons["keyCompromise"] {
+		t.Errorf("reason code = %d, want %d", entry.ReasonCode, revocationReasons["keyCompromise"])
+	}
+}
+
+func TestRevokeMatchesZeroPaddedSerials(t *testing.T) {
+	dir := t.TempDir()
+	caCert, caKey := writeTestCA(t, filepath.Join(dir, "ca"))
+	leafPath := writeTestLeaf(t, caCert, caKey, filepath.Join(dir, "leaf"))
+	leaf := readTestCertificate(t, leafPath)
+	ca := readTestCertificate(t, caCert)
+
+	// A store holding the serial zero-padded, as older versions kept it
+	storePath := filepath.Join(dir, "revocations.json")
+	store := revocationStore{
+		hex.EncodeToString(ca.SubjectKeyId): &caRevocations{
+			Revoked: []revocation{{Serial: "00" + leaf.SerialNumber.Text(16), RevokedAt: time.Now().UTC(), Reason: "unspecified"}},
+		},
+	}
+	if err := saveRevocationStore(storePath, store, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	err := RevokeCertificate(&RevokeConfig{
+		CACertPath: caCert,
+		CAKeyPath:  caKey,
+		CertPath:   leafPath,
+		StorePath:  storePath,
+		OutputDir:  dir,
+		Quiet:      true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "already revoked") {
+		t.Fatalf("revoking a recorded serial: got %v, want an already revoked error", err)
+	}
+}
+
+func TestRevokeSerialIsReadLikeIssuance(t *testing.T) {
+	dir := t.TempDir()
+	caCert, caKey := writeTestCA(t, dir)
+
+	// Digits alone are decimal, as for cert --serial
+	config := &RevokeConfig{
+		CACertPath: caCert,
+		CAKeyPath:  caKey,
+		Serial:     "1000",
+		OutputDir:  dir,
+		Quiet:      true,
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if config.Serial != "3e8" {
+		t.Errorf("serial 1000 stored as %q, want 3e8", config.Serial)
+	}
+}
@@ -0,0 +1,162 @@
+package cert
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Certificate statuses reported by a scan
+const (
+	ScanOK       = "ok"
+	ScanExpiring = "expiring"
+	ScanExpired  = "expired"
+)
+
+// ScanResult describes a certificate found by ScanCertificates
+type ScanResult struct {
+	Path     string    `json:"path"`
+	Subject  string    `json:"subject"`
+	Serial   string    `json:"serial"` // Upper-case hex
+	NotAfter time.Time `json:"notAfter"`
+	DaysLeft int       `json:"daysLeft"` // Negative once expired
+	Status   string    `json:"status"`   // ok, expiring or expired
+}
+
+// ScanCertificates reports the certificates in the .crt and .pem files below
+// a directory that expire within the warning period or already have. With
+// JSON set, every certificate is written as JSON instead. A certificate
+// found in several files, such as a CA repeated in full chains, is reported
+// once, for the first file. An error is returned if any certificate
+// breaches the warning period, so that scheduled jobs can alert on it.
+func ScanCertificates(config *ScanConfig) ([]ScanResult, error) {
+	if err := config.Validate(); err != nil {
+		return nil, withKind(ErrValidation, fmt.Errorf("invalid scan configuration: %w", err))
+	}
+	warn, _ := parseValidity(config.Warn)
+
+	results, err := scanDirectory(config.Dir, time.Now(), warn, os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	breaches := 0
+	for _, result := range results {
+		if result.Status != ScanOK {
+			breaches++
+		}
+	}
+	if config.JSON {
+		encoder := json.NewEncoder(config.Output)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return nil, fmt.Errorf("writing scan results: %w", err)
+		}
+	} else if err := printScanResults(config.Output, results, breaches); err != nil {
+		return nil, err
+	}
+
+	if breaches > 0 {
+		return results, fmt.Errorf("%d of %d certificates expire within %s or have expired", breaches, len(results), config.Warn)
+	}
+	return results, nil
+}
+
+// scanDirectory parses the certificates in the .crt and .pem files below
+// dir. Files holding no certificates, such as keys, are skipped, and
+// unparsable certificates are reported to warnings without failing the scan.
+func scanDirectory(dir string, now time.Time, warn time.Duration, warnings io.Writer) ([]ScanResult, error) {
+	results := []ScanResult{}
+	seen := make(map[[32]byte]bool)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if entry.IsDir() || ext != ".crt" && ext != ".pem" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for {
+			var block *pem.Block
+			block, data = pem.Decode(data)
+			if block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				fmt.Fprintf(warnings, "Skipping certificate in %s: %v\n", path, err)
+				continue
+			}
+			sum := sha256.Sum256(cert.Raw)
+			if seen[sum] {
+				continue
+			}
+			seen[sum] = true
+			results = append(results, scanResult(path, cert, now, warn))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning %s: %w", dir, err)
+	}
+	return results, nil
+}
+
+// scanResult classifies a certificate by the time left until it expires
+func scanResult(path string, cert *x509.Certificate, now time.Time, warn time.Duration) ScanResult {
+	left := cert.NotAfter.Sub(now)
+	status := ScanOK
+	switch {
+	case left <= 0:
+		status = ScanExpired
+	case left <= warn:
+		status = ScanExpiring
+	}
+	subject := cert.Subject.CommonName
+	if subject == "" {
+		subject = cert.Subject.String()
+	}
+	return ScanResult{
+		Path:     path,
+		Subject:  subject,
+		Serial:   fmt.Sprintf("%X", cert.SerialNumber),
+		NotAfter: cert.NotAfter.UTC(),
+		DaysLeft: int(left.Hours() / 24),
+		Status:   status,
+	}
+}
+
+// printScanResults lists the expiring and expired certificates, followed by
+// a count of all of them
+func printScanResults(out io.Writer, results []ScanResult, breaches int) error {
+	if breaches > 0 {
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "STATUS\tEXPIRES\tDAYS\tSUBJECT\tPATH")
+		for _, result := range results {
+			if result.Status != ScanOK {
+				fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", result.Status, result.NotAfter.Format(time.DateOnly), result.DaysLeft, result.Subject, result.Path)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(out, "Scanned %d certificates: %d ok, %d expiring or expired\n", len(results), len(results)-breaches, breaches)
+	return err
+}
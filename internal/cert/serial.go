@@ -0,0 +1,111 @@
+package cert
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// serialFileName is the sequential serial store, kept next to the CA key
+const serialFileName = "serial"
+
+// maxSerialBits keeps serial numbers within the 20 octets RFC 5280 allows,
+// including the sign octet of the DER INTEGER
+const maxSerialBits = 20*8 - 1
+
+// templateSerialNumber returns the explicit serial number if one is set, or
+// a random one
+func templateSerialNumber(random io.Reader, serial string) (*big.Int, error) {
+	if serial == "" {
+		return generateSerialNumber(random)
+	}
+	return parseSerial(serial)
+}
+
+// parseSerial parses an explicit serial number. Digits alone are decimal;
+// a 0x prefix, hex letters or colon-separated octets make it hex.
+func parseSerial(serial string) (*big.Int, error) {
+	text := strings.ToLower(strings.TrimSpace(serial))
+	base := 10
+	if strings.HasPrefix(text, "0x") || strings.Contains(text, ":") || strings.ContainsAny(text, "abcdef") {
+		text = strings.ReplaceAll(strings.TrimPrefix(text, "0x"), ":", "")
+		base = 16
+	}
+	number, ok := new(big.Int).SetString(text, base)
+	if !ok {
+		return nil, fmt.Errorf("invalid serial %q: expected a decimal or hex number", serial)
+	}
+	if number.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid serial %q: must be positive", serial)
+	}
+	if number.BitLen() > maxSerialBits {
+		return nil, fmt.Errorf("invalid serial %q: must fit in 20 octets (at most %d bits)", serial, maxSerialBits)
+	}
+	return number, nil
+}
+
+// assignSerialNumber replaces the template's random serial number with the
+// next sequential serial of the CA whose private key is at caKeyPath. The
+// serials of a CA key held by a signer, or not read from a file, are kept
+// next to its certificate.
+func assignSerialNumber(template *x509.Certificate, mode SerialMode, caCertPath, caKeyPath string) error {
+	if mode != SerialSequential {
+		return nil
+	}
+	if caKeyPath == "" || isSignerKey(caKeyPath) {
+		caKeyPath = caCertPath
+	}
+	if caKeyPath == "" {
+		return fmt.Errorf("sequential serial numbers require the CA private key or certificate path")
+	}
+	serial, err := nextSerialNumber(filepath.Join(filepath.Dir(caKeyPath), serialFileName))
+	if err != nil {
+		return err
+	}
+	template.SerialNumber = serial
+	return nil
+}
+
+// nextSerialNumber returns the serial number stored in path and increments
+// it. Like OpenSSL's serial file, the file holds the next serial number in
+// hex and starts at 1 when it does not exist yet. The file is locked while
+// it is updated so concurrent issuance never reuses a serial.
+func nextSerialNumber(path string) (*big.Int, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("opening serial file: %w", err)
+	}
+	defer file.Close()
+
+	if err := lockFile(file); err != nil {
+		return nil, fmt.Errorf("locking serial file: %w", err)
+	}
+	defer unlockFile(file)
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading serial file: %w", err)
+	}
+	serial := big.NewInt(1)
+	if text := strings.TrimSpace(string(data)); text != "" {
+		if _, ok := serial.SetString(text, 16); !ok || serial.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid serial number %q in %s", text, path)
+		}
+	}
+
+	next := new(big.Int).Add(serial, big.NewInt(1))
+	if err := file.Truncate(0); err != nil {
+		return nil, fmt.Errorf("updating serial file: %w", err)
+	}
+	if _, err := file.WriteAt([]byte(fmt.Sprintf("%02X\n", next)), 0); err != nil {
+		return nil, fmt.Errorf("updating serial file: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return nil, fmt.Errorf("updating serial file: %w", err)
+	}
+	return serial, nil
+}
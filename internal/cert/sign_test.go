@@ -0,0 +1,110 @@
+package cert
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSignCertificateReissuesUnderCA(t *testing.T) {
+	caCert, caKey := writeTestCA(t, t.TempDir())
+	ca := readTestCertificate(t, caCert)
+
+	// The input is issued by another CA, so its issuer must be replaced
+	otherDir := t.TempDir()
+	if _, err := GenerateCA(&CAConfig{
+		Type:         Root,
+		Class:        Class2,
+		CommonName:   "Other Root CA",
+		Organization: StringList{"Other"},
+		Country:      StringList{"US"},
+		ValidityDays: 3650,
+		KeyType:      KeyTypeECDSA,
+		Curve:        CurveP384,
+		OutputDir:    otherDir,
+		Quiet:        true,
+	}); err != nil {
+		t.Fatalf("GenerateCA: %v", err)
+	}
+	certPath := writeTestLeaf(t, filepath.Join(otherDir, "ca.crt"), filepath.Join(otherDir, "ca.key"), t.TempDir())
+	original := readTestCertificate(t, certPath)
+	if bytes.Equal(original.RawIssuer, ca.RawSubject) {
+		t.Fatal("test certificate is already issued by the CA subject")
+	}
+
+	csrDir := t.TempDir()
+	if err := GenerateCSR(&CSRConfig{
+		CommonName:   "api.example.com",
+		Organization: StringList{"Test"},
+		Country:      StringList{"US"},
+		KeyType:      KeyTypeECDSA,
+		DNSNames:     []string{"api.example.com"},
+		OutputDir:    csrDir,
+		Quiet:        true,
+	}); err != nil {
+		t.Fatalf("GenerateCSR: %v", err)
+	}
+
+	for name, config := range map[string]*SignConfig{
+		"certificate": {CertPath: certPath},
+		"csr":         {CSRPath: filepath.Join(csrDir, "request.csr")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			out := t.TempDir()
+			config.CACertPath, config.CAKeyPath = caCert, caKey
+			config.OutputDir, config.Quiet = out, true
+			if err := SignCertificate(config); err != nil {
+				t.Fatalf("SignCertificate: %v", err)
+			}
+			signed := readTestCertificate(t, filepath.Join(out, "signed.crt"))
+
+			if !bytes.Equal(signed.RawIssuer, ca.RawSubject) {
+				t.Errorf("issuer %s does not match CA subject %s", signed.Issuer, ca.Subject)
+			}
+			if !bytes.Equal(signed.AuthorityKeyId, ca.SubjectKeyId) {
+				t.Errorf("authority key id %x, want CA subject key id %x", signed.AuthorityKeyId, ca.SubjectKeyId)
+			}
+			if signed.IsCA {
+				t.Error("signed certificate is a CA")
+			}
+			if config.CertPath != "" && signed.SerialNumber.Cmp(original.SerialNumber) == 0 {
+				t.Errorf("serial %x was kept from the original certificate", signed.SerialNumber)
+			}
+			if err := signed.CheckSignatureFrom(ca); err != nil {
+				t.Errorf("signature does not verify against the CA: %v", err)
+			}
+		})
+	}
+}
+
+func TestSignCertificateWithCABundleKeepsSerialsNextToCert(t *testing.T) {
+	dir := t.TempDir()
+	caCert, caKey := writeTestCA(t, filepath.Join(dir, "ca"))
+	key, err := loadPrivateKey(caKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := NewCABundle(readTestCertificate(t, caCert), key)
+	if err != nil {
+		t.Fatalf("NewCABundle: %v", err)
+	}
+	ca.CertPath = caCert
+
+	certPath := writeTestLeaf(t, caCert, caKey, t.TempDir())
+	out := t.TempDir()
+	if err := SignCertificateWithCA(&SignConfig{
+		CertPath:   certPath,
+		SerialMode: SerialSequential,
+		OutputDir:  out,
+		Quiet:      true,
+	}, ca); err != nil {
+		t.Fatalf("SignCertificateWithCA: %v", err)
+	}
+	if got := readTestCertificate(t, filepath.Join(out, "signed.crt")).SerialNumber.Int64(); got != 1 {
+		t.Errorf("serial = %d, want 1", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ca", serialFileName)); err != nil {
+		t.Errorf("serial file next to the CA certificate: %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package cert
+
+import (
+	"crypto"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// SignerOpener opens a CA private key held outside a key file, such as in an
+// HSM or a key management service. It is passed the full key reference,
+// including its scheme.
+type SignerOpener func(ref string) (crypto.Signer, error)
+
+// signerScheme matches URI schemes, at least two characters long so Windows
+// drive letters are never taken for one
+var signerScheme = regexp.MustCompile(`^[a-z][a-z0-9+.-]+$`)
+
+// KeyCreator creates a CA private key in an HSM or key management service.
+// It is passed the full reference of the key to create, including its
+// scheme, and the key type, RSA size and ECDSA curve the CA configuration
+// asks for, and returns the reference that opens the new key as a signer.
+type KeyCreator func(ref string, keyType KeyType, keySize int, curve Curve) (string, error)
+
+var (
+	signersMu sync.RWMutex
+	signers   = map[string]SignerOpener{}
+	creators  = map[string]KeyCreator{}
+)
+
+// RegisterSigner makes CA private key references of the form scheme:... open
+// through open instead of being read from a file. Such a reference can be
+// used anywhere a CA or parent CA key path is accepted. Registering a scheme
+// again replaces its opener.
+func RegisterSigner(scheme string, open SignerOpener) {
+	scheme = strings.ToLower(scheme)
+	if !signerScheme.MatchString(scheme) {
+		panic(fmt.Sprintf("cert: invalid signer scheme %q", scheme))
+	}
+	if open == nil {
+		panic("cert: RegisterSigner with a nil opener")
+	}
+	signersMu.Lock()
+	defer signersMu.Unlock()
+	signers[scheme] = open
+}
+
+// RegisterKeyCreator makes CA key references of the form scheme:... usable
+// as createKMSKey, creating the key through create. The scheme also needs a
+// signer registered with RegisterSigner to open the created key.
+// Registering a scheme again replaces its creator.
+func RegisterKeyCreator(scheme string, create KeyCreator) {
+	scheme = strings.ToLower(scheme)
+	if !signerScheme.MatchString(scheme) {
+		panic(fmt.Sprintf("cert: invalid key creator scheme %q", scheme))
+	}
+	if create == nil {
+		panic("cert: RegisterKeyCreator with a nil creator")
+	}
+	signersMu.Lock()
+	defer signersMu.Unlock()
+	creators[scheme] = create
+}
+
+// keyCreator returns the creator of a key reference whose scheme is
+// registered
+func keyCreator(ref string) (KeyCreator, bool) {
+	scheme, _, ok := strings.Cut(ref, ":")
+	if !ok {
+		return nil, false
+	}
+	signersMu.RLock()
+	defer signersMu.RUnlock()
+	create, ok := creators[strings.ToLower(scheme)]
+	return create, ok
+}
+
+// signerOpener returns the opener of a key reference whose scheme is
+// registered
+func signerOpener(keyPath string) (SignerOpener, bool) {
+	scheme, _, ok := strings.Cut(keyPath, ":")
+	if !ok {
+		return nil, false
+	}
+	signersMu.RLock()
+	defer signersMu.RUnlock()
+	open, ok := signers[strings.ToLower(scheme)]
+	return open, ok
+}
+
+// isSignerKey reports whether a CA key path is a signer reference rather
+// than a file
+func isSignerKey(keyPath string) bool {
+	_, ok := signerOpener(keyPath)
+	return ok
+}
@@ -0,0 +1,90 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// testKMS holds the keys created through the testkms scheme
+var testKMS sync.Map
+
+func init() {
+	RegisterKeyCreator("testkms", func(ref string, keyType KeyType, keySize int, curve Curve) (string, error) {
+		if keyType != KeyTypeECDSA || curve != CurveP384 {
+			return "", fmt.Errorf("testkms only creates P384 keys, not %s %s", keyType, curve)
+		}
+		key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		if err != nil {
+			return "", err
+		}
+		version := ref + "/1"
+		testKMS.Store(version, key)
+		return version, nil
+	})
+	RegisterSigner("testkms", func(ref string) (crypto.Signer, error) {
+		key, ok := testKMS.Load(ref)
+		if !ok {
+			return nil, fmt.Errorf("no key %s", ref)
+		}
+		return key.(crypto.Signer), nil
+	})
+}
+
+func TestGenerateCACreateKMSKey(t *testing.T) {
+	dir := t.TempDir()
+	config := &CAConfig{
+		Type:         Root,
+		Class:        Class2,
+		CommonName:   "KMS Root CA",
+		Organization: StringList{"Test"},
+		Country:      StringList{"US"},
+		ValidityDays: 3650,
+		KeyType:      KeyTypeECDSA,
+		Curve:        CurveP384,
+		CreateKMSKey: "testkms:root",
+		OutputDir:    dir,
+		Quiet:        true,
+	}
+	result, err := GenerateCA(config)
+	if err != nil {
+		t.Fatalf("GenerateCA: %v", err)
+	}
+
+	key, ok := testKMS.Load("testkms:root/1")
+	if !ok {
+		t.Fatalf("no key was created in KMS")
+	}
+	if !publicKeysEqual(key.(crypto.Signer).Public(), result.Certificate.PublicKey) {
+		t.Errorf("CA certificate does not hold the created key")
+	}
+	if err := result.Certificate.CheckSignatureFrom(result.Certificate); err != nil {
+		t.Errorf("CA certificate is not signed by the created key: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ca.key")); !os.IsNotExist(err) {
+		t.Errorf("a key file was written for a key kept in KMS")
+	}
+
+	// The key is created by KMS, not read from a key file
+	config = &CAConfig{
+		Type:         Root,
+		Class:        Class2,
+		CommonName:   "KMS Root CA",
+		Organization: StringList{"Test"},
+		Country:      StringList{"US"},
+		KeyType:      KeyTypeECDSA,
+		Curve:        CurveP384,
+		KeyFile:      "testkms:root/1",
+		CreateKMSKey: "testkms:other",
+		OutputDir:    dir,
+	}
+	if err := config.Validate(); err == nil {
+		t.Errorf("Validate accepted both keyFile and createKMSKey")
+	}
+}
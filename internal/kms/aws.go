@@ -0,0 +1,192 @@
+package kms
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+
+	"certgen/internal/cert"
+)
+
+// AWS KMS signing algorithms by digest
+var (
+	awsPKCS1 = map[crypto.Hash]types.SigningAlgorithmSpec{
+		crypto.SHA256: types.SigningAlgorithmSpecRsassaPkcs1V15Sha256,
+		crypto.SHA384: types.SigningAlgorithmSpecRsassaPkcs1V15Sha384,
+		crypto.SHA512: types.SigningAlgorithmSpecRsassaPkcs1V15Sha512,
+	}
+	awsPSS = map[crypto.Hash]types.SigningAlgorithmSpec{
+		crypto.SHA256: types.SigningAlgorithmSpecRsassaPssSha256,
+		crypto.SHA384: types.SigningAlgorithmSpecRsassaPssSha384,
+		crypto.SHA512: types.SigningAlgorithmSpecRsassaPssSha512,
+	}
+	awsECDSA = map[crypto.Hash]types.SigningAlgorithmSpec{
+		crypto.SHA256: types.SigningAlgorithmSpecEcdsaSha256,
+		crypto.SHA384: types.SigningAlgorithmSpecEcdsaSha384,
+		crypto.SHA512: types.SigningAlgorithmSpecEcdsaSha512,
+	}
+)
+
+// awsSigner signs with an AWS KMS asymmetric signing key
+type awsSigner struct {
+	client     *kms.Client
+	keyID      string
+	public     crypto.PublicKey
+	algorithms []types.SigningAlgorithmSpec
+}
+
+// openAWS opens a key referenced by its ARN or as awskms:///KEY, using the
+// default credential chain. The region of an ARN takes precedence over the
+// configured one.
+func openAWS(ref string) (crypto.Signer, error) {
+	keyID := ref
+	if rest, ok := strings.CutPrefix(ref, "awskms://"); ok {
+		keyID = strings.TrimPrefix(rest, "/")
+	}
+	var options []func(*config.LoadOptions) error
+	if arn.IsARN(keyID) {
+		parsed, err := arn.Parse(keyID)
+		if err != nil || parsed.Service != "kms" {
+			return nil, fmt.Errorf("%s is not an AWS KMS key ARN", keyID)
+		}
+		options = append(options, config.WithRegion(parsed.Region))
+	} else if strings.HasPrefix(ref, "arn:") {
+		return nil, fmt.Errorf("%s is not an AWS KMS key ARN", keyID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	cfg, err := config.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	client := kms.NewFromConfig(cfg)
+	out, err := client.GetPublicKey(ctx, &kms.GetPublicKeyInput{KeyId: aws.String(keyID)})
+	if err != nil {
+		return nil, fmt.Errorf("getting public key: %w", err)
+	}
+	if out.KeyUsage != types.KeyUsageTypeSignVerify {
+		return nil, fmt.Errorf("key usage is %s, not %s", out.KeyUsage, types.KeyUsageTypeSignVerify)
+	}
+	public, err := x509.ParsePKIXPublicKey(out.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("parsing public key: %w", err)
+	}
+	return &awsSigner{client: client, keyID: keyID, public: public, algorithms: out.SigningAlgorithms}, nil
+}
+
+func (s *awsSigner) Public() crypto.PublicKey {
+	return s.public
+}
+
+func (s *awsSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	var algorithms map[crypto.Hash]types.SigningAlgorithmSpec
+	switch s.public.(type) {
+	case *rsa.PublicKey:
+		algorithms = awsPKCS1
+		if _, ok := opts.(*rsa.PSSOptions); ok {
+			algorithms = awsPSS
+		}
+	case *ecdsa.PublicKey:
+		algorithms = awsECDSA
+	default:
+		return nil, fmt.Errorf("unsupported AWS KMS key type %T", s.public)
+	}
+	algorithm, ok := algorithms[opts.HashFunc()]
+	if !ok {
+		return nil, fmt.Errorf("unsupported digest %s", opts.HashFunc())
+	}
+	if !slices.Contains(s.algorithms, algorithm) {
+		return nil, fmt.Errorf("key cannot sign with %s, set a signature algorithm it supports: %v", algorithm, s.algorithms)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	out, err := s.client.Sign(ctx, &kms.SignInput{
+		KeyId:            aws.String(s.keyID),
+		Message:          digest,
+		MessageType:      types.MessageTypeDigest,
+		SigningAlgorithm: algorithm,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("signing with %s: %w", s.keyID, err)
+	}
+	return out.Signature, nil
+}
+
+// awsKeySpec returns the AWS KMS key spec of a new key
+func awsKeySpec(keyType cert.KeyType, keySize int, curve cert.Curve) (types.KeySpec, error) {
+	switch keyType {
+	case cert.KeyTypeRSA:
+		switch keySize {
+		case 2048:
+			return types.KeySpecRsa2048, nil
+		case 3072:
+			return types.KeySpecRsa3072, nil
+		case 4096:
+			return types.KeySpecRsa4096, nil
+		}
+		return "", fmt.Errorf("AWS KMS creates 2048, 3072 or 4096-bit RSA keys, not %d-bit", keySize)
+	case cert.KeyTypeECDSA:
+		switch curve {
+		case cert.CurveP256:
+			return types.KeySpecEccNistP256, nil
+		case cert.CurveP384:
+			return types.KeySpecEccNistP384, nil
+		case cert.CurveP521:
+			return types.KeySpecEccNistP521, nil
+		}
+		return "", fmt.Errorf("unsupported curve %s", curve)
+	}
+	return "", fmt.Errorf("AWS KMS keys cannot sign certificates as %s", keyType)
+}
+
+// createAWS creates a signing key named by the alias in awskms:///alias/NAME,
+// in the configured region, and returns its ARN
+func createAWS(ref string, keyType cert.KeyType, keySize int, curve cert.Curve) (string, error) {
+	alias := strings.TrimPrefix(strings.TrimPrefix(ref, "awskms://"), "/")
+	if !strings.HasPrefix(alias, "alias/") || alias == "alias/" {
+		return "", fmt.Errorf("expected awskms:///alias/NAME, the alias the new key is created under")
+	}
+	spec, err := awsKeySpec(keyType, keySize, curve)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return "", fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	client := kms.NewFromConfig(cfg)
+	out, err := client.CreateKey(ctx, &kms.CreateKeyInput{
+		KeySpec:     spec,
+		KeyUsage:    types.KeyUsageTypeSignVerify,
+		Description: aws.String("certgen CA key " + alias),
+	})
+	if err != nil {
+		return "", fmt.Errorf("creating key: %w", err)
+	}
+	keyARN := aws.ToString(out.KeyMetadata.Arn)
+	_, err = client.CreateAlias(ctx, &kms.CreateAliasInput{
+		AliasName:   aws.String(alias),
+		TargetKeyId: out.KeyMetadata.KeyId,
+	})
+	if err != nil {
+		return "", fmt.Errorf("key %s was created, but naming it %s failed: %w", keyARN, alias, err)
+	}
+	return keyARN, nil
+}
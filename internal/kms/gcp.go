@@ -0,0 +1,197 @@
+package kms
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	kmsapi "cloud.google.com/go/kms/apiv1"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+
+	"certgen/internal/cert"
+)
+
+// gcpAlgorithm is the digest a Cloud KMS key version signs and whether its
+// RSA signatures use PSS
+type gcpAlgorithm struct {
+	hash crypto.Hash
+	pss  bool
+}
+
+// gcpAlgorithms lists the Cloud KMS algorithms that can sign certificates.
+// Ed25519 signs the message itself rather than a digest.
+var gcpAlgorithms = map[kmspb.CryptoKeyVersion_CryptoKeyVersionAlgorithm]gcpAlgorithm{
+	kmspb.CryptoKeyVersion_RSA_SIGN_PSS_2048_SHA256:   {crypto.SHA256, true},
+	kmspb.CryptoKeyVersion_RSA_SIGN_PSS_3072_SHA256:   {crypto.SHA256, true},
+	kmspb.CryptoKeyVersion_RSA_SIGN_PSS_4096_SHA256:   {crypto.SHA256, true},
+	kmspb.CryptoKeyVersion_RSA_SIGN_PSS_4096_SHA512:   {crypto.SHA512, true},
+	kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_2048_SHA256: {crypto.SHA256, false},
+	kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_3072_SHA256: {crypto.SHA256, false},
+	kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_4096_SHA256: {crypto.SHA256, false},
+	kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_4096_SHA512: {crypto.SHA512, false},
+	kmspb.CryptoKeyVersion_EC_SIGN_P256_SHA256:        {crypto.SHA256, false},
+	kmspb.CryptoKeyVersion_EC_SIGN_P384_SHA384:        {crypto.SHA384, false},
+	kmspb.CryptoKeyVersion_EC_SIGN_ED25519:            {0, false},
+}
+
+// gcpSigner signs with a Cloud KMS asymmetric signing key version
+type gcpSigner struct {
+	client    *kmsapi.KeyManagementClient
+	name      string
+	public    crypto.PublicKey
+	algorithm gcpAlgorithm
+}
+
+// openGCP opens a key version referenced as gcpkms://RESOURCE_NAME, using
+// the application default credentials
+func openGCP(ref string) (crypto.Signer, error) {
+	name := strings.TrimPrefix(ref, "gcpkms://")
+	if !strings.HasPrefix(name, "projects/") || !strings.Contains(name, "/cryptoKeyVersions/") {
+		return nil, fmt.Errorf("expected gcpkms://projects/PROJECT/locations/LOCATION/keyRings/RING/cryptoKeys/KEY/cryptoKeyVersions/VERSION")
+	}
+
+	client, err := kmsapi.NewKeyManagementClient(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("creating Cloud KMS client: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	resp, err := client.GetPublicKey(ctx, &kmspb.GetPublicKeyRequest{Name: name})
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("getting public key: %w", err)
+	}
+	algorithm, ok := gcpAlgorithms[resp.Algorithm]
+	if !ok {
+		client.Close()
+		return nil, fmt.Errorf("key algorithm %s cannot sign certificates", resp.Algorithm)
+	}
+	block, _ := pem.Decode([]byte(resp.Pem))
+	if block == nil {
+		client.Close()
+		return nil, fmt.Errorf("public key is not PEM encoded")
+	}
+	public, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("parsing public key: %w", err)
+	}
+	return &gcpSigner{client: client, name: name, public: public, algorithm: algorithm}, nil
+}
+
+func (s *gcpSigner) Public() crypto.PublicKey {
+	return s.public
+}
+
+func (s *gcpSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if err := checkOpts(opts, s.algorithm.hash, s.algorithm.pss); err != nil {
+		return nil, err
+	}
+	req := &kmspb.AsymmetricSignRequest{Name: s.name}
+	switch s.algorithm.hash {
+	case crypto.SHA256:
+		req.Digest = &kmspb.Digest{Digest: &kmspb.Digest_Sha256{Sha256: digest}}
+	case crypto.SHA384:
+		req.Digest = &kmspb.Digest{Digest: &kmspb.Digest_Sha384{Sha384: digest}}
+	case crypto.SHA512:
+		req.Digest = &kmspb.Digest{Digest: &kmspb.Digest_Sha512{Sha512: digest}}
+	default:
+		req.Data = digest
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	resp, err := s.client.AsymmetricSign(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("signing with %s: %w", s.name, err)
+	}
+	return resp.Signature, nil
+}
+
+// gcpCreateAlgorithm returns the Cloud KMS algorithm of a new key, signing
+// the digest certgen uses by default for the key type
+func gcpCreateAlgorithm(keyType cert.KeyType, keySize int, curve cert.Curve) (kmspb.CryptoKeyVersion_CryptoKeyVersionAlgorithm, error) {
+	switch keyType {
+	case cert.KeyTypeRSA:
+		switch keySize {
+		case 2048:
+			return kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_2048_SHA256, nil
+		case 3072:
+			return kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_3072_SHA256, nil
+		case 4096:
+			return kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_4096_SHA256, nil
+		}
+		return 0, fmt.Errorf("Cloud KMS creates 2048, 3072 or 4096-bit RSA keys, not %d-bit", keySize)
+	case cert.KeyTypeECDSA:
+		switch curve {
+		case cert.CurveP256:
+			return kmspb.CryptoKeyVersion_EC_SIGN_P256_SHA256, nil
+		case cert.CurveP384:
+			return kmspb.CryptoKeyVersion_EC_SIGN_P384_SHA384, nil
+		}
+		return 0, fmt.Errorf("Cloud KMS creates P256 or P384 keys, not %s", curve)
+	case cert.KeyTypeEd25519:
+		return kmspb.CryptoKeyVersion_EC_SIGN_ED25519, nil
+	}
+	return 0, fmt.Errorf("unsupported key type %s", keyType)
+}
+
+// createGCP creates an asymmetric signing key referenced as
+// gcpkms://projects/.../keyRings/RING/cryptoKeys/KEY and returns the
+// reference of its first version once it can sign
+func createGCP(ref string, keyType cert.KeyType, keySize int, curve cert.Curve) (string, error) {
+	name := strings.TrimPrefix(ref, "gcpkms://")
+	parent, keyID, ok := strings.Cut(name, "/cryptoKeys/")
+	if !strings.HasPrefix(parent, "projects/") || !strings.Contains(parent, "/keyRings/") || !ok || keyID == "" || strings.Contains(keyID, "/") {
+		return "", fmt.Errorf("expected gcpkms://projects/PROJECT/locations/LOCATION/keyRings/RING/cryptoKeys/KEY")
+	}
+	algorithm, err := gcpCreateAlgorithm(keyType, keySize, curve)
+	if err != nil {
+		return "", err
+	}
+
+	client, err := kmsapi.NewKeyManagementClient(context.Background())
+	if err != nil {
+		return "", fmt.Errorf("creating Cloud KMS client: %w", err)
+	}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	key, err := client.CreateCryptoKey(ctx, &kmspb.CreateCryptoKeyRequest{
+		Parent:      parent,
+		CryptoKeyId: keyID,
+		CryptoKey: &kmspb.CryptoKey{
+			Purpose:         kmspb.CryptoKey_ASYMMETRIC_SIGN,
+			VersionTemplate: &kmspb.CryptoKeyVersionTemplate{Algorithm: algorithm},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("creating key: %w", err)
+	}
+
+	// The first version is generated in the background
+	version := key.Name + "/cryptoKeyVersions/1"
+	for {
+		resp, err := client.GetCryptoKeyVersion(ctx, &kmspb.GetCryptoKeyVersionRequest{Name: version})
+		if err != nil {
+			return "", fmt.Errorf("waiting for %s: %w", version, err)
+		}
+		switch resp.State {
+		case kmspb.CryptoKeyVersion_ENABLED:
+			return "gcpkms://" + version, nil
+		case kmspb.CryptoKeyVersion_PENDING_GENERATION:
+		default:
+			return "", fmt.Errorf("key version %s is %s", version, resp.State)
+		}
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("waiting for %s: %w", version, ctx.Err())
+		case <-time.After(time.Second):
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// Package kms provides CA keys held in Google Cloud KMS and AWS KMS as
+// signers, so certificates are issued without the private key ever leaving
+// the key management service
+package kms
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"fmt"
+	"time"
+
+	"certgen/internal/cert"
+)
+
+// callTimeout bounds every request to a key management service
+const callTimeout = 30 * time.Second
+
+// Register makes Cloud KMS key versions, referenced as
+// gcpkms://projects/.../cryptoKeyVersions/N, and AWS KMS keys, referenced by
+// key ARN or as awskms:///KEY with a key ID, alias or ARN, usable wherever a
+// CA key path is accepted. New CA keys can be created as
+// gcpkms://projects/.../cryptoKeys/KEY or awskms:///alias/NAME.
+func Register() {
+	cert.RegisterSigner("gcpkms", openGCP)
+	cert.RegisterSigner("awskms", openAWS)
+	cert.RegisterSigner("arn", openAWS)
+	cert.RegisterKeyCreator("gcpkms", createGCP)
+	cert.RegisterKeyCreator("awskms", createAWS)
+}
+
+// checkOpts rejects signatures a key cannot make. KMS keys sign with the
+// digest and RSA padding fixed when they were created.
+func checkOpts(opts crypto.SignerOpts, hash crypto.Hash, pss bool) error {
+	if opts.HashFunc() != hash {
+		return fmt.Errorf("key signs %s digests, not %s, set a matching signature algorithm", hash, opts.HashFunc())
+	}
+	if _, isPSS := opts.(*rsa.PSSOptions); isPSS != pss {
+		if pss {
+			return fmt.Errorf("key signs with RSA-PSS, set a matching signature algorithm")
+		}
+		return fmt.Errorf("key signs with RSA PKCS#1 v1.5, set a matching signature algorithm")
+	}
+	return nil
+}
@@ -1,17 +1,27 @@
 package system
 
 import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"syscall"
 )
 
 // CertificateTrustManager handles system-level certificate trust operations
 type CertificateTrustManager struct {
-	progress ProgressReporter
+	progress   ProgressReporter
+	escalation string // Command running privileged commands, or EscalationNone
 }
 
 // ProgressReporter interface for reporting progress
@@ -20,25 +30,471 @@ type ProgressReporter interface {
 	CompleteProgress()
 }
 
-// NewCertificateTrustManager creates a new trust manager
-func NewCertificateTrustManager(progress ProgressReporter) *CertificateTrustManager {
+// NewCertificateTrustManager creates a new trust manager that runs commands
+// needing administrator rights through escalation, such as sudo, doas or
+// pkexec, or directly if it is EscalationNone
+func NewCertificateTrustManager(progress ProgressReporter, escalation string) *CertificateTrustManager {
 	return &CertificateTrustManager{
-		progress: progress,
+		progress:   progress,
+		escalation: escalation,
 	}
 }
 
-// InstallAndTrustCA installs and trusts a CA certificate in the system
-func (m *CertificateTrustManager) InstallAndTrustCA(certPath string) error {
+// EscalationNone runs privileged commands directly, for CI jobs that already
+// run as root or must not wait for a password prompt
+const EscalationNone = "none"
+
+// privileged returns a command run with administrator rights, directly if
+// the process already runs as root
+func (m *CertificateTrustManager) privileged(args ...string) *exec.Cmd {
+	if m.escalation == EscalationNone || os.Geteuid() == 0 {
+		return exec.Command(args[0], args[1:]...)
+	}
+	return exec.Command(m.escalation, args...)
+}
+
+// privilegedError describes a failed privileged command. Without escalation,
+// it lists the commands to run manually as an administrator.
+func (m *CertificateTrustManager) privilegedError(action string, err error, steps ...[]string) error {
+	if m.escalation != EscalationNone {
+		return fmt.Errorf("%s: %w", action, err)
+	}
+	var commands []string
+	for _, step := range steps {
+		commands = append(commands, "  "+strings.Join(step, " "))
+	}
+	return fmt.Errorf("%s: %w\nRun these commands as an administrator, or retry with a privilege escalation command:\n%s",
+		action, err, strings.Join(commands, "\n"))
+}
+
+// CommandError describes a failed trust store command with its exit status
+// and the output it wrote to stdout and stderr
+type CommandError struct {
+	Args     []string
+	ExitCode int // -1 if the command could not be started
+	Stdout   string
+	Stderr   string
+	Err      error
+}
+
+// Error reports the command and its exit status, followed by its output
+func (e *CommandError) Error() string {
+	command := strings.Join(e.Args, " ")
+	message := fmt.Sprintf("%s exited with status %d", command, e.ExitCode)
+	if e.ExitCode < 0 {
+		message = fmt.Sprintf("%s: %v", command, e.Err)
+	}
+	if e.Stderr != "" {
+		message += "\nstderr: " + e.Stderr
+	}
+	if e.Stdout != "" {
+		message += "\nstdout: " + e.Stdout
+	}
+	return message
+}
+
+// Unwrap returns the error of running the command
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// runCommand runs cmd and returns its stdout. If it fails, the error is a
+// *CommandError holding its exit status, stdout and stderr.
+func runCommand(cmd *exec.Cmd) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err == nil {
+		return stdout.String(), nil
+	}
+	exitCode := -1
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	}
+	return stdout.String(), &CommandError{
+		Args:     cmd.Args,
+		ExitCode: exitCode,
+		Stdout:   strings.TrimSpace(stdout.String()),
+		Stderr:   strings.TrimSpace(stderr.String()),
+		Err:      err,
+	}
+}
+
+// permissionDenied reports whether a command failed for lack of
+// administrator rights
+func permissionDenied(err error) bool {
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		return false
+	}
+	output := cmdErr.Stderr + cmdErr.Stdout
+	return strings.Contains(output, "authorization") || strings.Contains(output, "permission")
+}
+
+// Scope selects whose trust store a CA certificate is added to
+type Scope string
+
+const (
+	// ScopeSystem trusts the certificate for all users and needs
+	// administrator rights
+	ScopeSystem Scope = "system"
+	// ScopeUser trusts the certificate for the current user only, without
+	// administrator rights (macOS login keychain, Windows CurrentUser store)
+	ScopeUser Scope = "user"
+)
+
+// InstallAndTrustCA installs and trusts a CA certificate in the system, then
+// checks that the trust store holds it, since some installation commands
+// report success without importing anything
+func (m *CertificateTrustManager) InstallAndTrustCA(certPath string, scope Scope) error {
+	var err error
 	switch runtime.GOOS {
 	case "darwin":
-		return m.installAndTrustCADarwin(certPath)
+		err = m.installAndTrustCADarwin(certPath, scope)
 	case "linux":
-		return m.installAndTrustCALinux(certPath)
+		if scope == ScopeUser {
+			return fmt.Errorf("user scope trust is not supported on Linux, use the NSS databases instead")
+		}
+		err = m.installAndTrustCALinux(certPath)
 	case "windows":
-		return m.installAndTrustCAWindows(certPath)
+		err = m.installAndTrustCAWindows(certPath, scope)
 	default:
 		return fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
 	}
+	if err != nil {
+		return err
+	}
+
+	trusted, err := m.IsTrustedCA(certPath, scope)
+	if err != nil {
+		return fmt.Errorf("verifying installation: %w", err)
+	}
+	if !trusted {
+		return fmt.Errorf("installation reported success, but the certificate is not in the %s trust store", scope)
+	}
+	return nil
+}
+
+// IsTrustedCA reports whether a CA certificate is already in the trust store
+// InstallAndTrustCA adds it to, matched by fingerprint
+func (m *CertificateTrustManager) IsTrustedCA(certPath string, scope Scope) (bool, error) {
+	sha1Sum, sha256Sum, err := certificateFingerprints(certPath)
+	if err != nil {
+		return false, err
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		keychain := "/Library/Keychains/System.keychain"
+		if scope == ScopeUser {
+			if keychain, err = darwinLoginKeychain(); err != nil {
+				return false, err
+			}
+		}
+		output, err := runCommand(exec.Command("security", "find-certificate", "-a", "-Z", keychain))
+		if err != nil {
+			return false, fmt.Errorf("listing certificates in %s: %w", keychain, err)
+		}
+		return strings.Contains(output, "SHA-1 hash: "+sha1Sum), nil
+	case "linux":
+		if scope == ScopeUser {
+			return false, fmt.Errorf("user scope trust is not supported on Linux, use the NSS databases instead")
+		}
+		matches, err := installedLinuxCopies(detectLinuxTrustStore(), sha256Sum)
+		return len(matches) > 0, err
+	case "windows":
+		args := []string{"-verifystore", "ROOT", sha1Sum}
+		if scope == ScopeUser {
+			args = append([]string{"-user"}, args...)
+		}
+		// certutil fails when the store holds no certificate with the hash
+		_, err := runCommand(exec.Command("certutil", args...))
+		return err == nil, nil
+	default:
+		return false, fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
+	}
+}
+
+// UntrustCA removes a CA certificate previously trusted with
+// InstallAndTrustCA. The certificate is matched by fingerprint so only that
+// exact certificate is removed from the system store.
+func (m *CertificateTrustManager) UntrustCA(certPath string, scope Scope) error {
+	var err error
+	switch runtime.GOOS {
+	case "darwin":
+		err = m.untrustCADarwin(certPath, scope)
+	case "linux":
+		if scope == ScopeUser {
+			return fmt.Errorf("user scope trust is not supported on Linux, use the NSS databases instead")
+		}
+		err = m.untrustCALinux(certPath)
+	case "windows":
+		err = m.untrustCAWindows(certPath, scope)
+	default:
+		return fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
+	}
+	if err != nil {
+		return err
+	}
+
+	trusted, err := m.IsTrustedCA(certPath, scope)
+	if err != nil {
+		return fmt.Errorf("verifying removal: %w", err)
+	}
+	if trusted {
+		return fmt.Errorf("removal reported success, but the certificate is still in the %s trust store", scope)
+	}
+	return nil
+}
+
+// TrustedCA is a CA certificate found in a trust store
+type TrustedCA struct {
+	Certificate *x509.Certificate
+	Source      string // File, keychain or store holding the certificate
+}
+
+// ListTrustedCAs returns the CA certificates in the trust store of scope.
+// Unless all is set, only those certgen likely installed are returned: the
+// files it names certgen-* on Linux and the certificates added to the System
+// or login keychain on macOS, rather than the roots shipped with the
+// operating system. Windows does not tell them apart, so its whole ROOT store
+// is listed.
+func (m *CertificateTrustManager) ListTrustedCAs(scope Scope, all bool) ([]TrustedCA, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return listTrustedCAsDarwin(scope, all)
+	case "linux":
+		if scope == ScopeUser {
+			return nil, fmt.Errorf("user scope trust is not supported on Linux, use the NSS databases instead")
+		}
+		return listTrustedCAsLinux(detectLinuxTrustStore(), all)
+	case "windows":
+		return listTrustedCAsWindows(scope)
+	default:
+		return nil, fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
+	}
+}
+
+func listTrustedCAsDarwin(scope Scope, all bool) ([]TrustedCA, error) {
+	keychains := []string{"/Library/Keychains/System.keychain"}
+	if scope == ScopeUser {
+		keychain, err := darwinLoginKeychain()
+		if err != nil {
+			return nil, err
+		}
+		keychains = []string{keychain}
+	}
+	if all {
+		keychains = append(keychains, "/System/Library/Keychains/SystemRootCertificates.keychain")
+	}
+
+	var cas []TrustedCA
+	for _, keychain := range keychains {
+		output, err := runCommand(exec.Command("security", "find-certificate", "-a", "-p", keychain))
+		if err != nil {
+			return nil, fmt.Errorf("listing certificates in %s: %w", keychain, err)
+		}
+		certs, err := parsePEMCertificates([]byte(output))
+		if err != nil {
+			return nil, fmt.Errorf("parsing certificates in %s: %w", keychain, err)
+		}
+		cas = appendTrustedCAs(cas, certs, keychain)
+	}
+	return cas, nil
+}
+
+func listTrustedCAsLinux(store linuxTrustStore, all bool) ([]TrustedCA, error) {
+	pattern := "certgen-*"
+	if all {
+		pattern = "*"
+	}
+	files, err := filepath.Glob(filepath.Join(store.dir, pattern))
+	if err != nil {
+		return nil, fmt.Errorf("listing CA certificates: %w", err)
+	}
+	if all {
+		files = append(files, store.bundle)
+	}
+
+	var cas []TrustedCA
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			if os.IsNotExist(err) || errors.Is(err, syscall.EISDIR) {
+				continue
+			}
+			return nil, fmt.Errorf("reading %s: %w", file, err)
+		}
+		certs, err := parsePEMCertificates(data)
+		if err != nil {
+			continue // Not a certificate file
+		}
+		cas = appendTrustedCAs(cas, certs, file)
+	}
+	return cas, nil
+}
+
+func listTrustedCAsWindows(scope Scope) ([]TrustedCA, error) {
+	location := `Cert:\LocalMachine\Root`
+	if scope == ScopeUser {
+		location = `Cert:\CurrentUser\Root`
+	}
+	script := "Get-ChildItem -Path " + location + " | ForEach-Object { [Convert]::ToBase64String($_.RawData) }"
+	output, err := runCommand(exec.Command("powershell", "-NoProfile", "-Command", script))
+	if err != nil {
+		return nil, fmt.Errorf("listing certificates in %s: %w", location, err)
+	}
+
+	var certs []*x509.Certificate
+	for _, line := range strings.Fields(output) {
+		der, err := base64.StdEncoding.DecodeString(line)
+		if err != nil {
+			return nil, fmt.Errorf("decoding certificate in %s: %w", location, err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			continue // Skip certificates Go cannot parse
+		}
+		certs = append(certs, cert)
+	}
+	return appendTrustedCAs(nil, certs, location), nil
+}
+
+// appendTrustedCAs appends the certificates found in source that are not
+// listed yet, as a bundle repeats the anchors it is built from
+func appendTrustedCAs(cas []TrustedCA, certs []*x509.Certificate, source string) []TrustedCA {
+	for _, cert := range certs {
+		duplicate := false
+		for _, ca := range cas {
+			if ca.Certificate.Equal(cert) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			cas = append(cas, TrustedCA{Certificate: cert, Source: source})
+		}
+	}
+	return cas
+}
+
+// parsePEMCertificates parses every CERTIFICATE block of PEM data
+func parsePEMCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no PEM certificates found")
+	}
+	return certs, nil
+}
+
+// TrustCANSS adds a CA certificate to the NSS databases used by Firefox and
+// Chromium, which do not consult the system trust store. It is a no-op when
+// no NSS database is found or NSS certutil is not installed.
+func (m *CertificateTrustManager) TrustCANSS(certPath string) error {
+	dbs, nickname, err := m.nssTargets(certPath)
+	if err != nil || len(dbs) == 0 {
+		return err
+	}
+
+	m.progress.StartProgress("Adding CA certificate to NSS databases")
+	defer m.progress.CompleteProgress()
+
+	for _, db := range dbs {
+		cmd := exec.Command("certutil", "-A", "-n", nickname, "-t", "C,,", "-i", certPath, "-d", "sql:"+db)
+		if _, err := runCommand(cmd); err != nil {
+			return fmt.Errorf("adding CA certificate to %s: %w", db, err)
+		}
+	}
+	return nil
+}
+
+// UntrustCANSS removes a CA certificate added by TrustCANSS from the NSS
+// databases that hold it
+func (m *CertificateTrustManager) UntrustCANSS(certPath string) error {
+	dbs, nickname, err := m.nssTargets(certPath)
+	if err != nil || len(dbs) == 0 {
+		return err
+	}
+
+	m.progress.StartProgress("Removing CA certificate from NSS databases")
+	defer m.progress.CompleteProgress()
+
+	for _, db := range dbs {
+		// Skip databases that never had the certificate
+		if _, err := runCommand(exec.Command("certutil", "-L", "-n", nickname, "-d", "sql:"+db)); err != nil {
+			continue
+		}
+		cmd := exec.Command("certutil", "-D", "-n", nickname, "-d", "sql:"+db)
+		if _, err := runCommand(cmd); err != nil {
+			return fmt.Errorf("removing CA certificate from %s: %w", db, err)
+		}
+	}
+	return nil
+}
+
+// nssTargets returns the NSS databases to update and the nickname of the
+// certificate in them. No databases are returned when NSS certutil is
+// missing; Windows' certutil is a different tool.
+func (m *CertificateTrustManager) nssTargets(certPath string) ([]string, string, error) {
+	_, sha256Sum, err := certificateFingerprints(certPath)
+	if err != nil {
+		return nil, "", err
+	}
+	nickname := "certgen-" + sha256Sum[:16]
+
+	if runtime.GOOS == "windows" {
+		m.progress.StartProgress("Skipping NSS databases: not supported on Windows")
+		return nil, nickname, nil
+	}
+	if _, err := exec.LookPath("certutil"); err != nil {
+		m.progress.StartProgress("Skipping NSS databases: certutil not found (install libnss3-tools or nss-tools)")
+		return nil, nickname, nil
+	}
+	dbs := findNSSDatabases()
+	if len(dbs) == 0 {
+		m.progress.StartProgress("Skipping NSS databases: none found")
+	}
+	return dbs, nickname, nil
+}
+
+// findNSSDatabases returns the shared NSS database and the Firefox profile
+// directories holding a cert9.db
+func findNSSDatabases() []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	patterns := []string{
+		filepath.Join(home, ".pki", "nssdb", "cert9.db"),
+		filepath.Join(home, ".mozilla", "firefox", "*", "cert9.db"),
+		filepath.Join(home, "snap", "firefox", "common", ".mozilla", "firefox", "*", "cert9.db"),
+		filepath.Join(home, ".var", "app", "org.mozilla.firefox", ".mozilla", "firefox", "*", "cert9.db"),
+		filepath.Join(home, "Library", "Application Support", "Firefox", "Profiles", "*", "cert9.db"),
+	}
+	var dbs []string
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		for _, match := range matches {
+			dbs = append(dbs, filepath.Dir(match))
+		}
+	}
+	return dbs
 }
 
 // InstallCertificate installs a server certificate
@@ -53,7 +509,7 @@ func (m *CertificateTrustManager) InstallCertificate(certPath, keyPath string) e
 	return nil
 }
 
-func (m *CertificateTrustManager) installAndTrustCADarwin(certPath string) error {
+func (m *CertificateTrustManager) installAndTrustCADarwin(certPath string, scope Scope) error {
 	m.progress.StartProgress("Installing CA certificate")
 	defer m.progress.CompleteProgress()
 
@@ -68,19 +524,31 @@ func (m *CertificateTrustManager) installAndTrustCADarwin(certPath string) error
 		return fmt.Errorf("certificate file not found: %w", err)
 	}
 
+	// Trust for the current user only, which needs no admin rights
+	if scope == ScopeUser {
+		keychain, err := darwinLoginKeychain()
+		if err != nil {
+			return err
+		}
+		cmd := exec.Command("security", "add-trusted-cert", "-r", "trustRoot", "-k", keychain, absPath)
+		if _, err := runCommand(cmd); err != nil {
+			return fmt.Errorf("installing CA certificate: %w", err)
+		}
+		return nil
+	}
+
 	// Add to keychain
-	cmd := exec.Command("security", "add-trusted-cert", "-d", "-r", "trustRoot", "-k", "/Library/Keychains/System.keychain", absPath)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		// Check if it's a permission error
-		if strings.Contains(string(output), "authorization") || strings.Contains(string(output), "permission") {
-			// Retry with sudo
-			sudoCmd := exec.Command("sudo", "security", "add-trusted-cert", "-d", "-r", "trustRoot", "-k", "/Library/Keychains/System.keychain", absPath)
-			if output, err = sudoCmd.CombinedOutput(); err != nil {
-				return fmt.Errorf("installing CA certificate (with sudo): %s", string(output))
-			}
-		} else {
-			return fmt.Errorf("installing CA certificate: %s", string(output))
+	args := []string{"security", "add-trusted-cert", "-d", "-r", "trustRoot", "-k", "/Library/Keychains/System.keychain", absPath}
+	if _, err := runCommand(exec.Command(args[0], args[1:]...)); err != nil {
+		if !permissionDenied(err) {
+			return fmt.Errorf("installing CA certificate: %w", err)
+		}
+		if m.escalation == EscalationNone {
+			return m.privilegedError("installing CA certificate", err, args)
+		}
+		// Retry with administrator rights
+		if _, err := runCommand(m.privileged(args...)); err != nil {
+			return fmt.Errorf("installing CA certificate (with %s): %w", m.escalation, err)
 		}
 	}
 
@@ -102,23 +570,86 @@ func (m *CertificateTrustManager) installAndTrustCALinux(certPath string) error
 		return fmt.Errorf("certificate file not found: %w", err)
 	}
 
-	// Copy to system CA directory
-	destPath := "/usr/local/share/ca-certificates/certgen-ca.crt"
-	cmd := exec.Command("sudo", "cp", absPath, destPath)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("copying CA certificate: %s", string(output))
+	_, sha256Sum, err := certificateFingerprints(absPath)
+	if err != nil {
+		return err
+	}
+
+	// Copy to system CA directory, named by fingerprint so that trusting
+	// another CA does not replace this one
+	store := detectLinuxTrustStore()
+	destPath := filepath.Join(store.dir, "certgen-"+sha256Sum[:16]+".crt")
+	copyArgs := []string{"cp", absPath, destPath}
+	if _, err := runCommand(m.privileged(copyArgs...)); err != nil {
+		return m.privilegedError("copying CA certificate", err, copyArgs, store.update)
 	}
 
 	// Update CA certificates
-	cmd = exec.Command("sudo", "update-ca-certificates")
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("updating CA certificates: %s", string(output))
+	if _, err := runCommand(m.privileged(store.update...)); err != nil {
+		return m.privilegedError("updating CA certificates", err, store.update)
 	}
 
 	return nil
 }
 
-func (m *CertificateTrustManager) installAndTrustCAWindows(certPath string) error {
+// linuxTrustStore is a distribution's CA anchor directory and the commands
+// that rebuild the system bundle from it
+type linuxTrustStore struct {
+	dir     string
+	bundle  string   // Bundle of every trusted CA built from the anchors
+	update  []string // Rebuilds the bundle after adding a certificate
+	refresh []string // Rebuilds the bundle after removing a certificate
+}
+
+var (
+	// debianTrustStore is used by Debian, Ubuntu and derivatives
+	debianTrustStore = linuxTrustStore{
+		dir:     "/usr/local/share/ca-certificates",
+		bundle:  "/etc/ssl/certs/ca-certificates.crt",
+		update:  []string{"update-ca-certificates"},
+		refresh: []string{"update-ca-certificates", "--fresh"},
+	}
+	// redHatTrustStore is used by Fedora, RHEL, CentOS and Rocky
+	redHatTrustStore = linuxTrustStore{
+		dir:     "/etc/pki/ca-trust/source/anchors",
+		bundle:  "/etc/pki/tls/certs/ca-bundle.crt",
+		update:  []string{"update-ca-trust", "extract"},
+		refresh: []string{"update-ca-trust", "extract"},
+	}
+)
+
+// detectLinuxTrustStore returns the RHEL-family trust store when its anchor
+// directory and update-ca-trust are present, and the Debian one otherwise
+func detectLinuxTrustStore() linuxTrustStore {
+	if info, err := os.Stat(redHatTrustStore.dir); err == nil && info.IsDir() {
+		if _, err := exec.LookPath("update-ca-trust"); err == nil {
+			return redHatTrustStore
+		}
+	}
+	return debianTrustStore
+}
+
+// installedLinuxCopies returns the files in a trust store's anchor directory
+// holding the certificate with the given SHA-256 fingerprint
+func installedLinuxCopies(store linuxTrustStore, sha256Sum string) ([]string, error) {
+	installed, err := filepath.Glob(filepath.Join(store.dir, "*"))
+	if err != nil {
+		return nil, fmt.Errorf("listing CA certificates: %w", err)
+	}
+	var matches []string
+	for _, path := range installed {
+		_, sum, err := certificateFingerprints(path)
+		if err != nil {
+			continue // Not a certificate we can compare
+		}
+		if sum == sha256Sum {
+			matches = append(matches, path)
+		}
+	}
+	return matches, nil
+}
+
+func (m *CertificateTrustManager) installAndTrustCAWindows(certPath string, scope Scope) error {
 	m.progress.StartProgress("Installing CA certificate")
 	defer m.progress.CompleteProgress()
 
@@ -133,18 +664,188 @@ func (m *CertificateTrustManager) installAndTrustCAWindows(certPath string) erro
 		return fmt.Errorf("certificate file not found: %w", err)
 	}
 
+	// Import certificate to the current user's root store
+	if scope == ScopeUser {
+		cmd := exec.Command("certutil", "-user", "-addstore", "-f", "ROOT", absPath)
+		if _, err := runCommand(cmd); err != nil {
+			return fmt.Errorf("installing CA certificate: %w", err)
+		}
+		return nil
+	}
+
 	// Import certificate to root store
-	cmd := exec.Command("certutil", "-addstore", "-f", "ROOT", absPath)
-	if output, err := cmd.CombinedOutput(); err != nil {
+	args := []string{"certutil", "-addstore", "-f", "ROOT", absPath}
+	if _, err := runCommand(exec.Command(args[0], args[1:]...)); err != nil {
+		if m.escalation == EscalationNone {
+			return m.privilegedError("installing CA certificate", err, args)
+		}
+		// Try with elevated privileges
+		if _, err := runCommand(elevatedCertutil(`-addstore -f ROOT "` + absPath + `"`)); err != nil {
+			return fmt.Errorf("installing CA certificate (elevated): %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (m *CertificateTrustManager) untrustCADarwin(certPath string, scope Scope) error {
+	m.progress.StartProgress("Removing CA certificate")
+	defer m.progress.CompleteProgress()
+
+	// Get the absolute path
+	absPath, err := filepath.Abs(certPath)
+	if err != nil {
+		return fmt.Errorf("getting absolute path: %w", err)
+	}
+
+	sha1Sum, _, err := certificateFingerprints(absPath)
+	if err != nil {
+		return err
+	}
+
+	// Remove the user's trust settings and certificate, which needs no admin rights
+	if scope == ScopeUser {
+		keychain, err := darwinLoginKeychain()
+		if err != nil {
+			return err
+		}
+		steps := [][]string{
+			{"security", "remove-trusted-cert", absPath},
+			{"security", "delete-certificate", "-Z", sha1Sum, keychain},
+		}
+		for _, args := range steps {
+			cmd := exec.Command(args[0], args[1:]...)
+			if _, err := runCommand(cmd); err != nil {
+				return fmt.Errorf("removing CA certificate: %w", err)
+			}
+		}
+		return nil
+	}
+
+	// Remove the trust settings, then the certificate itself
+	steps := [][]string{
+		{"security", "remove-trusted-cert", "-d", absPath},
+		{"security", "delete-certificate", "-Z", sha1Sum, "/Library/Keychains/System.keychain"},
+	}
+	for _, args := range steps {
+		_, err := runCommand(exec.Command(args[0], args[1:]...))
+		if err == nil {
+			continue
+		}
+		if !permissionDenied(err) {
+			return fmt.Errorf("removing CA certificate: %w", err)
+		}
+		if m.escalation == EscalationNone {
+			return m.privilegedError("removing CA certificate", err, steps...)
+		}
+		// Retry with administrator rights
+		if _, err := runCommand(m.privileged(args...)); err != nil {
+			return fmt.Errorf("removing CA certificate (with %s): %w", m.escalation, err)
+		}
+	}
+
+	return nil
+}
+
+func (m *CertificateTrustManager) untrustCALinux(certPath string) error {
+	m.progress.StartProgress("Removing CA certificate")
+	defer m.progress.CompleteProgress()
+
+	_, sha256Sum, err := certificateFingerprints(certPath)
+	if err != nil {
+		return err
+	}
+
+	// Find the installed copies of the certificate
+	store := detectLinuxTrustStore()
+	matches, err := installedLinuxCopies(store, sha256Sum)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("certificate %s is not installed in %s", sha256Sum, store.dir)
+	}
+
+	// Remove them from the system CA directory
+	removeArgs := append([]string{"rm", "-f"}, matches...)
+	if _, err := runCommand(m.privileged(removeArgs...)); err != nil {
+		return m.privilegedError("removing CA certificate", err, removeArgs, store.refresh)
+	}
+
+	// Update CA certificates, dropping the removed ones from the bundle
+	if _, err := runCommand(m.privileged(store.refresh...)); err != nil {
+		return m.privilegedError("updating CA certificates", err, store.refresh)
+	}
+
+	return nil
+}
+
+func (m *CertificateTrustManager) untrustCAWindows(certPath string, scope Scope) error {
+	m.progress.StartProgress("Removing CA certificate")
+	defer m.progress.CompleteProgress()
+
+	sha1Sum, _, err := certificateFingerprints(certPath)
+	if err != nil {
+		return err
+	}
+
+	// Delete the certificate from the current user's root store
+	if scope == ScopeUser {
+		cmd := exec.Command("certutil", "-user", "-delstore", "ROOT", sha1Sum)
+		if _, err := runCommand(cmd); err != nil {
+			return fmt.Errorf("removing CA certificate: %w", err)
+		}
+		return nil
+	}
+
+	// Delete the certificate from the root store by its SHA-1 hash
+	args := []string{"certutil", "-delstore", "ROOT", sha1Sum}
+	if _, err := runCommand(exec.Command(args[0], args[1:]...)); err != nil {
+		if m.escalation == EscalationNone {
+			return m.privilegedError("removing CA certificate", err, args)
+		}
 		// Try with elevated privileges
-		cmd = exec.Command("powershell", "Start-Process", "certutil",
-			"-ArgumentList '-addstore -f ROOT \""+absPath+"\"'",
-			"-Verb RunAs",
-			"-Wait")
-		if output, err = cmd.CombinedOutput(); err != nil {
-			return fmt.Errorf("installing CA certificate: %s", string(output))
+		if _, err := runCommand(elevatedCertutil("-delstore ROOT " + sha1Sum)); err != nil {
+			return fmt.Errorf("removing CA certificate (elevated): %w", err)
 		}
 	}
 
 	return nil
 }
+
+// elevatedCertutil returns a command running certutil with arguments in an
+// elevated process. Start-Process does not pass on the exit status of the
+// process it starts, so the script exits with it explicitly.
+func elevatedCertutil(arguments string) *exec.Cmd {
+	quoted := strings.ReplaceAll(arguments, "'", "''")
+	script := "$p = Start-Process certutil -ArgumentList '" + quoted + "' -Verb RunAs -Wait -PassThru; exit $p.ExitCode"
+	return exec.Command("powershell", "-NoProfile", "-Command", script)
+}
+
+// darwinLoginKeychain returns the current user's login keychain
+func darwinLoginKeychain() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("locating login keychain: %w", err)
+	}
+	return filepath.Join(home, "Library", "Keychains", "login.keychain-db"), nil
+}
+
+// certificateFingerprints returns the uppercase hex SHA-1 and SHA-256
+// fingerprints of the first certificate in a PEM file
+func certificateFingerprints(certPath string) (sha1Sum, sha256Sum string, err error) {
+	data, err := os.ReadFile(certPath)
+	if err != nil {
+		return "", "", fmt.Errorf("reading certificate: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return "", "", fmt.Errorf("no PEM certificate found in %s", certPath)
+	}
+	if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+		return "", "", fmt.Errorf("parsing certificate: %w", err)
+	}
+	s1 := sha1.Sum(block.Bytes)
+	s256 := sha256.Sum256(block.Bytes)
+	return strings.ToUpper(hex.EncodeToString(s1[:])), strings.ToUpper(hex.EncodeToString(s256[:])), nil
+}